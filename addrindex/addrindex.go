@@ -0,0 +1,117 @@
+// Package addrindex indexes payout addresses of all users, so ownership of
+// address is checked without reading every user. bestore has no address
+// query, index is built by one pass over users and reused until it expires
+// or is invalidated by address change made through mineradmin.
+package addrindex
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+)
+
+// Address is user address with its owner.
+type Address struct {
+	User    bestore.User
+	Coin    bestore.Coin
+	Address string
+}
+
+// Same checks that addresses are the same wallet, case is ignored since
+// e.g. ETH addresses differ in case only by checksum.
+func Same(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+// Read returns addresses of all users.
+func Read(s bestore.Store) ([]Address, error) {
+	users, err := s.GetUsers()
+	if err != nil {
+		return nil, errors.New("failed to get users from DB: " + err.Error())
+	}
+
+	var addrs []Address
+
+	for _, u := range users {
+		uas, err := s.GetUserAddresses(u.ID)
+		if err != nil {
+			return nil, errors.New("failed to get user addresses from DB: " +
+				err.Error())
+		}
+		for _, ua := range uas {
+			addrs = append(addrs, Address{
+				User:    u,
+				Coin:    ua.Coin,
+				Address: ua.Address,
+			})
+		}
+	}
+
+	return addrs, nil
+}
+
+// Owners returns users other than userID who own address in any coin.
+func Owners(addrs []Address, userID uint, address string) []bestore.User {
+	var (
+		owners []bestore.User
+		seen   = map[uint]bool{}
+	)
+
+	for _, a := range addrs {
+		if a.User.ID != userID && !seen[a.User.ID] &&
+			Same(a.Address, address) {
+			seen[a.User.ID] = true
+			owners = append(owners, a.User)
+		}
+	}
+
+	return owners
+}
+
+// Index keeps addresses read last. Addresses changed outside of
+// mineradmin are seen after TTL.
+type Index struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mutex  sync.Mutex
+	addrs  []Address
+	readAt time.Time
+	valid  bool
+}
+
+func NewIndex(ttl time.Duration) *Index {
+	return &Index{ttl: ttl, now: time.Now}
+}
+
+// Addresses returns addresses of all users, they are read from s if index
+// expired. Index mutex is held while reading, so concurrent callers read
+// once. Result is shared by callers and must not be changed.
+func (i *Index) Addresses(s bestore.Store) ([]Address, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if i.valid && i.now().Sub(i.readAt) < i.ttl {
+		return i.addrs, nil
+	}
+
+	addrs, err := Read(s)
+	if err != nil {
+		return nil, err
+	}
+
+	i.addrs, i.readAt, i.valid = addrs, i.now(), true
+
+	return addrs, nil
+}
+
+// Invalidate makes next Addresses call read addresses again.
+func (i *Index) Invalidate() {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.addrs, i.valid = nil, false
+}
@@ -0,0 +1,78 @@
+package addrindex
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Owners(t *testing.T) {
+	a := bestore.User{ID: 1}
+	b := bestore.User{ID: 2}
+	c := bestore.User{ID: 3}
+
+	addrs := []Address{
+		{User: a, Coin: bestore.ETH, Address: "0xab"},
+		{User: b, Coin: bestore.ETH, Address: "0xAB"},
+		{User: b, Coin: bestore.BTC, Address: "0xab"},
+		{User: c, Coin: bestore.BTC, Address: "other"},
+	}
+
+	assert.Equal(t, []bestore.User{b}, Owners(addrs, 1, "0xAb"))
+	assert.Empty(t, Owners(addrs, 3, "other"))
+}
+
+func Test_Index_Addresses(t *testing.T) {
+	s := bestore.NewMockStore()
+
+	s.On("GetUsers").Return([]bestore.User{{ID: 1}, {ID: 2}}, nil)
+	s.On("GetUserAddresses", uint(1)).Return([]bestore.UserAddress{
+		{UserID: 1, Coin: bestore.ETH, Address: "addr"},
+	}, nil)
+	s.On("GetUserAddresses", uint(2)).Return([]bestore.UserAddress{}, nil)
+
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	i := NewIndex(time.Minute)
+	i.now = func() time.Time { return now }
+
+	addrs, err := i.Addresses(s)
+	assert.NoError(t, err)
+	assert.Equal(t, []Address{
+		{User: bestore.User{ID: 1}, Coin: bestore.ETH, Address: "addr"},
+	}, addrs)
+
+	_, err = i.Addresses(s)
+	assert.NoError(t, err)
+	s.AssertNumberOfCalls(t, "GetUsers", 1)
+
+	i.Invalidate()
+
+	_, err = i.Addresses(s)
+	assert.NoError(t, err)
+	s.AssertNumberOfCalls(t, "GetUsers", 2)
+
+	now = now.Add(time.Minute)
+
+	_, err = i.Addresses(s)
+	assert.NoError(t, err)
+	s.AssertNumberOfCalls(t, "GetUsers", 3)
+	s.AssertNumberOfCalls(t, "GetUserAddresses", 6)
+}
+
+func Test_Index_errorsNotKept(t *testing.T) {
+	s := bestore.NewMockStore()
+
+	s.On("GetUsers").Return([]bestore.User(nil), errors.New("oops"))
+
+	i := NewIndex(time.Minute)
+
+	_, err := i.Addresses(s)
+	assert.EqualError(t, err, "failed to get users from DB: oops")
+
+	_, err = i.Addresses(s)
+	assert.Error(t, err)
+	s.AssertNumberOfCalls(t, "GetUsers", 2)
+}
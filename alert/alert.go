@@ -0,0 +1,417 @@
+// Package alert watches user balances in projects and raises alerts about
+// miners who stopped mining or mine unusually. Balances are snapshotted
+// periodically, alerts are opened and resolved by comparing balance growth
+// between snapshots.
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mail"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/trash"
+	"github.com/boomstarternetwork/mineradmin/webhook"
+	"github.com/shopspring/decimal"
+)
+
+// Config is alert thresholds.
+type Config struct {
+	// StalePeriod is how long balance may not grow, stale alerts are not
+	// raised if it is zero.
+	StalePeriod time.Duration
+	// Factor is how many times recent growth rate may be above or below
+	// baseline one, anomaly alerts are not raised if it is zero.
+	Factor float64
+	// Window is period recent growth rate is computed over.
+	Window time.Duration
+	// Baseline is period before Window baseline growth rate is computed
+	// over.
+	Baseline time.Duration
+}
+
+// History returns how long snapshots are needed for evaluation.
+func (conf Config) History() time.Duration {
+	h := conf.StalePeriod
+	if conf.Factor > 0 && conf.Window+conf.Baseline > h {
+		h = conf.Window + conf.Baseline
+	}
+	return h
+}
+
+// Key is user balance in project coin.
+type Key struct {
+	ProjectID uint
+	Email     string
+	Coin      string
+}
+
+// Finding is alert condition found about balance.
+type Finding struct {
+	Key
+	Kind    string
+	Details string
+}
+
+// point is balance at time.
+type point struct {
+	at     time.Time
+	amount float64
+}
+
+// growth sums balance increases of points after from up to to. Decreases
+// are payouts, not mining, so they are skipped.
+func growth(ps []point, from time.Time, to time.Time) float64 {
+	var g float64
+	for i := 1; i < len(ps); i++ {
+		if ps[i].at.After(from) && !ps[i].at.After(to) &&
+			ps[i].amount > ps[i-1].amount {
+			g += ps[i].amount - ps[i-1].amount
+		}
+	}
+	return g
+}
+
+const timeFormat = "2006-01-02 15:04 MST"
+
+// stale checks that balance did not grow for stale period. Balances with
+// shorter history are not judged.
+func stale(ps []point, now time.Time, conf Config) (string, bool) {
+	since := now.Add(-conf.StalePeriod)
+	if conf.StalePeriod <= 0 || ps[0].at.After(since) {
+		return "", false
+	}
+
+	last := ps[0].at
+	for i := 1; i < len(ps); i++ {
+		if ps[i].amount > ps[i-1].amount {
+			last = ps[i].at
+		}
+	}
+
+	if last.After(since) {
+		return "", false
+	}
+
+	return "no growth since " + last.UTC().Format(timeFormat), true
+}
+
+// anomaly checks that balance growth rate over window deviates from
+// baseline rate by more than factor. Balances which did not grow in baseline
+// or have baseline history shorter than two windows are not judged.
+func anomaly(ps []point, now time.Time, conf Config) (string, bool) {
+	if conf.Factor <= 0 || conf.Window <= 0 {
+		return "", false
+	}
+
+	windowStart := now.Add(-conf.Window)
+	baselineStart := windowStart.Add(-conf.Baseline)
+	if ps[0].at.After(baselineStart) {
+		baselineStart = ps[0].at
+	}
+
+	baseline := windowStart.Sub(baselineStart)
+	if baseline < 2*conf.Window {
+		return "", false
+	}
+
+	baseRate := growth(ps, baselineStart, windowStart) / baseline.Hours()
+	if baseRate <= 0 {
+		return "", false
+	}
+
+	rate := growth(ps, windowStart, now) / conf.Window.Hours()
+
+	if rate > baseRate*conf.Factor || rate < baseRate/conf.Factor {
+		return fmt.Sprintf("growth %s/h over last %s, usually %s/h",
+			formatRate(rate), conf.Window, formatRate(baseRate)), true
+	}
+
+	return "", false
+}
+
+func formatRate(r float64) string {
+	return decimal.NewFromFloat(r).Round(8).String()
+}
+
+// Evaluate finds alert conditions in snapshots. Balance is never both
+// stale and anomalous. Findings are ordered by key.
+func Evaluate(snaps []mastore.BalanceSnapshot, now time.Time,
+	conf Config) ([]Finding, error) {
+	points := map[Key][]point{}
+
+	for _, sn := range snaps {
+		d, err := decimal.NewFromString(sn.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s amount %q", sn.Coin,
+				sn.Amount)
+		}
+		amount, _ := d.Float64()
+		k := Key{sn.ProjectID, sn.Email, sn.Coin}
+		points[k] = append(points[k], point{sn.TakenAt, amount})
+	}
+
+	var fs []Finding
+
+	for k, ps := range points {
+		sort.SliceStable(ps, func(i, j int) bool {
+			return ps[i].at.Before(ps[j].at)
+		})
+
+		if details, ok := stale(ps, now, conf); ok {
+			fs = append(fs, Finding{k, mastore.AlertStale, details})
+		} else if details, ok := anomaly(ps, now, conf); ok {
+			fs = append(fs, Finding{k, mastore.AlertAnomaly, details})
+		}
+	}
+
+	sort.Slice(fs, func(i, j int) bool {
+		a, b := fs[i].Key, fs[j].Key
+		if a.ProjectID != b.ProjectID {
+			return a.ProjectID < b.ProjectID
+		}
+		if a.Email != b.Email {
+			return a.Email < b.Email
+		}
+		return a.Coin < b.Coin
+	})
+
+	return fs, nil
+}
+
+// Event is webhook.AlertOpened and webhook.AlertResolved event data.
+type Event struct {
+	ID        uint   `json:"id"`
+	Kind      string `json:"kind"`
+	ProjectID uint   `json:"project_id"`
+	Email     string `json:"email"`
+	Coin      string `json:"coin"`
+	Details   string `json:"details"`
+}
+
+func newEvent(a mastore.Alert) Event {
+	return Event{
+		ID:        a.ID,
+		Kind:      a.Kind,
+		ProjectID: a.ProjectID,
+		Email:     a.Email,
+		Coin:      a.Coin,
+		Details:   a.Details,
+	}
+}
+
+// Logger logs errors which do not stop check, echo.Logger is one.
+type Logger interface {
+	Error(i ...interface{})
+}
+
+// Checker snapshots balances and opens and resolves alerts.
+type Checker struct {
+	store  bestore.Store
+	mstore mastore.Store
+	bin    trash.Bin
+	conf   Config
+	// notifier and events can be nil.
+	notifier *mail.Notifier
+	events   webhook.Emitter
+	logger   Logger
+}
+
+func NewChecker(s bestore.Store, ms mastore.Store, conf Config,
+	n *mail.Notifier, events webhook.Emitter, logger Logger) Checker {
+	return Checker{
+		store:    s,
+		mstore:   ms,
+		bin:      trash.NewBin(s, ms),
+		conf:     conf,
+		notifier: n,
+		events:   events,
+		logger:   logger,
+	}
+}
+
+// snapshot returns balances of users in projects which are not in trash and
+// project names.
+func (ch Checker) snapshot(now time.Time) ([]mastore.BalanceSnapshot,
+	map[uint]string, error) {
+	hidden, err := ch.bin.Hidden(trash.Projects)
+	if err != nil {
+		return nil, nil, errors.New("failed to get trashed projects from " +
+			"DB: " + err.Error())
+	}
+
+	pbs, err := ch.store.ProjectsBalances()
+	if err != nil {
+		return nil, nil, errors.New("failed to get projects balances " +
+			"from DB: " + err.Error())
+	}
+
+	var snaps []mastore.BalanceSnapshot
+	names := map[uint]string{}
+
+	for _, pb := range pbs {
+		if hidden[pb.ProjectID] {
+			continue
+		}
+		names[pb.ProjectID] = pb.ProjectName
+
+		ubs, err := ch.store.ProjectUsersBalances(pb.ProjectID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get project %d users "+
+				"balances from DB: %v", pb.ProjectID, err)
+		}
+
+		for _, ub := range ubs {
+			for _, ca := range ub.Coins {
+				snaps = append(snaps, mastore.BalanceSnapshot{
+					ProjectID: pb.ProjectID,
+					Email:     ub.Email,
+					Coin:      fmt.Sprintf("%s", ca.Coin),
+					Amount:    ca.Amount,
+					TakenAt:   now,
+				})
+			}
+		}
+	}
+
+	return snaps, names, nil
+}
+
+// Check snapshots balances, opens alerts about new findings and resolves
+// alerts whose conditions cleared. Numbers of opened and resolved alerts are
+// returned. Alerts about projects in trash are resolved.
+func (ch Checker) Check(now time.Time) (int, int, error) {
+	snaps, names, err := ch.snapshot(now)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = ch.mstore.AddBalanceSnapshots(snaps)
+	if err != nil {
+		return 0, 0, errors.New("failed to add balance snapshots to DB: " +
+			err.Error())
+	}
+
+	// A bit more history is kept than needed, so there is a snapshot at
+	// its very start.
+	since := now.Add(-ch.conf.History() - ch.conf.History()/10)
+
+	err = ch.mstore.RemoveBalanceSnapshots(since)
+	if err != nil {
+		return 0, 0, errors.New("failed to remove old balance snapshots " +
+			"from DB: " + err.Error())
+	}
+
+	history, err := ch.mstore.BalanceSnapshots(since)
+	if err != nil {
+		return 0, 0, errors.New("failed to get balance snapshots from DB: " +
+			err.Error())
+	}
+
+	var live []mastore.BalanceSnapshot
+	for _, sn := range history {
+		if _, ok := names[sn.ProjectID]; ok {
+			live = append(live, sn)
+		}
+	}
+
+	fs, err := Evaluate(live, now, ch.conf)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	open, err := ch.mstore.OpenAlerts()
+	if err != nil {
+		return 0, 0, errors.New("failed to get open alerts from DB: " +
+			err.Error())
+	}
+
+	type alertKey struct {
+		Key
+		kind string
+	}
+
+	found := map[alertKey]bool{}
+	for _, f := range fs {
+		found[alertKey{f.Key, f.Kind}] = true
+	}
+
+	opened := map[alertKey]bool{}
+	resolved := 0
+
+	for _, a := range open {
+		k := alertKey{Key{a.ProjectID, a.Email, a.Coin}, a.Kind}
+		if found[k] {
+			opened[k] = true
+			continue
+		}
+
+		err := ch.mstore.ResolveAlert(a.ID, now)
+		if err != nil {
+			return 0, resolved, fmt.Errorf("failed to resolve alert %d in "+
+				"DB: %v", a.ID, err)
+		}
+		resolved++
+
+		ch.emit(webhook.AlertResolved, a)
+	}
+
+	n := 0
+
+	for _, f := range fs {
+		if opened[alertKey{f.Key, f.Kind}] {
+			continue
+		}
+
+		a := mastore.Alert{
+			Kind:        f.Kind,
+			ProjectID:   f.ProjectID,
+			ProjectName: names[f.ProjectID],
+			Email:       f.Email,
+			Coin:        f.Coin,
+			Details:     f.Details,
+			OpenedAt:    now,
+		}
+
+		a.ID, err = ch.mstore.AddAlert(a)
+		if err != nil {
+			return n, resolved, errors.New("failed to add alert to DB: " +
+				err.Error())
+		}
+		n++
+
+		ch.emit(webhook.AlertOpened, a)
+		ch.notify(a)
+	}
+
+	return n, resolved, nil
+}
+
+// emit emits alert event, emitting errors are logged only.
+func (ch Checker) emit(event string, a mastore.Alert) {
+	if ch.events == nil {
+		return
+	}
+
+	err := ch.events.Emit(event, "", newEvent(a))
+	if err != nil {
+		ch.logger.Error("failed to emit " + event + " event: " +
+			err.Error())
+	}
+}
+
+// notify sends alert to admins, sending errors are logged only.
+func (ch Checker) notify(a mastore.Alert) {
+	if ch.notifier == nil {
+		return
+	}
+
+	err := ch.notifier.AlertOpened(a.Kind, a.ProjectName, a.Email, a.Coin,
+		a.Details)
+	if err != nil {
+		ch.logger.Error("failed to send notification: " + err.Error())
+	}
+}
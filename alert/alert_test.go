@@ -0,0 +1,153 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	now  = time.Date(2018, 10, 10, 12, 0, 0, 0, time.UTC)
+	conf = Config{
+		StalePeriod: 24 * time.Hour,
+		Factor:      3,
+		Window:      6 * time.Hour,
+		Baseline:    48 * time.Hour,
+	}
+)
+
+// hourly returns snapshots taken every hour during last hours with given
+// balance increase per hour.
+func hourly(email string, hours int,
+	step func(h int) float64) []mastore.BalanceSnapshot {
+	var (
+		snaps  []mastore.BalanceSnapshot
+		amount float64
+	)
+	for h := 0; h <= hours; h++ {
+		if h > 0 {
+			amount += step(h)
+		}
+		snaps = append(snaps, mastore.BalanceSnapshot{
+			ProjectID: 1,
+			Email:     email,
+			Coin:      "ETH",
+			Amount:    formatRate(amount),
+			TakenAt:   now.Add(time.Duration(h-hours) * time.Hour),
+		})
+	}
+	return snaps
+}
+
+func Test_Evaluate(t *testing.T) {
+	var snaps []mastore.BalanceSnapshot
+
+	// Steady miner.
+	snaps = append(snaps, hourly("steady@example.com", 60,
+		func(h int) float64 { return 1 })...)
+	// Stopped mining 30 hours ago.
+	snaps = append(snaps, hourly("stopped@example.com", 60,
+		func(h int) float64 {
+			if h > 30 {
+				return 0
+			}
+			return 1
+		})...)
+	// Mines ten times faster in last 6 hours.
+	snaps = append(snaps, hourly("fast@example.com", 60,
+		func(h int) float64 {
+			if h > 54 {
+				return 10
+			}
+			return 1
+		})...)
+	// Was paid out 2 hours ago, payout is not growth change.
+	snaps = append(snaps, hourly("paid@example.com", 60,
+		func(h int) float64 {
+			if h == 58 {
+				return -50
+			}
+			return 1
+		})...)
+	// Too short history to judge.
+	snaps = append(snaps, hourly("new@example.com", 10,
+		func(h int) float64 { return 0 })...)
+
+	fs, err := Evaluate(snaps, now, conf)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if !assert.Len(t, fs, 2) {
+		return
+	}
+
+	assert.Equal(t, Key{1, "fast@example.com", "ETH"}, fs[0].Key)
+	assert.Equal(t, mastore.AlertAnomaly, fs[0].Kind)
+	assert.Equal(t, "growth 10/h over last 6h0m0s, usually 1/h",
+		fs[0].Details)
+
+	assert.Equal(t, Key{1, "stopped@example.com", "ETH"}, fs[1].Key)
+	assert.Equal(t, mastore.AlertStale, fs[1].Kind)
+	assert.Equal(t, "no growth since 2018-10-09 06:00 UTC", fs[1].Details)
+}
+
+func Test_Evaluate_disabled(t *testing.T) {
+	snaps := hourly("stopped@example.com", 60,
+		func(h int) float64 { return 0 })
+
+	fs, err := Evaluate(snaps, now, Config{})
+	assert.NoError(t, err)
+	assert.Empty(t, fs)
+
+	_, err = Evaluate([]mastore.BalanceSnapshot{{Coin: "ETH",
+		Amount: "x"}}, now, conf)
+	assert.EqualError(t, err, `invalid ETH amount "x"`)
+}
+
+func Test_Checker_Check(t *testing.T) {
+	s := bestore.NewMockStore()
+	ms := mastore.NewMockStore()
+
+	ms.On("TrashItems", "projects").Return([]mastore.TrashItem{}, nil)
+	s.On("ProjectsBalances").Return([]bestore.ProjectBalance{
+		{ProjectID: 1, ProjectName: "Alpha"},
+	}, nil)
+	s.On("ProjectUsersBalances", uint(1)).Return([]bestore.UserBalance{
+		{Email: "stopped@example.com", Coins: []bestore.CoinAmount{
+			{Coin: bestore.ETH, Amount: "30"}}},
+	}, nil)
+	ms.On("AddBalanceSnapshots", []mastore.BalanceSnapshot{
+		{ProjectID: 1, Email: "stopped@example.com", Coin: "ETH",
+			Amount: "30", TakenAt: now},
+	}).Return(nil)
+	ms.On("RemoveBalanceSnapshots", mock.Anything).Return(nil)
+	ms.On("BalanceSnapshots", mock.Anything).Return(
+		hourly("stopped@example.com", 60, func(h int) float64 {
+			if h > 30 {
+				return 0
+			}
+			return 1
+		}), nil)
+	ms.On("OpenAlerts").Return([]mastore.Alert{
+		{ID: 7, Kind: mastore.AlertStale, ProjectID: 1,
+			Email: "steady@example.com", Coin: "ETH"},
+	}, nil)
+	ms.On("ResolveAlert", uint(7), now).Return(nil)
+	ms.On("AddAlert", mock.MatchedBy(func(a mastore.Alert) bool {
+		return a.Kind == mastore.AlertStale && a.ProjectName == "Alpha" &&
+			a.Email == "stopped@example.com"
+	})).Return(uint(8), nil)
+
+	opened, resolved, err := NewChecker(s, ms, conf, nil, nil, nil).
+		Check(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, opened)
+	assert.Equal(t, 1, resolved)
+
+	ms.AssertExpectations(t)
+}
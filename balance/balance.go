@@ -0,0 +1,147 @@
+// Package balance does exact arithmetic on mined coin amounts which bestore
+// returns as decimal strings and values them in fiat currency.
+package balance
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/shopspring/decimal"
+)
+
+// CoinTotal is total amount of coin.
+type CoinTotal struct {
+	Coin   bestore.Coin
+	Amount decimal.Decimal
+}
+
+// Totals is amounts summed per coin with optional fiat value.
+type Totals struct {
+	Coins []CoinTotal
+
+	// Valued is true if Value is computed, Value then includes only priced
+	// coins and Unpriced lists coins without price.
+	Valued   bool
+	Currency string
+	Value    decimal.Decimal
+	Unpriced []bestore.Coin
+}
+
+// Parse parses coin amount string.
+func Parse(ca bestore.CoinAmount) (decimal.Decimal, error) {
+	d, err := decimal.NewFromString(ca.Amount)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("invalid %s amount %q",
+			ca.Coin, ca.Amount)
+	}
+	return d, nil
+}
+
+// Sum sums amounts of all lists per coin. Coins are sorted by name.
+func Sum(lists ...[]bestore.CoinAmount) (Totals, error) {
+	sums := map[bestore.Coin]decimal.Decimal{}
+
+	for _, cas := range lists {
+		for _, ca := range cas {
+			d, err := Parse(ca)
+			if err != nil {
+				return Totals{}, err
+			}
+			if sum, ok := sums[ca.Coin]; ok {
+				d = sum.Add(d)
+			}
+			sums[ca.Coin] = d
+		}
+	}
+
+	var t Totals
+
+	for coin, amount := range sums {
+		t.Coins = append(t.Coins, CoinTotal{Coin: coin, Amount: amount})
+	}
+
+	sort.Slice(t.Coins, func(i, j int) bool {
+		return fmt.Sprint(t.Coins[i].Coin) < fmt.Sprint(t.Coins[j].Coin)
+	})
+
+	return t, nil
+}
+
+// Valuate returns totals with fiat value computed with prices.
+func (t Totals) Valuate(p Prices) Totals {
+	t.Valued = true
+	t.Currency = p.Currency
+	t.Value = decimal.New(0, 0)
+	t.Unpriced = nil
+
+	for _, ct := range t.Coins {
+		price, ok := p.Coins[ct.Coin]
+		if !ok {
+			t.Unpriced = append(t.Unpriced, ct.Coin)
+			continue
+		}
+		t.Value = t.Value.Add(ct.Amount.Mul(price))
+	}
+
+	return t
+}
+
+// UnpricedCoins returns comma separated list of coins without price.
+func (t Totals) UnpricedCoins() string {
+	ss := make([]string, len(t.Unpriced))
+	for i, coin := range t.Unpriced {
+		ss[i] = fmt.Sprint(coin)
+	}
+	return strings.Join(ss, ", ")
+}
+
+// Prices are fiat prices of coin units.
+type Prices struct {
+	Currency string
+	Coins    map[bestore.Coin]decimal.Decimal
+}
+
+// PriceSource provides current coin prices.
+type PriceSource interface {
+	Prices() (Prices, error)
+}
+
+// pricesDoc is prices file and HTTP response format, e.g. in YAML:
+//
+//	currency: USD
+//	prices:
+//	  BTC: "6421.50"
+//	  ETH: "203.17"
+type pricesDoc struct {
+	Currency string            `yaml:"currency" json:"currency"`
+	Prices   map[string]string `yaml:"prices" json:"prices"`
+}
+
+func (d pricesDoc) parse() (Prices, error) {
+	if d.Currency == "" {
+		return Prices{}, errors.New("blank currency")
+	}
+
+	p := Prices{
+		Currency: d.Currency,
+		Coins:    map[bestore.Coin]decimal.Decimal{},
+	}
+
+	for coinStr, priceStr := range d.Prices {
+		coin, err := bestore.ParseCoin(coinStr)
+		if err != nil {
+			return Prices{}, fmt.Errorf("invalid coin %q", coinStr)
+		}
+		price, err := decimal.NewFromString(priceStr)
+		if err != nil {
+			return Prices{}, fmt.Errorf("invalid %s price %q", coinStr,
+				priceStr)
+		}
+		p.Coins[coin] = price
+	}
+
+	return p, nil
+}
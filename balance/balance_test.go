@@ -0,0 +1,109 @@
+package balance
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Sum(t *testing.T) {
+	totals, err := Sum(
+		[]bestore.CoinAmount{
+			{Coin: bestore.ETH, Amount: "0.1"},
+			{Coin: bestore.BTC, Amount: "0.00000001"},
+		},
+		[]bestore.CoinAmount{
+			{Coin: bestore.ETH, Amount: "0.2"},
+		},
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if assert.Len(t, totals.Coins, 2) {
+		assert.Equal(t, bestore.BTC, totals.Coins[0].Coin)
+		assert.Equal(t, "0.00000001", totals.Coins[0].Amount.String())
+		assert.Equal(t, bestore.ETH, totals.Coins[1].Coin)
+		assert.Equal(t, "0.3", totals.Coins[1].Amount.String())
+	}
+
+	_, err = Sum([]bestore.CoinAmount{{Coin: bestore.BTC, Amount: "x"}})
+	assert.Error(t, err)
+}
+
+func Test_Totals_Valuate(t *testing.T) {
+	totals, _ := Sum([]bestore.CoinAmount{
+		{Coin: bestore.BTC, Amount: "0.5"},
+		{Coin: bestore.ETH, Amount: "2"},
+	})
+
+	totals = totals.Valuate(Prices{
+		Currency: "USD",
+		Coins: map[bestore.Coin]decimal.Decimal{
+			bestore.BTC: decimal.New(642150, -2),
+		},
+	})
+
+	assert.True(t, totals.Valued)
+	assert.Equal(t, "USD", totals.Currency)
+	assert.Equal(t, "3210.75", totals.Value.String())
+	assert.Equal(t, []bestore.Coin{bestore.ETH}, totals.Unpriced)
+}
+
+func Test_LoadStaticPriceSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prices")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "prices.yaml")
+
+	err = ioutil.WriteFile(path, []byte("currency: USD\n"+
+		"prices:\n  BTC: \"6421.50\"\n  ETH: \"203.17\"\n"), 0600)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	s, err := LoadStaticPriceSource(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	p, err := s.Prices()
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", p.Currency)
+	assert.Equal(t, "203.17", p.Coins[bestore.ETH].String())
+}
+
+func Test_HTTPPriceSource(t *testing.T) {
+	requests := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Write([]byte(`{"currency":"EUR","prices":{"BTC":"5600.1"}}`))
+		}))
+	defer srv.Close()
+
+	s := NewHTTPPriceSource(srv.URL, time.Minute)
+
+	p, err := s.Prices()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "EUR", p.Currency)
+	assert.Equal(t, "5600.1", p.Coins[bestore.BTC].String())
+
+	_, err = s.Prices()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+}
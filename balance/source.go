@@ -0,0 +1,105 @@
+package balance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// StaticPriceSource serves prices loaded from YAML file once.
+type StaticPriceSource struct {
+	prices Prices
+}
+
+func LoadStaticPriceSource(path string) (StaticPriceSource, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return StaticPriceSource{}, err
+	}
+
+	var doc pricesDoc
+
+	err = yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return StaticPriceSource{}, err
+	}
+
+	p, err := doc.parse()
+	if err != nil {
+		return StaticPriceSource{}, errors.New("invalid prices file: " +
+			err.Error())
+	}
+
+	return StaticPriceSource{prices: p}, nil
+}
+
+func (s StaticPriceSource) Prices() (Prices, error) {
+	return s.prices, nil
+}
+
+// HTTPPriceSource fetches prices as JSON document from URL and caches them
+// for TTL.
+type HTTPPriceSource struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mutex     sync.Mutex
+	prices    Prices
+	fetchedAt time.Time
+}
+
+func NewHTTPPriceSource(url string, ttl time.Duration) *HTTPPriceSource {
+	return &HTTPPriceSource{
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (s *HTTPPriceSource) Prices() (Prices, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.fetchedAt.IsZero() && time.Since(s.fetchedAt) < s.ttl {
+		return s.prices, nil
+	}
+
+	p, err := s.fetch()
+	if err != nil {
+		return Prices{}, err
+	}
+
+	s.prices = p
+	s.fetchedAt = time.Now()
+
+	return p, nil
+}
+
+func (s *HTTPPriceSource) fetch() (Prices, error) {
+	res, err := s.client.Get(s.url)
+	if err != nil {
+		return Prices{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return Prices{}, fmt.Errorf("unexpected prices response status %d",
+			res.StatusCode)
+	}
+
+	var doc pricesDoc
+
+	err = json.NewDecoder(res.Body).Decode(&doc)
+	if err != nil {
+		return Prices{}, errors.New("failed to decode prices: " + err.Error())
+	}
+
+	return doc.parse()
+}
@@ -0,0 +1,208 @@
+// Package cache keeps results of slow bestore reads in memory, so pages do
+// not aggregate balances in Postgres on every load. Mutations made through
+// cached store invalidate results they change, balances grow by mining
+// outside of mineradmin, so they are refreshed only by TTL.
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+)
+
+// TTLs is how long read results are cached, zero TTL disables caching of
+// results.
+type TTLs struct {
+	// Projects is TTL of GetProject.
+	Projects time.Duration
+	// Users is TTL of GetUsers.
+	Users time.Duration
+	// Balances is TTL of ProjectsBalances and ProjectUsersBalances.
+	Balances time.Duration
+}
+
+type entry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// Store is bestore.Store which caches GetProject, GetUsers,
+// ProjectsBalances and ProjectUsersBalances results. Cached results are
+// copied with their coin amounts on set and get, so callers sorting or
+// changing results do not change cached ones. Other methods are passed to
+// wrapped store.
+type Store struct {
+	bestore.Store
+	ttls TTLs
+	now  func() time.Time
+
+	mutex   sync.Mutex
+	entries map[string]entry
+}
+
+func NewStore(s bestore.Store, ttls TTLs) *Store {
+	return &Store{
+		Store:   s,
+		ttls:    ttls,
+		now:     time.Now,
+		entries: map[string]entry{},
+	}
+}
+
+// Cache keys.
+const (
+	usersKey    = "users"
+	balancesKey = "balances"
+)
+
+func projectKey(id uint) string {
+	return fmt.Sprintf("project/%d", id)
+}
+
+func projectUsersKey(id uint) string {
+	return fmt.Sprintf("project-users/%d", id)
+}
+
+func (s *Store) get(key string) (interface{}, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !s.now().Before(e.expiresAt) {
+		delete(s.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (s *Store) set(key string, value interface{}, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.entries[key] = entry{value: value, expiresAt: s.now().Add(ttl)}
+}
+
+// invalidate removes results with keys.
+func (s *Store) invalidate(keys ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, k := range keys {
+		delete(s.entries, k)
+	}
+}
+
+func copyProjectBalances(
+	pbs []bestore.ProjectBalance) []bestore.ProjectBalance {
+	cp := append([]bestore.ProjectBalance(nil), pbs...)
+	for i := range cp {
+		cp[i].Coins = append([]bestore.CoinAmount(nil), cp[i].Coins...)
+	}
+	return cp
+}
+
+func copyUserBalances(ubs []bestore.UserBalance) []bestore.UserBalance {
+	cp := append([]bestore.UserBalance(nil), ubs...)
+	for i := range cp {
+		cp[i].Coins = append([]bestore.CoinAmount(nil), cp[i].Coins...)
+	}
+	return cp
+}
+
+func (s *Store) GetProject(id uint) (bestore.Project, error) {
+	if v, ok := s.get(projectKey(id)); ok {
+		return v.(bestore.Project), nil
+	}
+
+	p, err := s.Store.GetProject(id)
+	if err != nil {
+		return p, err
+	}
+
+	s.set(projectKey(id), p, s.ttls.Projects)
+
+	return p, nil
+}
+
+func (s *Store) GetUsers() ([]bestore.User, error) {
+	if v, ok := s.get(usersKey); ok {
+		return append([]bestore.User(nil), v.([]bestore.User)...), nil
+	}
+
+	users, err := s.Store.GetUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	s.set(usersKey, append([]bestore.User(nil), users...), s.ttls.Users)
+
+	return users, nil
+}
+
+func (s *Store) ProjectsBalances() ([]bestore.ProjectBalance, error) {
+	if v, ok := s.get(balancesKey); ok {
+		return copyProjectBalances(v.([]bestore.ProjectBalance)), nil
+	}
+
+	pbs, err := s.Store.ProjectsBalances()
+	if err != nil {
+		return nil, err
+	}
+
+	s.set(balancesKey, copyProjectBalances(pbs), s.ttls.Balances)
+
+	return pbs, nil
+}
+
+func (s *Store) ProjectUsersBalances(projectID uint) ([]bestore.UserBalance,
+	error) {
+	key := projectUsersKey(projectID)
+
+	if v, ok := s.get(key); ok {
+		return copyUserBalances(v.([]bestore.UserBalance)), nil
+	}
+
+	ubs, err := s.Store.ProjectUsersBalances(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.set(key, copyUserBalances(ubs), s.ttls.Balances)
+
+	return ubs, nil
+}
+
+// AddProject invalidates balances, new project is listed in them.
+func (s *Store) AddProject(name string) error {
+	err := s.Store.AddProject(name)
+	s.invalidate(balancesKey)
+	return err
+}
+
+func (s *Store) SetProjectName(id uint, name string) error {
+	err := s.Store.SetProjectName(id, name)
+	s.invalidate(projectKey(id), balancesKey)
+	return err
+}
+
+func (s *Store) RemoveProject(id uint) error {
+	err := s.Store.RemoveProject(id)
+	s.invalidate(projectKey(id), projectUsersKey(id), balancesKey)
+	return err
+}
+
+func (s *Store) AddUser(ethAddress string, email string, password string,
+	name string, country string) (uint, error) {
+	id, err := s.Store.AddUser(ethAddress, email, password, name, country)
+	s.invalidate(usersKey)
+	return id, err
+}
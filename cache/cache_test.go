@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/stretchr/testify/assert"
+)
+
+var ttls = TTLs{
+	Projects: time.Minute,
+	Users:    time.Minute,
+	Balances: time.Minute,
+}
+
+// newTestStore returns cached store with clock which is moved by returned
+// func.
+func newTestStore(ttls TTLs) (*bestore.MockStore, *Store,
+	func(d time.Duration)) {
+	ms := bestore.NewMockStore()
+	s := NewStore(ms, ttls)
+
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	return ms, s, func(d time.Duration) { now = now.Add(d) }
+}
+
+func Test_Store_ProjectsBalances(t *testing.T) {
+	ms, s, advance := newTestStore(ttls)
+
+	ms.On("ProjectsBalances").Return([]bestore.ProjectBalance{
+		{ProjectID: 1, ProjectName: "Alpha", Coins: []bestore.CoinAmount{
+			{Coin: bestore.ETH, Amount: "1"},
+		}},
+	}, nil)
+
+	pbs, err := s.ProjectsBalances()
+	assert.NoError(t, err)
+	assert.Len(t, pbs, 1)
+
+	// Changing result does not change cached one.
+	pbs[0].ProjectName = "changed"
+	pbs[0].Coins[0].Amount = "2"
+
+	pbs, err = s.ProjectsBalances()
+	assert.NoError(t, err)
+	assert.Equal(t, "Alpha", pbs[0].ProjectName)
+	assert.Equal(t, "1", pbs[0].Coins[0].Amount)
+	ms.AssertNumberOfCalls(t, "ProjectsBalances", 1)
+
+	advance(time.Minute)
+
+	_, err = s.ProjectsBalances()
+	assert.NoError(t, err)
+	ms.AssertNumberOfCalls(t, "ProjectsBalances", 2)
+}
+
+func Test_Store_ProjectUsersBalances_coinsCopied(t *testing.T) {
+	ms, s, _ := newTestStore(ttls)
+
+	coins := []bestore.CoinAmount{{Coin: bestore.ETH, Amount: "1"}}
+	ms.On("ProjectUsersBalances", uint(1)).Return([]bestore.UserBalance{
+		{Email: "a@example.com", Coins: coins},
+	}, nil)
+
+	_, err := s.ProjectUsersBalances(1)
+	assert.NoError(t, err)
+
+	// Changing wrapped store result does not change cached one.
+	coins[0].Amount = "2"
+	// Neither does changing coins of cached result.
+	ubs, err := s.ProjectUsersBalances(1)
+	assert.NoError(t, err)
+	ubs[0].Coins[0].Amount = "3"
+
+	ubs, err = s.ProjectUsersBalances(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", ubs[0].Coins[0].Amount)
+	ms.AssertNumberOfCalls(t, "ProjectUsersBalances", 1)
+}
+
+func Test_Store_errorsNotCached(t *testing.T) {
+	ms, s, _ := newTestStore(ttls)
+
+	ms.On("GetUsers").Return([]bestore.User(nil), errors.New("oops"))
+
+	_, err := s.GetUsers()
+	assert.EqualError(t, err, "oops")
+	_, err = s.GetUsers()
+	assert.EqualError(t, err, "oops")
+
+	ms.AssertNumberOfCalls(t, "GetUsers", 2)
+}
+
+func Test_Store_disabled(t *testing.T) {
+	ms, s, _ := newTestStore(TTLs{})
+
+	ms.On("ProjectUsersBalances", uint(1)).
+		Return([]bestore.UserBalance{{Email: "a@example.com"}}, nil)
+
+	s.ProjectUsersBalances(1)
+	s.ProjectUsersBalances(1)
+
+	ms.AssertNumberOfCalls(t, "ProjectUsersBalances", 2)
+}
+
+func Test_Store_invalidation(t *testing.T) {
+	ms, s, _ := newTestStore(ttls)
+
+	ms.On("GetProject", uint(1)).
+		Return(bestore.Project{ID: 1, Name: "Alpha"}, nil)
+	ms.On("GetProject", uint(2)).
+		Return(bestore.Project{ID: 2, Name: "Beta"}, nil)
+	ms.On("ProjectsBalances").Return([]bestore.ProjectBalance{}, nil)
+	ms.On("ProjectUsersBalances", uint(1)).
+		Return([]bestore.UserBalance{}, nil)
+	ms.On("GetUsers").Return([]bestore.User{}, nil)
+	ms.On("SetProjectName", uint(1), "Gamma").Return(nil)
+	ms.On("RemoveProject", uint(1)).Return(nil)
+	ms.On("AddProject", "Delta").Return(nil)
+	ms.On("AddUser", "", "a@example.com", "", "Bob", "").
+		Return(uint(3), nil)
+
+	warm := func() {
+		s.GetProject(1)
+		s.GetProject(2)
+		s.ProjectsBalances()
+		s.ProjectUsersBalances(1)
+		s.GetUsers()
+	}
+
+	warm()
+	assert.NoError(t, s.SetProjectName(1, "Gamma"))
+	warm()
+	ms.AssertNumberOfCalls(t, "GetProject", 3)
+	ms.AssertNumberOfCalls(t, "ProjectsBalances", 2)
+	ms.AssertNumberOfCalls(t, "ProjectUsersBalances", 1)
+
+	assert.NoError(t, s.RemoveProject(1))
+	warm()
+	ms.AssertNumberOfCalls(t, "GetProject", 4)
+	ms.AssertNumberOfCalls(t, "ProjectsBalances", 3)
+	ms.AssertNumberOfCalls(t, "ProjectUsersBalances", 2)
+
+	assert.NoError(t, s.AddProject("Delta"))
+	warm()
+	ms.AssertNumberOfCalls(t, "ProjectsBalances", 4)
+	ms.AssertNumberOfCalls(t, "GetUsers", 1)
+
+	id, err := s.AddUser("", "a@example.com", "", "Bob", "")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), id)
+	warm()
+	ms.AssertNumberOfCalls(t, "GetUsers", 2)
+	ms.AssertNumberOfCalls(t, "GetProject", 4)
+}
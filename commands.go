@@ -0,0 +1,657 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/addrindex"
+	"github.com/boomstarternetwork/mineradmin/handler"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/migration"
+	"github.com/boomstarternetwork/mineradmin/trash"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var postgresFlag = cli.StringFlag{
+	Name:  "postgres-cs, p",
+	Usage: "postgres connection string",
+}
+
+var projectsCommand = cli.Command{
+	Name:  "projects",
+	Usage: "manage projects",
+	Subcommands: []cli.Command{
+		{
+			Name:   "list",
+			Usage:  "list projects",
+			Action: listProjects,
+			Flags:  []cli.Flag{postgresFlag},
+		},
+		{
+			Name:   "add",
+			Usage:  "add project",
+			Action: addProject,
+			Flags: []cli.Flag{
+				postgresFlag,
+				cli.StringFlag{
+					Name:  "name, n",
+					Usage: "project name",
+				},
+			},
+		},
+		{
+			Name:   "rename",
+			Usage:  "rename project",
+			Action: renameProject,
+			Flags: []cli.Flag{
+				postgresFlag,
+				cli.UintFlag{
+					Name:  "id",
+					Usage: "project ID",
+				},
+				cli.StringFlag{
+					Name:  "name, n",
+					Usage: "new project name",
+				},
+			},
+		},
+		{
+			Name:   "remove",
+			Usage:  "move archived project to trash",
+			Action: removeProject,
+			Flags: []cli.Flag{
+				postgresFlag,
+				cli.UintFlag{
+					Name:  "id",
+					Usage: "project ID",
+				},
+				cli.StringFlag{
+					Name:  "by",
+					Usage: "login of admin who removes project",
+				},
+			},
+		},
+	},
+}
+
+var usersCommand = cli.Command{
+	Name:  "users",
+	Usage: "manage users",
+	Subcommands: []cli.Command{
+		{
+			Name:   "list",
+			Usage:  "list users",
+			Action: listUsers,
+			Flags:  []cli.Flag{postgresFlag},
+		},
+		{
+			Name:   "add",
+			Usage:  "add user",
+			Action: addUser,
+			Flags: []cli.Flag{
+				postgresFlag,
+				cli.StringFlag{
+					Name:  "email, e",
+					Usage: "user email",
+				},
+				cli.StringFlag{
+					Name:  "name, n",
+					Usage: "user name",
+				},
+			},
+		},
+	},
+}
+
+var addressFlags = []cli.Flag{
+	postgresFlag,
+	cli.UintFlag{
+		Name:  "user-id, u",
+		Usage: "user ID",
+	},
+	cli.StringFlag{
+		Name:  "coin",
+		Usage: "address coin",
+	},
+	cli.StringFlag{
+		Name:  "address, a",
+		Usage: "payout address",
+	},
+	cli.StringFlag{
+		Name: "by",
+		Usage: "login of admin who requests change, another admin " +
+			"approves it in web UI",
+	},
+}
+
+var addressesCommand = cli.Command{
+	Name:  "addresses",
+	Usage: "manage user payout addresses",
+	Subcommands: []cli.Command{
+		{
+			Name:   "list",
+			Usage:  "list user addresses",
+			Action: listAddresses,
+			Flags: []cli.Flag{
+				postgresFlag,
+				cli.UintFlag{
+					Name:  "user-id, u",
+					Usage: "user ID",
+				},
+			},
+		},
+		{
+			Name:   "add",
+			Usage:  "request adding user address",
+			Action: addAddress,
+			Flags:  addressFlags,
+		},
+		{
+			Name:   "remove",
+			Usage:  "request removing user address",
+			Action: removeAddress,
+			Flags: append(addressFlags, cli.StringFlag{
+				Name: "replacement",
+				Usage: "new primary address, required to remove primary " +
+					"address",
+			}),
+		},
+	},
+}
+
+// openStore and openMStore open stores of database from postgres-cs flag,
+// tests replace them to run commands against mock stores.
+var openStore = func(c *cli.Context) (bestore.Store, error) {
+	s, err := bestore.NewDBStore(c.String("postgres-cs"), "production")
+	if err != nil {
+		return nil, cli.NewExitError("failed to create new DB store: "+
+			err.Error(), 5)
+	}
+	return s, nil
+}
+
+// trashedProjects returns IDs of projects in trash, they are not shown and
+// changed as in web UI.
+func trashedProjects(s bestore.Store, ms mastore.Store) (map[uint]bool,
+	error) {
+	trashed, err := trash.NewBin(s, ms).Hidden(trash.Projects)
+	if err != nil {
+		return nil, errors.New("failed to get trashed projects from DB: " +
+			err.Error())
+	}
+	return trashed, nil
+}
+
+func listProjects(c *cli.Context) error {
+	s, err := openStore(c)
+	if err != nil {
+		return err
+	}
+
+	ms, err := openMStore(c)
+	if err != nil {
+		return err
+	}
+
+	balances, err := s.ProjectsBalances()
+	if err != nil {
+		return errors.New("failed to get project balances from DB: " +
+			err.Error())
+	}
+
+	trashed, err := trashedProjects(s, ms)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 4, 2, ' ', 0)
+	for _, b := range balances {
+		if !trashed[b.ProjectID] {
+			fmt.Fprintf(w, "%d\t%s\n", b.ProjectID, b.ProjectName)
+		}
+	}
+	return w.Flush()
+}
+
+func addProject(c *cli.Context) error {
+	name, err := handler.ValidateProjectName(c.String("name"))
+	if err != nil {
+		return err
+	}
+
+	s, err := openStore(c)
+	if err != nil {
+		return err
+	}
+
+	ms, err := openMStore(c)
+	if err != nil {
+		return err
+	}
+
+	err = s.AddProject(name)
+	if err != nil {
+		return errors.New("failed to add project to DB: " + err.Error())
+	}
+
+	return handler.TrackNewProject(s, ms, name)
+}
+
+func renameProject(c *cli.Context) error {
+	id := c.Uint("id")
+	if id == 0 {
+		return errors.New("invalid project ID")
+	}
+
+	name, err := handler.ValidateProjectName(c.String("name"))
+	if err != nil {
+		return err
+	}
+
+	s, err := openStore(c)
+	if err != nil {
+		return err
+	}
+
+	ms, err := openMStore(c)
+	if err != nil {
+		return err
+	}
+
+	trashed, err := trashedProjects(s, ms)
+	if err != nil {
+		return err
+	}
+	if trashed[id] {
+		return errors.New("project is in trash")
+	}
+
+	err = s.SetProjectName(id, name)
+	if err != nil {
+		return errors.New("failed to set in DB: " + err.Error())
+	}
+
+	return nil
+}
+
+func removeProject(c *cli.Context) error {
+	id := c.Uint("id")
+	if id == 0 {
+		return errors.New("invalid project ID")
+	}
+
+	s, err := openStore(c)
+	if err != nil {
+		return err
+	}
+
+	by, err := adminArg(c, s)
+	if err != nil {
+		return err
+	}
+
+	ms, err := openMStore(c)
+	if err != nil {
+		return err
+	}
+
+	project, err := s.GetProject(id)
+	if err != nil {
+		if bestore.NotFound(err) {
+			return errors.New("project not found")
+		}
+		return errors.New("failed to get project from DB: " + err.Error())
+	}
+
+	trashed, err := trashedProjects(s, ms)
+	if err != nil {
+		return err
+	}
+	if trashed[id] {
+		return errors.New("project is in trash already")
+	}
+
+	meta, err := ms.GetProjectMeta(id)
+	if err != nil {
+		return errors.New("failed to get project metadata from DB: " +
+			err.Error())
+	}
+
+	// Projects are archived first, so active project is never removed by
+	// mistake.
+	if meta.Status != mastore.ProjectArchived {
+		return errors.New("only archived project can be removed")
+	}
+
+	_, err = trash.NewBin(s, ms).RemoveProject(id, project.Name, by)
+	if err != nil {
+		return errors.New("failed to move project to trash: " + err.Error())
+	}
+
+	return nil
+}
+
+func listUsers(c *cli.Context) error {
+	s, err := openStore(c)
+	if err != nil {
+		return err
+	}
+
+	users, err := s.GetUsers()
+	if err != nil {
+		return errors.New("failed to get users list from DB: " + err.Error())
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 4, 2, ' ', 0)
+	for _, u := range users {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", u.ID, u.Email, u.Name)
+	}
+	return w.Flush()
+}
+
+func addUser(c *cli.Context) error {
+	email, err := handler.ValidateUserEmail(c.String("email"))
+	if err != nil {
+		return err
+	}
+
+	name, err := handler.ValidateUserName(c.String("name"))
+	if err != nil {
+		return err
+	}
+
+	s, err := openStore(c)
+	if err != nil {
+		return err
+	}
+
+	userID, err := s.AddUser("", email, "", name, "")
+	if err != nil {
+		return errors.New("failed to add user to DB: " + err.Error())
+	}
+
+	fmt.Fprintln(c.App.Writer, "User ID:", userID)
+
+	return nil
+}
+
+// userStore opens store and checks that user from user-id flag exists.
+func userStore(c *cli.Context) (bestore.Store, uint, error) {
+	userID := c.Uint("user-id")
+	if userID == 0 {
+		return nil, 0, errors.New("invalid user ID")
+	}
+
+	s, err := openStore(c)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	_, err = s.GetUserByID(userID)
+	if err != nil {
+		if bestore.NotFound(err) {
+			return nil, 0, errors.New("user not found")
+		}
+		return nil, 0, errors.New("failed to get user from DB: " +
+			err.Error())
+	}
+
+	return s, userID, nil
+}
+
+func listAddresses(c *cli.Context) error {
+	s, userID, err := userStore(c)
+	if err != nil {
+		return err
+	}
+
+	uas, err := s.GetUserAddresses(userID)
+	if err != nil {
+		return errors.New("failed to get user addresses from DB: " +
+			err.Error())
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 4, 2, ' ', 0)
+	for _, ua := range uas {
+		fmt.Fprintf(w, "%s\t%s\n", ua.Coin, ua.Address)
+	}
+	return w.Flush()
+}
+
+// addressArgs validates coin and address flags.
+func addressArgs(c *cli.Context) (cn bestore.Coin, address string,
+	err error) {
+	cn, err = bestore.ParseCoin(c.String("coin"))
+	if err != nil {
+		return cn, "", errors.New("invalid coin")
+	}
+
+	address, err = handler.ValidateAddress(c.String("address"))
+	if err != nil {
+		return cn, "", err
+	}
+
+	return cn, address, nil
+}
+
+var openMStore = func(c *cli.Context) (mastore.Store, error) {
+	db, err := openDB(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = migration.Check(db)
+	if err != nil {
+		return nil, cli.NewExitError("failed to check DB schema: "+
+			err.Error(), 4)
+	}
+
+	return mastore.NewDBStore(db), nil
+}
+
+// adminArg returns login of existing admin from by flag.
+func adminArg(c *cli.Context, s bestore.Store) (string, error) {
+	by := c.String("by")
+	if by == "" {
+		return "", errors.New("admin login is required")
+	}
+
+	admins, err := s.GetAdmins()
+	if err != nil {
+		return "", errors.New("failed to get admins from DB: " + err.Error())
+	}
+
+	for _, a := range admins {
+		if a.Login == by {
+			return by, nil
+		}
+	}
+
+	return "", errors.New("admin not found")
+}
+
+// addressChangeStores opens stores for address change requested by admin
+// from by flag.
+func addressChangeStores(c *cli.Context) (bestore.Store, mastore.Store,
+	uint, string, error) {
+	s, userID, err := userStore(c)
+	if err != nil {
+		return nil, nil, 0, "", err
+	}
+
+	by, err := adminArg(c, s)
+	if err != nil {
+		return nil, nil, 0, "", err
+	}
+
+	ms, err := openMStore(c)
+	if err != nil {
+		return nil, nil, 0, "", err
+	}
+
+	return s, ms, userID, by, nil
+}
+
+// requestAddressChange adds pending address change, it takes effect after
+// another admin approves it in web UI.
+func requestAddressChange(c *cli.Context, ms mastore.Store,
+	ch mastore.AddressChange) error {
+	chs, err := ms.UserAddressChanges(ch.UserID)
+	if err != nil {
+		return errors.New("failed to get user address changes from DB: " +
+			err.Error())
+	}
+
+	if handler.ChangePending(chs, ch.Action, ch.Coin, ch.Address) {
+		return errors.New("the same address change is pending already")
+	}
+
+	id, err := ms.AddAddressChange(ch)
+	if err != nil {
+		return errors.New("failed to add address change to DB: " +
+			err.Error())
+	}
+
+	fmt.Fprintln(c.App.Writer, "Address change ID:", id)
+	fmt.Fprintln(c.App.Writer,
+		"Change is waiting for approval by another admin")
+
+	return nil
+}
+
+// userCoinAddresses returns user addresses of coin.
+func userCoinAddresses(s bestore.Store, userID uint,
+	cn bestore.Coin) ([]string, error) {
+	uas, err := s.GetUserAddresses(userID)
+	if err != nil {
+		return nil, errors.New("failed to get user addresses from DB: " +
+			err.Error())
+	}
+
+	var addrs []string
+	for _, ua := range uas {
+		if ua.Coin == cn {
+			addrs = append(addrs, ua.Address)
+		}
+	}
+
+	return addrs, nil
+}
+
+func containsAddress(addrs []string, address string) bool {
+	for _, a := range addrs {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
+// otherOwners returns emails of other users who have address.
+func otherOwners(s bestore.Store, userID uint, address string) ([]string,
+	error) {
+	addrs, err := addrindex.Read(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []string
+	for _, u := range addrindex.Owners(addrs, userID, address) {
+		emails = append(emails, u.Email)
+	}
+
+	return emails, nil
+}
+
+func addAddress(c *cli.Context) error {
+	cn, address, err := addressArgs(c)
+	if err != nil {
+		return err
+	}
+
+	s, ms, userID, by, err := addressChangeStores(c)
+	if err != nil {
+		return err
+	}
+
+	addrs, err := userCoinAddresses(s, userID, cn)
+	if err != nil {
+		return err
+	}
+
+	if containsAddress(addrs, address) {
+		return errors.New("address is added already")
+	}
+
+	owners, err := otherOwners(s, userID, address)
+	if err != nil {
+		return err
+	}
+
+	// Approval is refused if duplicate addresses are blocked.
+	if len(owners) > 0 {
+		fmt.Fprintln(os.Stderr, "Warning: address belongs to",
+			strings.Join(owners, ", "), "already")
+	}
+
+	return requestAddressChange(c, ms, mastore.AddressChange{
+		UserID:      userID,
+		Action:      mastore.AddressAdd,
+		Coin:        fmt.Sprintf("%s", cn),
+		Address:     address,
+		RequestedBy: by,
+	})
+}
+
+func removeAddress(c *cli.Context) error {
+	cn, address, err := addressArgs(c)
+	if err != nil {
+		return err
+	}
+
+	s, ms, userID, by, err := addressChangeStores(c)
+	if err != nil {
+		return err
+	}
+
+	addrs, err := userCoinAddresses(s, userID, cn)
+	if err != nil {
+		return err
+	}
+
+	if !containsAddress(addrs, address) {
+		return errors.New("user has no such address")
+	}
+
+	primaries, err := ms.PrimaryAddresses(userID)
+	if err != nil {
+		return errors.New("failed to get user primary addresses from DB: " +
+			err.Error())
+	}
+
+	coinStr := fmt.Sprintf("%s", cn)
+
+	// Payouts must not stop silently, so primary address is removed only
+	// with replacement, unless it is the last one.
+	var replacement string
+	if primaries[coinStr] == address && len(addrs) > 1 {
+		replacement = c.String("replacement")
+		if replacement == address || !containsAddress(addrs, replacement) {
+			return errors.New("choose new primary address among user " +
+				"addresses with replacement flag")
+		}
+	}
+
+	return requestAddressChange(c, ms, mastore.AddressChange{
+		UserID:      userID,
+		Action:      mastore.AddressRemove,
+		Coin:        coinStr,
+		Address:     address,
+		RequestedBy: by,
+		Replacement: replacement,
+	})
+}
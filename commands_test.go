@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/trash"
+	"github.com/stretchr/testify/assert"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+// runCommand runs mineradmin with args against mock stores and returns its
+// output.
+func runCommand(s bestore.Store, ms mastore.Store, args ...string) (string,
+	error) {
+	origStore, origMStore := openStore, openMStore
+	defer func() {
+		openStore, openMStore = origStore, origMStore
+	}()
+
+	openStore = func(*cli.Context) (bestore.Store, error) {
+		return s, nil
+	}
+	openMStore = func(*cli.Context) (mastore.Store, error) {
+		return ms, nil
+	}
+
+	var out bytes.Buffer
+
+	app := cli.NewApp()
+	app.Writer = &out
+	app.Commands = []cli.Command{
+		projectsCommand,
+		usersCommand,
+		addressesCommand,
+	}
+
+	err := app.Run(append([]string{"mineradmin"}, args...))
+
+	return out.String(), err
+}
+
+type commandTest struct {
+	name  string
+	args  []string
+	setup func(s *bestore.MockStore, ms *mastore.MockStore)
+	out   string
+	err   string
+}
+
+func runCommandTests(t *testing.T, tests []commandTest) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := bestore.NewMockStore()
+			ms := mastore.NewMockStore()
+			if tt.setup != nil {
+				tt.setup(s, ms)
+			}
+
+			out, err := runCommand(s, ms, tt.args...)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.out, out)
+
+			s.AssertExpectations(t)
+			ms.AssertExpectations(t)
+		})
+	}
+}
+
+// trashProjects makes projects with ids trashed.
+func trashProjects(ms *mastore.MockStore, ids ...uint) {
+	var items []mastore.TrashItem
+	for _, id := range ids {
+		items = append(items, mastore.TrashItem{Kind: trash.Projects,
+			EntityID: id})
+	}
+	ms.On("TrashItems", trash.Projects).Return(items, nil)
+}
+
+func Test_projectsCommand(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name: "list skips trashed",
+			args: []string{"projects", "list"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				s.On("ProjectsBalances").Return([]bestore.ProjectBalance{
+					{ProjectID: 1, ProjectName: "Alpha"},
+					{ProjectID: 2, ProjectName: "Beta"},
+				}, nil)
+				trashProjects(ms, 2)
+			},
+			out: "1  Alpha\n",
+		},
+		{
+			name: "rename invalid ID",
+			args: []string{"projects", "rename", "--name", "Gamma"},
+			err:  "invalid project ID",
+		},
+		{
+			name: "rename trashed",
+			args: []string{"projects", "rename", "--id", "2", "--name",
+				"Gamma"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				trashProjects(ms, 2)
+			},
+			err: "project is in trash",
+		},
+		{
+			name: "rename",
+			args: []string{"projects", "rename", "--id", "1", "--name",
+				"Gamma"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				trashProjects(ms, 2)
+				s.On("SetProjectName", uint(1), "Gamma").Return(nil)
+			},
+		},
+		{
+			name: "remove invalid ID",
+			args: []string{"projects", "remove", "--by", "login"},
+			err:  "invalid project ID",
+		},
+		{
+			name: "remove without admin",
+			args: []string{"projects", "remove", "--id", "1"},
+			err:  "admin login is required",
+		},
+		{
+			name: "remove by unknown admin",
+			args: []string{"projects", "remove", "--id", "1", "--by",
+				"other"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				s.On("GetAdmins").Return([]bestore.Admin{
+					{ID: 1, Login: "login"},
+				}, nil)
+			},
+			err: "admin not found",
+		},
+		{
+			name: "remove active",
+			args: []string{"projects", "remove", "--id", "1", "--by",
+				"login"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				s.On("GetAdmins").Return([]bestore.Admin{
+					{ID: 1, Login: "login"},
+				}, nil)
+				s.On("GetProject", uint(1)).
+					Return(bestore.Project{ID: 1, Name: "Alpha"}, nil)
+				trashProjects(ms)
+				ms.On("GetProjectMeta", uint(1)).Return(mastore.ProjectMeta{
+					ProjectID: 1,
+					Status:    mastore.ProjectActive,
+				}, nil)
+			},
+			err: "only archived project can be removed",
+		},
+		{
+			name: "remove trashed",
+			args: []string{"projects", "remove", "--id", "1", "--by",
+				"login"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				s.On("GetAdmins").Return([]bestore.Admin{
+					{ID: 1, Login: "login"},
+				}, nil)
+				s.On("GetProject", uint(1)).
+					Return(bestore.Project{ID: 1, Name: "Alpha"}, nil)
+				trashProjects(ms, 1)
+			},
+			err: "project is in trash already",
+		},
+		{
+			name: "remove archived",
+			args: []string{"projects", "remove", "--id", "1", "--by",
+				"login"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				s.On("GetAdmins").Return([]bestore.Admin{
+					{ID: 1, Login: "login"},
+				}, nil)
+				s.On("GetProject", uint(1)).
+					Return(bestore.Project{ID: 1, Name: "Alpha"}, nil)
+				trashProjects(ms)
+				ms.On("GetProjectMeta", uint(1)).Return(mastore.ProjectMeta{
+					ProjectID: 1,
+					Status:    mastore.ProjectArchived,
+				}, nil)
+				ms.On("AddTrashItem", mastore.TrashItem{
+					Kind:      trash.Projects,
+					EntityID:  1,
+					Name:      "Alpha",
+					DeletedBy: "login",
+				}).Return(uint(5), nil)
+			},
+		},
+	})
+}
+
+func Test_usersCommand(t *testing.T) {
+	runCommandTests(t, []commandTest{
+		{
+			name: "list",
+			args: []string{"users", "list"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				s.On("GetUsers").Return([]bestore.User{
+					{ID: 3, Email: "a@example.com", Name: "Alice"},
+				}, nil)
+			},
+			out: "3  a@example.com  Alice\n",
+		},
+		{
+			name: "add invalid email",
+			args: []string{"users", "add", "--email", "a", "--name",
+				"Alice"},
+			err: "invalid email format",
+		},
+		{
+			name: "add",
+			args: []string{"users", "add", "--email", "a@example.com",
+				"--name", "Alice"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				s.On("AddUser", "", "a@example.com", "", "Alice", "").
+					Return(uint(3), nil)
+			},
+			out: "User ID: 3\n",
+		},
+	})
+}
+
+func Test_addressesCommand(t *testing.T) {
+	// user makes user 3 with ETH addresses exist.
+	user := func(s *bestore.MockStore, addrs ...string) {
+		s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3}, nil)
+
+		var uas []bestore.UserAddress
+		for _, a := range addrs {
+			uas = append(uas, bestore.UserAddress{UserID: 3,
+				Coin: bestore.ETH, Address: a})
+		}
+		s.On("GetUserAddresses", uint(3)).Return(uas, nil)
+	}
+	admins := func(s *bestore.MockStore) {
+		s.On("GetAdmins").Return([]bestore.Admin{{ID: 1, Login: "login"}},
+			nil)
+	}
+
+	runCommandTests(t, []commandTest{
+		{
+			name: "list invalid user ID",
+			args: []string{"addresses", "list"},
+			err:  "invalid user ID",
+		},
+		{
+			name: "list",
+			args: []string{"addresses", "list", "--user-id", "3"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				user(s, "addr")
+			},
+			out: "ETH  addr\n",
+		},
+		{
+			name: "add invalid coin",
+			args: []string{"addresses", "add", "--user-id", "3", "--coin",
+				"XXX", "--address", "addr", "--by", "login"},
+			err: "invalid coin",
+		},
+		{
+			name: "add without admin",
+			args: []string{"addresses", "add", "--user-id", "3", "--coin",
+				"ETH", "--address", "addr"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				s.On("GetUserByID", uint(3)).
+					Return(bestore.User{ID: 3}, nil)
+			},
+			err: "admin login is required",
+		},
+		{
+			name: "add owned",
+			args: []string{"addresses", "add", "--user-id", "3", "--coin",
+				"ETH", "--address", "addr", "--by", "login"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				user(s, "addr")
+				admins(s)
+			},
+			err: "address is added already",
+		},
+		{
+			name: "add",
+			args: []string{"addresses", "add", "--user-id", "3", "--coin",
+				"ETH", "--address", "addr", "--by", "login"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				user(s)
+				admins(s)
+				s.On("GetUsers").Return([]bestore.User{{ID: 3}}, nil)
+				ms.On("UserAddressChanges", uint(3)).
+					Return([]mastore.AddressChange{}, nil)
+				ms.On("AddAddressChange", mastore.AddressChange{
+					UserID:      3,
+					Action:      mastore.AddressAdd,
+					Coin:        "ETH",
+					Address:     "addr",
+					RequestedBy: "login",
+				}).Return(uint(7), nil)
+			},
+			out: "Address change ID: 7\n" +
+				"Change is waiting for approval by another admin\n",
+		},
+		{
+			name: "add pending",
+			args: []string{"addresses", "add", "--user-id", "3", "--coin",
+				"ETH", "--address", "addr", "--by", "login"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				user(s)
+				admins(s)
+				s.On("GetUsers").Return([]bestore.User{{ID: 3}}, nil)
+				ms.On("UserAddressChanges", uint(3)).
+					Return([]mastore.AddressChange{{
+						UserID:  3,
+						Action:  mastore.AddressAdd,
+						Coin:    "ETH",
+						Address: "addr",
+						Status:  mastore.ChangePending,
+					}}, nil)
+			},
+			err: "the same address change is pending already",
+		},
+		{
+			name: "remove not owned",
+			args: []string{"addresses", "remove", "--user-id", "3",
+				"--coin", "ETH", "--address", "other", "--by", "login"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				user(s, "addr")
+				admins(s)
+			},
+			err: "user has no such address",
+		},
+		{
+			name: "remove primary without replacement",
+			args: []string{"addresses", "remove", "--user-id", "3",
+				"--coin", "ETH", "--address", "addr1", "--by", "login"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				user(s, "addr1", "addr2")
+				admins(s)
+				ms.On("PrimaryAddresses", uint(3)).
+					Return(map[string]string{"ETH": "addr1"}, nil)
+			},
+			err: "choose new primary address among user addresses with " +
+				"replacement flag",
+		},
+		{
+			name: "remove primary",
+			args: []string{"addresses", "remove", "--user-id", "3",
+				"--coin", "ETH", "--address", "addr1", "--by", "login",
+				"--replacement", "addr2"},
+			setup: func(s *bestore.MockStore, ms *mastore.MockStore) {
+				user(s, "addr1", "addr2")
+				admins(s)
+				ms.On("PrimaryAddresses", uint(3)).
+					Return(map[string]string{"ETH": "addr1"}, nil)
+				ms.On("UserAddressChanges", uint(3)).
+					Return([]mastore.AddressChange{}, nil)
+				ms.On("AddAddressChange", mastore.AddressChange{
+					UserID:      3,
+					Action:      mastore.AddressRemove,
+					Coin:        "ETH",
+					Address:     "addr1",
+					RequestedBy: "login",
+					Replacement: "addr2",
+				}).Return(uint(7), nil)
+			},
+			out: "Address change ID: 7\n" +
+				"Change is waiting for approval by another admin\n",
+		},
+	})
+}
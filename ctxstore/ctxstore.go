@@ -0,0 +1,211 @@
+// Package ctxstore binds bestore.Store calls to context. bestore does not
+// take context, so its queries can not be cancelled: reads are abandoned
+// when context is done and their results are dropped, writes are not
+// started when context is done already and otherwise run to completion, so
+// caller never loses track of changes it made.
+package ctxstore
+
+import (
+	"context"
+
+	"github.com/boomstarternetwork/bestore"
+)
+
+// Store is bestore.Store bound to context. Methods it does not know are
+// passed to wrapped store as is.
+type Store struct {
+	bestore.Store
+	ctx context.Context
+}
+
+func New(s bestore.Store, ctx context.Context) Store {
+	return Store{Store: s, ctx: ctx}
+}
+
+// read runs f until it returns or context is done. f must only set
+// variables which are read after read returns nil.
+func (s Store) read(f func() error) error {
+	err := s.ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- f()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// write runs f unless context is done already.
+func (s Store) write(f func() error) error {
+	err := s.ctx.Err()
+	if err != nil {
+		return err
+	}
+	return f()
+}
+
+func (s Store) AddAdmin(login string) (string, error) {
+	var password string
+	err := s.write(func() (err error) {
+		password, err = s.Store.AddAdmin(login)
+		return err
+	})
+	return password, err
+}
+
+func (s Store) CheckAdminPassword(login string, password string) error {
+	return s.read(func() error {
+		return s.Store.CheckAdminPassword(login, password)
+	})
+}
+
+func (s Store) ResetAdminPassword(id uint) (string, error) {
+	var password string
+	err := s.write(func() (err error) {
+		password, err = s.Store.ResetAdminPassword(id)
+		return err
+	})
+	return password, err
+}
+
+func (s Store) RemoveAdmin(id uint) error {
+	return s.write(func() error {
+		return s.Store.RemoveAdmin(id)
+	})
+}
+
+func (s Store) GetAdmins() ([]bestore.Admin, error) {
+	var admins []bestore.Admin
+	err := s.read(func() (err error) {
+		admins, err = s.Store.GetAdmins()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return admins, nil
+}
+
+func (s Store) AddProject(name string) error {
+	return s.write(func() error {
+		return s.Store.AddProject(name)
+	})
+}
+
+func (s Store) SetProjectName(id uint, name string) error {
+	return s.write(func() error {
+		return s.Store.SetProjectName(id, name)
+	})
+}
+
+func (s Store) RemoveProject(id uint) error {
+	return s.write(func() error {
+		return s.Store.RemoveProject(id)
+	})
+}
+
+func (s Store) GetProject(id uint) (bestore.Project, error) {
+	var p bestore.Project
+	err := s.read(func() (err error) {
+		p, err = s.Store.GetProject(id)
+		return err
+	})
+	if err != nil {
+		return bestore.Project{}, err
+	}
+	return p, nil
+}
+
+func (s Store) ProjectsBalances() ([]bestore.ProjectBalance, error) {
+	var pbs []bestore.ProjectBalance
+	err := s.read(func() (err error) {
+		pbs, err = s.Store.ProjectsBalances()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pbs, nil
+}
+
+func (s Store) ProjectUsersBalances(projectID uint) ([]bestore.UserBalance,
+	error) {
+	var ubs []bestore.UserBalance
+	err := s.read(func() (err error) {
+		ubs, err = s.Store.ProjectUsersBalances(projectID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ubs, nil
+}
+
+func (s Store) AddUser(ethAddress string, email string, password string,
+	name string, country string) (uint, error) {
+	var id uint
+	err := s.write(func() (err error) {
+		id, err = s.Store.AddUser(ethAddress, email, password, name, country)
+		return err
+	})
+	return id, err
+}
+
+func (s Store) GetUserByID(id uint) (bestore.User, error) {
+	var u bestore.User
+	err := s.read(func() (err error) {
+		u, err = s.Store.GetUserByID(id)
+		return err
+	})
+	if err != nil {
+		return bestore.User{}, err
+	}
+	return u, nil
+}
+
+func (s Store) GetUsers() ([]bestore.User, error) {
+	var users []bestore.User
+	err := s.read(func() (err error) {
+		users, err = s.Store.GetUsers()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s Store) GetUserAddresses(userID uint) ([]bestore.UserAddress, error) {
+	var uas []bestore.UserAddress
+	err := s.read(func() (err error) {
+		uas, err = s.Store.GetUserAddresses(userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uas, nil
+}
+
+func (s Store) AddUserAddress(userID uint, coin bestore.Coin,
+	address string) error {
+	return s.write(func() error {
+		return s.Store.AddUserAddress(userID, coin, address)
+	})
+}
+
+func (s Store) RemoveUserAddress(userID uint, coin bestore.Coin,
+	address string) error {
+	return s.write(func() error {
+		return s.Store.RemoveUserAddress(userID, coin, address)
+	})
+}
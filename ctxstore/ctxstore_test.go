@@ -0,0 +1,54 @@
+package ctxstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Store_read(t *testing.T) {
+	ms := bestore.NewMockStore()
+	ms.On("GetUsers").Return([]bestore.User{{ID: 1}}, nil)
+
+	users, err := New(ms, context.Background()).GetUsers()
+	assert.NoError(t, err)
+	assert.Equal(t, []bestore.User{{ID: 1}}, users)
+}
+
+func Test_Store_readTimeout(t *testing.T) {
+	ms := bestore.NewMockStore()
+	ms.On("ProjectsBalances").After(time.Second).
+		Return([]bestore.ProjectBalance{{ProjectID: 1}}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+
+	pbs, err := New(ms, ctx).ProjectsBalances()
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Nil(t, pbs)
+	assert.True(t, time.Since(started) < time.Second)
+}
+
+func Test_Store_doneContext(t *testing.T) {
+	ms := bestore.NewMockStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := New(ms, ctx)
+
+	_, err := s.GetUserByID(1)
+	assert.Equal(t, context.Canceled, err)
+
+	err = s.AddUserAddress(1, bestore.ETH, "addr")
+	assert.Equal(t, context.Canceled, err)
+
+	ms.AssertNotCalled(t, "GetUserByID", uint(1))
+	ms.AssertNotCalled(t, "AddUserAddress", uint(1), bestore.ETH, "addr")
+}
@@ -8,43 +8,70 @@ import (
 	"strings"
 
 	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mail"
+	"github.com/boomstarternetwork/mineradmin/trash"
+	"github.com/boomstarternetwork/mineradmin/webhook"
 	"github.com/labstack/echo"
 )
 
 type adminsPageData struct {
-	CSRFToken string
-	Admins    []bestore.Admin
+	Admins []bestore.Admin
+	Form   formData
 }
 
 func (h Handler) Admins(c echo.Context) error {
-	admins, err := h.store.GetAdmins()
+	return h.renderAdmins(c, http.StatusOK, formData{})
+}
+
+func (h Handler) renderAdmins(c echo.Context, code int, form formData) error {
+	admins, err := h.accountStore(c).GetAdmins()
 	if err != nil {
 		return errors.New("failed to get admins from DB: " + err.Error())
 	}
-	return c.Render(http.StatusOK, "admins", adminsPageData{
-		CSRFToken: c.Get("csrf-token").(string),
-		Admins:    admins,
-	})
+
+	trashed, err := h.accountBin(c).Hidden(trash.Admins)
+	if err != nil {
+		return errors.New("failed to get trashed admins from DB: " +
+			err.Error())
+	}
+
+	data := adminsPageData{Form: form}
+
+	for _, a := range admins {
+		if !trashed[a.ID] {
+			data.Admins = append(data.Admins, a)
+		}
+	}
+
+	return c.Render(code, "admins", data)
 }
 
 var AdminLoginRe = regexp.MustCompile(`[\w._-]*\w[\w._-]*`)
 
 func (h Handler) NewAdmin(c echo.Context) error {
-	login := c.FormValue("login")
-	login = strings.TrimSpace(login)
+	form := newFormData(c, "login")
+
+	login := strings.TrimSpace(form.Value("login"))
 	if login == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "blank login")
+		form.Errors["login"] = tr(c, "blank login")
+	} else if !AdminLoginRe.MatchString(login) {
+		form.Errors["login"] = tr(c, "invalid login format")
 	}
 
-	if !AdminLoginRe.MatchString(login) {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid login format")
+	if len(form.Errors) > 0 {
+		return h.renderAdmins(c, http.StatusBadRequest, form)
 	}
 
-	password, err := h.store.AddAdmin(login)
+	password, err := h.accountStore(c).AddAdmin(login)
 	if err != nil {
-		return errors.New("failed to add admin to DB: " + err.Error())
+		return h.redirectWithError(c, "/admins", "Failed to add admin", err)
 	}
 
+	by := adminLogin(c)
+	h.notify(c, func(n mail.Notifier) error {
+		return n.AdminCreated(login, by)
+	})
+
 	return c.Render(http.StatusOK, "admin/password", password)
 }
 
@@ -53,7 +80,8 @@ func (h Handler) EditAdmin(c echo.Context) error {
 
 	id64, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid admin ID")
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid admin ID"))
 	}
 
 	id := uint(id64)
@@ -62,21 +90,53 @@ func (h Handler) EditAdmin(c echo.Context) error {
 
 	switch action {
 	case "reset-password":
-		newPassword, err := h.store.ResetAdminPassword(id)
+		newPassword, err := h.accountStore(c).ResetAdminPassword(id)
 		if err != nil {
-			return errors.New("failed to reset password in DB: " + err.Error())
+			return h.redirectWithError(c, "/admins",
+				"Failed to reset admin password", err)
 		}
 
+		h.notifyPasswordReset(c, id)
+
+		h.emit(c, webhook.AdminUpdated, echo.Map{"id": id,
+			"action": action})
+
 		return c.Render(http.StatusOK, "admin/password", newPassword)
 
 	case "remove":
-		err := h.store.RemoveAdmin(id)
+		admins, err := h.accountStore(c).GetAdmins()
 		if err != nil {
-			return errors.New("failed to remove from DB: " + err.Error())
+			return h.redirectWithError(c, "/admins",
+				"Failed to remove admin", err)
+		}
+
+		for _, a := range admins {
+			if a.ID != id {
+				continue
+			}
+
+			if a.Login == adminLogin(c) {
+				return h.redirectWithFlash(c, "/admins", FlashError,
+					"You can not remove yourself")
+			}
+
+			itemID, err := h.accountBin(c).RemoveAdmin(id, a.Login,
+				adminLogin(c))
+			if err != nil {
+				return h.redirectWithError(c, "/admins",
+					"Failed to remove admin", err)
+			}
+
+			h.emit(c, webhook.AdminUpdated, echo.Map{"id": id,
+				"action": action, "login": a.Login})
+
+			return h.redirectWithUndo(c, "/admins", trash.Admins, itemID,
+				`Admin "%s" moved to trash`, a.Login)
 		}
 
-		return c.Redirect(http.StatusFound, "/admins")
+		return echo.NewHTTPError(http.StatusNotFound,
+			tr(c, "admin not found"))
 	}
 
-	return echo.NewHTTPError(http.StatusBadRequest, "unknown action")
+	return echo.NewHTTPError(http.StatusBadRequest, tr(c, "unknown action"))
 }
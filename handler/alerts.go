@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/labstack/echo"
+)
+
+// resolvedAlertsShown is how many recently resolved alerts alerts page
+// shows.
+const resolvedAlertsShown = 50
+
+type alertsPageData struct {
+	Open     []mastore.Alert
+	Resolved []mastore.Alert
+}
+
+func (h Handler) Alerts(c echo.Context) error {
+	open, err := h.mstore(c).OpenAlerts()
+	if err != nil {
+		return errors.New("failed to get open alerts from DB: " + err.Error())
+	}
+
+	// Alerts nobody looked at go first.
+	sort.SliceStable(open, func(i, j int) bool {
+		return open[i].AcknowledgedBy == "" && open[j].AcknowledgedBy != ""
+	})
+
+	resolved, err := h.mstore(c).ResolvedAlerts(resolvedAlertsShown)
+	if err != nil {
+		return errors.New("failed to get resolved alerts from DB: " +
+			err.Error())
+	}
+
+	return c.Render(http.StatusOK, "alerts", alertsPageData{
+		Open:     open,
+		Resolved: resolved,
+	})
+}
+
+func (h Handler) EditAlert(c echo.Context) error {
+	id64, err := strconv.ParseUint(c.Param("alert-id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid alert ID"))
+	}
+
+	a, err := h.mstore(c).GetAlert(uint(id64))
+	if err != nil {
+		if err == mastore.ErrNotFound {
+			return echo.NewHTTPError(http.StatusNotFound,
+				tr(c, "alert not found"))
+		}
+		return errors.New("failed to get alert from DB: " + err.Error())
+	}
+
+	switch c.FormValue("action") {
+	case "acknowledge":
+		err := h.mstore(c).AcknowledgeAlert(a.ID, adminLogin(c))
+		if err == mastore.ErrNotFound {
+			return h.redirectWithFlash(c, "/alerts", FlashWarning,
+				"Alert is resolved already")
+		}
+		if err != nil {
+			return h.redirectWithError(c, "/alerts",
+				"Failed to acknowledge alert", err)
+		}
+
+		return h.redirectWithFlash(c, "/alerts", FlashSuccess,
+			"Alert about %s acknowledged", a.Email)
+	}
+
+	return echo.NewHTTPError(http.StatusBadRequest, tr(c, "unknown action"))
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_Alerts(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("OpenAlerts").Return([]mastore.Alert{
+		{ID: 7, Kind: mastore.AlertStale, Email: "a@example.com",
+			AcknowledgedBy: "login"},
+		{ID: 8, Kind: mastore.AlertStale, Email: "b@example.com"},
+	}, nil)
+	th.ms.On("ResolvedAlerts", 50).Return([]mastore.Alert{
+		{ID: 5, Kind: mastore.AlertStale, Email: "c@example.com"},
+	}, nil)
+
+	res := th.serve("/alerts", th.Alerts,
+		httptest.NewRequest(http.MethodGet, "/alerts", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "alerts", th.rendered.name)
+
+	// Alerts nobody looked at go first.
+	data := th.rendered.data.(alertsPageData)
+	if assert.Len(t, data.Open, 2) {
+		assert.Equal(t, uint(8), data.Open[0].ID)
+		assert.Equal(t, uint(7), data.Open[1].ID)
+	}
+	if assert.Len(t, data.Resolved, 1) {
+		assert.Equal(t, uint(5), data.Resolved[0].ID)
+	}
+}
+
+func Test_EditAlert_acknowledge(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("GetAlert", uint(7)).Return(mastore.Alert{ID: 7,
+		Kind: mastore.AlertStale, Email: "a@example.com"}, nil)
+	th.ms.On("AcknowledgeAlert", uint(7), "login").Return(nil)
+
+	res := th.serve("/alerts/:alert-id", th.EditAlert,
+		newFormRequest("/alerts/7", url.Values{"action": {"acknowledge"}}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/alerts", res.Header().Get("Location"))
+	assert.Equal(t, []Flash{{Kind: FlashSuccess,
+		Message: "Alert about a@example.com acknowledged"}},
+		th.flashes(res))
+
+	th.ms.AssertExpectations(t)
+}
+
+func Test_EditAlert_resolved(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("GetAlert", uint(7)).Return(mastore.Alert{ID: 7,
+		Kind: mastore.AlertStale, Email: "a@example.com"}, nil)
+	th.ms.On("AcknowledgeAlert", uint(7), "login").
+		Return(mastore.ErrNotFound)
+
+	res := th.serve("/alerts/:alert-id", th.EditAlert,
+		newFormRequest("/alerts/7", url.Values{"action": {"acknowledge"}}))
+
+	// Alert was resolved after page was shown.
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, []Flash{{Kind: FlashWarning,
+		Message: "Alert is resolved already"}}, th.flashes(res))
+}
+
+func Test_EditAlert_notFound(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("GetAlert", uint(7)).Return(mastore.Alert{}, mastore.ErrNotFound)
+
+	res := th.serve("/alerts/:alert-id", th.EditAlert,
+		newFormRequest("/alerts/7", url.Values{"action": {"acknowledge"}}))
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+
+	th.ms.AssertNotCalled(t, "AcknowledgeAlert", mock.Anything,
+		mock.Anything)
+}
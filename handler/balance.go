@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/balance"
+	"github.com/labstack/echo"
+)
+
+const pricesKey = "prices"
+
+// coinPrices returns coin prices from price source once per request. Nothing is
+// returned if price source is not configured or failed, so balances are
+// shown without fiat value. Failure is remembered for request too, so
+// request context is not changed after the first call.
+func (h Handler) coinPrices(c echo.Context) (balance.Prices, bool) {
+	if h.prices == nil {
+		return balance.Prices{}, false
+	}
+
+	if p, ok := c.Get(pricesKey).(*balance.Prices); ok {
+		if p == nil {
+			return balance.Prices{}, false
+		}
+		return *p, true
+	}
+
+	p, err := h.prices.Prices()
+	if err != nil {
+		c.Logger().Warn("failed to get coin prices: " + err.Error())
+		c.Set(pricesKey, (*balance.Prices)(nil))
+		return balance.Prices{}, false
+	}
+
+	c.Set(pricesKey, &p)
+
+	return p, true
+}
+
+// totals sums coin amounts and values them if prices are available.
+func (h Handler) totals(c echo.Context,
+	lists ...[]bestore.CoinAmount) (balance.Totals, error) {
+	var prices *balance.Prices
+	if p, ok := h.coinPrices(c); ok {
+		prices = &p
+	}
+	return sumTotals(prices, lists...)
+}
+
+// sumTotals sums coin amounts and values them if prices are not nil.
+func sumTotals(prices *balance.Prices,
+	lists ...[]bestore.CoinAmount) (balance.Totals, error) {
+	t, err := balance.Sum(lists...)
+	if err != nil {
+		return balance.Totals{}, err
+	}
+
+	if prices != nil {
+		t = t.Valuate(*prices)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,409 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/webhook"
+	"github.com/labstack/echo"
+)
+
+const pendingChangesKey = "pending-changes"
+
+// PendingChanges is middleware which lets templates show number of pending
+// address changes, so admins notice changes waiting for their approval.
+// Changes are counted only if page shows them.
+func (h Handler) PendingChanges(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if c.Request().Method == http.MethodGet {
+			c.Set(pendingChangesKey, func() int {
+				n, err := h.mstore(c).CountPendingAddressChanges()
+				if err != nil {
+					c.Logger().Error("failed to count pending address " +
+						"changes: " + err.Error())
+				}
+				return n
+			})
+		}
+		return next(c)
+	}
+}
+
+// pendingChanges returns number of pending address changes.
+func pendingChanges(c echo.Context) int {
+	if c == nil {
+		return 0
+	}
+	count, ok := c.Get(pendingChangesKey).(func() int)
+	if !ok {
+		return 0
+	}
+	return count()
+}
+
+type addressChange struct {
+	mastore.AddressChange
+	User bestore.User
+	// Mine is true if change is requested by current admin, so it can be
+	// cancelled but not approved.
+	Mine bool
+	// Back is where to return after decision, "user" for user addresses
+	// page.
+	Back string
+}
+
+type addressChangesPageData struct {
+	Changes []addressChange
+}
+
+func (h Handler) AddressChanges(c echo.Context) error {
+	chs, err := h.mstore(c).PendingAddressChanges()
+	if err != nil {
+		return errors.New("failed to get pending address changes from DB: " +
+			err.Error())
+	}
+
+	data := addressChangesPageData{}
+	login := adminLogin(c)
+
+	users := map[uint]bestore.User{}
+
+	for _, ch := range chs {
+		user, ok := users[ch.UserID]
+		if !ok {
+			user, err = h.store(c).GetUserByID(ch.UserID)
+			if err != nil && !bestore.NotFound(err) {
+				return errors.New("failed to get user from DB: " +
+					err.Error())
+			}
+			user.ID = ch.UserID
+			users[ch.UserID] = user
+		}
+
+		data.Changes = append(data.Changes, addressChange{
+			AddressChange: ch,
+			User:          user,
+			Mine:          ch.RequestedBy == login,
+		})
+	}
+
+	return c.Render(http.StatusOK, "address-changes", data)
+}
+
+// errChangePending is returned by queueAddressChange if the same change is
+// pending already.
+var errChangePending = errors.New("the same address change is pending " +
+	"already")
+
+// queueAddressChange adds pending address change unless the same change
+// is pending already. replacement is new primary address if primary one is
+// removed.
+func (h Handler) queueAddressChange(c echo.Context, userID uint,
+	action string, cn bestore.Coin, address string,
+	replacement string) error {
+	chs, err := h.mstore(c).UserAddressChanges(userID)
+	if err != nil {
+		return errors.New("failed to get user address changes from DB: " +
+			err.Error())
+	}
+
+	coinStr := fmt.Sprintf("%s", cn)
+
+	if ChangePending(chs, action, coinStr, address) {
+		return errChangePending
+	}
+
+	_, err = h.mstore(c).AddAddressChange(mastore.AddressChange{
+		UserID:      userID,
+		Action:      action,
+		Coin:        coinStr,
+		Address:     address,
+		RequestedBy: adminLogin(c),
+		Replacement: replacement,
+	})
+	if err != nil {
+		return errors.New("failed to add address change to DB: " +
+			err.Error())
+	}
+
+	h.emit(c, webhook.AddressesUpdated, addressEvent{UserID: userID,
+		Action: action, Coin: coinStr, Address: address,
+		Status: mastore.ChangePending})
+
+	return nil
+}
+
+// requestAddressChange queues address change and redirects to user
+// addresses telling that change waits for approval.
+func (h Handler) requestAddressChange(c echo.Context, userID uint,
+	action string, cn bestore.Coin, address string,
+	replacement string) error {
+	addrsPath := fmt.Sprintf("/users/%d/addresses", userID)
+
+	err := h.queueAddressChange(c, userID, action, cn, address, replacement)
+	if err == errChangePending {
+		return h.redirectWithFlash(c, addrsPath, FlashError,
+			"The same address change is pending already")
+	}
+	if err != nil {
+		return h.redirectWithError(c, addrsPath,
+			"Failed to request address change", err)
+	}
+
+	return h.redirectPending(c, addrsPath, action, cn, address)
+}
+
+// redirectPending redirects telling that address change waits for approval.
+func (h Handler) redirectPending(c echo.Context, path string, action string,
+	cn bestore.Coin, address string) error {
+	switch action {
+	case mastore.AddressAdd:
+		return h.redirectWithFlash(c, path, FlashSuccess,
+			`Adding %s address "%s" is waiting for approval by another admin`,
+			cn, address)
+	case mastore.AddressPrimary:
+		return h.redirectWithFlash(c, path, FlashSuccess,
+			`Making %s address "%s" primary is waiting for approval by another admin`,
+			cn, address)
+	}
+
+	return h.redirectWithFlash(c, path, FlashSuccess,
+		`Removing %s address "%s" is waiting for approval by another admin`,
+		cn, address)
+}
+
+// ChangePending checks that the same address change is pending among
+// changes.
+func ChangePending(chs []mastore.AddressChange, action string, coin string,
+	address string) bool {
+	for _, ch := range chs {
+		if ch.Status == mastore.ChangePending && ch.Action == action &&
+			ch.Coin == coin && ch.Address == address {
+			return true
+		}
+	}
+	return false
+}
+
+func (h Handler) EditAddressChange(c echo.Context) error {
+	id64, err := strconv.ParseUint(c.Param("change-id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid address change ID"))
+	}
+
+	id := uint(id64)
+
+	ch, err := h.mstore(c).GetAddressChange(id)
+	if err != nil {
+		if err == mastore.ErrNotFound {
+			return echo.NewHTTPError(http.StatusNotFound,
+				tr(c, "address change not found"))
+		}
+		return errors.New("failed to get address change from DB: " +
+			err.Error())
+	}
+
+	backPath := "/address-changes"
+	if c.FormValue("back") == "user" {
+		// Changes can be decided on user addresses page too.
+		backPath = fmt.Sprintf("/users/%d/addresses", ch.UserID)
+	}
+
+	login := adminLogin(c)
+
+	switch c.FormValue("action") {
+	case "approve":
+		if ch.RequestedBy == login {
+			return h.redirectWithFlash(c, backPath, FlashError,
+				"Address change must be approved by another admin")
+		}
+		return h.approveAddressChange(c, backPath, ch, login)
+
+	case "reject":
+		if ch.RequestedBy == login {
+			return h.redirectWithFlash(c, backPath, FlashError,
+				"Cancel your own address change instead of rejecting it")
+		}
+
+		err := h.mstore(c).DecideAddressChange(id, mastore.ChangeRejected, login,
+			time.Time{})
+		if err != nil {
+			return h.decideError(c, backPath, err)
+		}
+
+		h.emit(c, webhook.AddressChangeRejected, addressEvent{
+			UserID: ch.UserID, Action: ch.Action, Coin: ch.Coin,
+			Address: ch.Address, Status: mastore.ChangeRejected})
+
+		return h.redirectWithFlash(c, backPath, FlashSuccess,
+			"Address change rejected")
+
+	case "cancel":
+		if ch.RequestedBy != login {
+			return h.redirectWithFlash(c, backPath, FlashError,
+				"Only admin who requested address change can cancel it")
+		}
+
+		err := h.mstore(c).DecideAddressChange(id, mastore.ChangeCancelled,
+			login, time.Time{})
+		if err != nil {
+			return h.decideError(c, backPath, err)
+		}
+
+		h.emit(c, webhook.AddressChangeCancelled, addressEvent{
+			UserID: ch.UserID, Action: ch.Action, Coin: ch.Coin,
+			Address: ch.Address, Status: mastore.ChangeCancelled})
+
+		return h.redirectWithFlash(c, backPath, FlashSuccess,
+			"Address change cancelled")
+	}
+
+	return echo.NewHTTPError(http.StatusBadRequest, tr(c, "unknown action"))
+}
+
+func (h Handler) decideError(c echo.Context, backPath string,
+	err error) error {
+	if err == mastore.ErrNotFound {
+		return h.redirectWithFlash(c, backPath, FlashError,
+			"Address change is not pending anymore")
+	}
+	return h.redirectWithError(c, backPath,
+		"Failed to decide address change", err)
+}
+
+// approveAddressChange marks change approved, so nobody else applies it
+// concurrently, and applies it to bestore. Change is reopened if it fails to
+// apply.
+func (h Handler) approveAddressChange(c echo.Context, backPath string,
+	ch mastore.AddressChange, login string) error {
+	cn, err := bestore.ParseCoin(ch.Coin)
+	if err != nil {
+		return h.redirectWithError(c, backPath,
+			"Failed to apply address change", err)
+	}
+
+	if ch.Action == mastore.AddressAdd && h.blockDuplicates {
+		// Address could be added to another user since change was
+		// requested.
+		owners, err := h.otherOwners(c, ch.UserID, ch.Address)
+		if err != nil {
+			return h.redirectWithError(c, backPath,
+				"Failed to apply address change", err)
+		}
+		if len(owners) > 0 {
+			return h.redirectWithFlash(c, backPath, FlashError,
+				"Address belongs to %s already", ownersEmails(owners))
+		}
+	}
+
+	addrs, primary, err := h.userPayoutAddresses(c, ch.UserID)
+	if err != nil {
+		return h.redirectWithError(c, backPath,
+			"Failed to apply address change", err)
+	}
+
+	// Address could be added by another change since this one was
+	// requested.
+	if ch.Action == mastore.AddressAdd && hasAddress(addrs[cn], ch.Address) {
+		return h.redirectWithFlash(c, backPath, FlashError,
+			"User has this address already, reject the change")
+	}
+
+	if ch.Action != mastore.AddressAdd {
+		// Address or replacement could be removed since change was
+		// requested.
+		if !hasAddress(addrs[cn], ch.Address) || (ch.Replacement != "" &&
+			!hasAddress(addrs[cn], ch.Replacement)) {
+			return h.redirectWithFlash(c, backPath, FlashError,
+				"User has no such address anymore, reject the change")
+		}
+		// Address could become primary since removal was requested,
+		// payouts would stop without replacement then.
+		if ch.Action == mastore.AddressRemove && ch.Replacement == "" &&
+			primary[cn] == ch.Address && len(addrs[cn]) > 1 {
+			return h.redirectWithFlash(c, backPath, FlashError,
+				"Address is primary now, reject the change and choose a replacement primary address")
+		}
+	}
+
+	var eligibleAt time.Time
+	if ch.Action == mastore.AddressAdd && h.addressCooldown > 0 {
+		eligibleAt = time.Now().Add(h.addressCooldown)
+	}
+
+	err = h.mstore(c).DecideAddressChange(ch.ID, mastore.ChangeApproved, login,
+		eligibleAt)
+	if err != nil {
+		return h.decideError(c, backPath, err)
+	}
+
+	switch ch.Action {
+	case mastore.AddressAdd:
+		err = h.store(c).AddUserAddress(ch.UserID, cn, ch.Address)
+	case mastore.AddressPrimary:
+		err = h.mstore(c).SetPrimaryAddress(ch.UserID, ch.Coin, ch.Address,
+			ch.RequestedBy)
+	default:
+		_, err = h.bin(c).RemoveAddress(ch.UserID, cn, ch.Address,
+			ch.RequestedBy)
+	}
+	if err != nil {
+		rerr := h.mstore(c).ReopenAddressChange(ch.ID)
+		if rerr != nil {
+			c.Logger().Error("failed to reopen address change: " +
+				rerr.Error())
+		}
+		return h.redirectWithError(c, backPath,
+			"Failed to apply address change", err)
+	}
+
+	if ch.Action != mastore.AddressPrimary {
+		h.addressesChanged(c)
+	}
+
+	h.notifyAddressChanged(c, ch, eligibleAt)
+
+	h.emit(c, webhook.AddressesUpdated, addressEvent{UserID: ch.UserID,
+		Action: ch.Action, Coin: ch.Coin, Address: ch.Address,
+		Status: mastore.ChangeApproved})
+
+	if ch.Action == mastore.AddressAdd {
+		err = h.mstore(c).TrackAddress(ch.UserID, ch.Coin, ch.Address,
+			ch.RequestedBy)
+		if err != nil {
+			c.Logger().Error("failed to track added address: " +
+				err.Error())
+		}
+
+		return h.redirectWithFlash(c, backPath, FlashSuccess,
+			`%s address "%s" added`, cn, ch.Address)
+	}
+
+	if ch.Action == mastore.AddressPrimary {
+		return h.redirectWithFlash(c, backPath, FlashSuccess,
+			`%s address "%s" is primary now`, cn, ch.Address)
+	}
+
+	primaries, err := h.mstore(c).PrimaryAddresses(ch.UserID)
+	if err == nil && primaries[ch.Coin] == ch.Address {
+		if ch.Replacement != "" {
+			err = h.mstore(c).SetPrimaryAddress(ch.UserID, ch.Coin,
+				ch.Replacement, ch.RequestedBy)
+		} else {
+			err = h.mstore(c).RemovePrimaryAddress(ch.UserID, ch.Coin)
+		}
+	}
+	if err != nil {
+		c.Logger().Error("failed to replace removed primary address: " +
+			err.Error())
+	}
+
+	return h.redirectWithFlash(c, backPath, FlashSuccess,
+		`%s address "%s" removed`, cn, ch.Address)
+}
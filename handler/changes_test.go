@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// pendingChange returns address change 1 of user 3 requested by admin
+// other.
+func pendingChange(action string, address string) mastore.AddressChange {
+	return mastore.AddressChange{
+		ID:          1,
+		UserID:      3,
+		Action:      action,
+		Coin:        "ETH",
+		Address:     address,
+		Status:      mastore.ChangePending,
+		RequestedBy: "other",
+	}
+}
+
+// userAddresses makes user 3 have ETH addresses, the first is primary.
+func (th testHandler) userAddresses(addrs ...string) {
+	var uas []bestore.UserAddress
+	for _, a := range addrs {
+		uas = append(uas, bestore.UserAddress{UserID: 3, Coin: bestore.ETH,
+			Address: a})
+	}
+	th.s.On("GetUserAddresses", uint(3)).Return(uas, nil)
+
+	primary := map[string]string{}
+	if len(addrs) > 0 {
+		primary["ETH"] = addrs[0]
+	}
+	th.ms.On("PrimaryAddresses", uint(3)).Return(primary, nil)
+}
+
+func Test_EditAddressChange_approve(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("GetAddressChange", uint(1)).
+		Return(pendingChange("add", "addr"), nil)
+	th.userAddresses()
+	th.ms.On("DecideAddressChange", uint(1), "approved", "login",
+		time.Time{}).Return(nil)
+	th.s.On("AddUserAddress", uint(3), bestore.ETH, "addr").Return(nil)
+	th.ms.On("TrackAddress", uint(3), "ETH", "addr", "other").Return(nil)
+
+	res := th.serve("/address-changes/:change-id", th.EditAddressChange,
+		newFormRequest("/address-changes/1", url.Values{
+			"action": {"approve"},
+			"back":   {"user"},
+		}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/users/3/addresses", res.Header().Get("Location"))
+	assert.Equal(t, []Flash{{Kind: FlashSuccess,
+		Message: `ETH address "addr" added`}}, th.flashes(res))
+
+	th.s.AssertExpectations(t)
+	th.ms.AssertExpectations(t)
+}
+
+func Test_EditAddressChange_approveAddOwned(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	// Address was added by another change after this one was requested.
+	th.ms.On("GetAddressChange", uint(1)).
+		Return(pendingChange("add", "addr"), nil)
+	th.userAddresses("addr")
+
+	res := th.serve("/address-changes/:change-id", th.EditAddressChange,
+		newFormRequest("/address-changes/1",
+			url.Values{"action": {"approve"}}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, []Flash{{Kind: FlashError,
+		Message: "User has this address already, reject the change"}},
+		th.flashes(res))
+
+	th.ms.AssertNotCalled(t, "DecideAddressChange", mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything)
+	th.s.AssertNotCalled(t, "AddUserAddress", mock.Anything, mock.Anything,
+		mock.Anything)
+}
+
+func Test_EditAddressChange_approveOwn(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	ch := pendingChange("add", "addr")
+	ch.RequestedBy = "login"
+	th.ms.On("GetAddressChange", uint(1)).Return(ch, nil)
+
+	res := th.serve("/address-changes/:change-id", th.EditAddressChange,
+		newFormRequest("/address-changes/1",
+			url.Values{"action": {"approve"}}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/address-changes", res.Header().Get("Location"))
+	assert.Equal(t, []Flash{{Kind: FlashError,
+		Message: "Address change must be approved by another admin"}},
+		th.flashes(res))
+
+	th.s.AssertNotCalled(t, "AddUserAddress", mock.Anything, mock.Anything,
+		mock.Anything)
+	th.ms.AssertNotCalled(t, "DecideAddressChange", mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything)
+}
+
+func Test_EditAddressChange_approvePrimary(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("GetAddressChange", uint(1)).
+		Return(pendingChange("primary", "addr2"), nil)
+	th.userAddresses("addr1", "addr2")
+	th.ms.On("DecideAddressChange", uint(1), "approved", "login",
+		time.Time{}).Return(nil)
+	th.ms.On("SetPrimaryAddress", uint(3), "ETH", "addr2", "other").
+		Return(nil)
+
+	res := th.serve("/address-changes/:change-id", th.EditAddressChange,
+		newFormRequest("/address-changes/1",
+			url.Values{"action": {"approve"}}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, []Flash{{Kind: FlashSuccess,
+		Message: `ETH address "addr2" is primary now`}}, th.flashes(res))
+
+	th.ms.AssertExpectations(t)
+}
+
+func Test_EditAddressChange_approveRemovePrimary(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	ch := pendingChange("remove", "addr1")
+	ch.Replacement = "addr2"
+	th.ms.On("GetAddressChange", uint(1)).Return(ch, nil)
+	th.userAddresses("addr1", "addr2")
+	th.ms.On("DecideAddressChange", uint(1), "approved", "login",
+		time.Time{}).Return(nil)
+	th.ms.On("AddTrashItem", mastore.TrashItem{
+		Kind:      "addresses",
+		EntityID:  3,
+		Name:      "ETH addr1",
+		Payload:   `{"coin":"ETH","address":"addr1"}`,
+		DeletedBy: "other",
+	}).Return(uint(5), nil)
+	th.s.On("RemoveUserAddress", uint(3), bestore.ETH, "addr1").Return(nil)
+	th.ms.On("SetPrimaryAddress", uint(3), "ETH", "addr2", "other").
+		Return(nil)
+
+	res := th.serve("/address-changes/:change-id", th.EditAddressChange,
+		newFormRequest("/address-changes/1",
+			url.Values{"action": {"approve"}}))
+
+	// Replacement becomes primary along with removal.
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, []Flash{{Kind: FlashSuccess,
+		Message: `ETH address "addr1" removed`}}, th.flashes(res))
+
+	th.s.AssertExpectations(t)
+	th.ms.AssertExpectations(t)
+}
+
+func Test_EditAddressChange_approveRemoveNowPrimary(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	// Removal was requested before addr1 became primary.
+	th.ms.On("GetAddressChange", uint(1)).
+		Return(pendingChange("remove", "addr1"), nil)
+	th.userAddresses("addr1", "addr2")
+
+	res := th.serve("/address-changes/:change-id", th.EditAddressChange,
+		newFormRequest("/address-changes/1",
+			url.Values{"action": {"approve"}}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, []Flash{{Kind: FlashError, Message: "Address is " +
+		"primary now, reject the change and choose a replacement " +
+		"primary address"}}, th.flashes(res))
+
+	th.ms.AssertNotCalled(t, "DecideAddressChange", mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything)
+	th.s.AssertNotCalled(t, "RemoveUserAddress", mock.Anything,
+		mock.Anything, mock.Anything)
+	th.ms.AssertNotCalled(t, "RemovePrimaryAddress", mock.Anything,
+		mock.Anything)
+}
+
+func Test_EditAddressChange_rejectEmitsEvent(t *testing.T) {
+	events := &recordingEmitter{}
+	th := newTestHandler(Config{Events: events})
+
+	th.ms.On("GetAddressChange", uint(1)).
+		Return(pendingChange("add", "addr"), nil)
+	th.ms.On("DecideAddressChange", uint(1), "rejected", "login",
+		time.Time{}).Return(nil)
+
+	res := th.serve("/address-changes/:change-id", th.EditAddressChange,
+		newFormRequest("/address-changes/1",
+			url.Values{"action": {"reject"}}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, []Flash{{Kind: FlashSuccess,
+		Message: "Address change rejected"}}, th.flashes(res))
+	assert.Equal(t, []recordedEvent{{
+		event: webhook.AddressChangeRejected,
+		by:    "login",
+		data: addressEvent{UserID: 3, Action: "add", Coin: "ETH",
+			Address: "addr", Status: mastore.ChangeRejected},
+	}}, events.events)
+
+	th.ms.AssertExpectations(t)
+	th.s.AssertNotCalled(t, "AddUserAddress", mock.Anything, mock.Anything,
+		mock.Anything)
+}
@@ -0,0 +1,508 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/balance"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/trash"
+	"github.com/labstack/echo"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// dashboardTTL is how long dashboard is shown without recomputing, it
+	// requests balances of every project and addresses of every user.
+	dashboardTTL = time.Minute
+	// topProjectsShown is how many projects are shown per coin.
+	topProjectsShown = 5
+	// recentEventsShown is how many recent events are shown.
+	recentEventsShown = 10
+)
+
+// unpaidUser is user who mined coins without payout address for them.
+type unpaidUser struct {
+	User  bestore.User
+	Coins []string
+}
+
+// projectAmount is amount of coin mined in project.
+type projectAmount struct {
+	ProjectID   uint
+	ProjectName string
+	Amount      decimal.Decimal
+}
+
+// coinTop is projects which mined most of coin, the most first.
+type coinTop struct {
+	Coin     bestore.Coin
+	Projects []projectAmount
+}
+
+// dashboardEvent is address change or removal to trash, exactly one of
+// Change and Removed is set.
+type dashboardEvent struct {
+	At      time.Time
+	By      string
+	Change  *mastore.AddressChange
+	Removed *mastore.TrashItem
+}
+
+// poolSummary is pool part of cross-pool dashboard.
+type poolSummary struct {
+	Name       string
+	Total      balance.Totals
+	Projects   int
+	Users      int
+	OpenAlerts int
+}
+
+type dashboardPageData struct {
+	Pool     string
+	Total    balance.Totals
+	Projects int
+	Users    int
+	Admins   int
+	Unpaid   []unpaidUser
+	Top      []coinTop
+	Events   []dashboardEvent
+	// OpenAlerts includes NewAlerts nobody acknowledged.
+	OpenAlerts int
+	NewAlerts  int
+	// Pools are summaries of pools admin has role in, they are shown only
+	// if there are several of them. PoolsTotal sums their totals.
+	Pools      []poolSummary
+	PoolsTotal balance.Totals
+	// UpdatedAt is when cached part of dashboard was computed.
+	UpdatedAt time.Time
+}
+
+// dashboardCache keeps last computed dashboards by pool name, it is shared
+// by handler copies.
+type dashboardCache struct {
+	mutex sync.Mutex
+	pools map[string]*poolDashboard
+}
+
+// poolDashboard is last computed dashboard of pool. Its mutex is held while
+// it is recomputed, so concurrent requests compute pool once and do not wait
+// for other pools.
+type poolDashboard struct {
+	mutex sync.Mutex
+	data  dashboardPageData
+}
+
+// pool returns cached dashboard of pool.
+func (dc *dashboardCache) pool(name string) *poolDashboard {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if dc.pools == nil {
+		dc.pools = map[string]*poolDashboard{}
+	}
+	pd, ok := dc.pools[name]
+	if !ok {
+		pd = &poolDashboard{}
+		dc.pools[name] = pd
+	}
+	return pd
+}
+
+func (h Handler) Index(c echo.Context) error {
+	p := h.pool(c)
+
+	data, err := h.cachedDashboard(h.dashboardSource(c, p))
+	if err != nil {
+		return err
+	}
+
+	data.Pool = p.Name
+
+	alerts, err := h.mstore(c).OpenAlerts()
+	if err != nil {
+		return errors.New("failed to get open alerts from DB: " + err.Error())
+	}
+
+	data.OpenAlerts = len(alerts)
+	for _, a := range alerts {
+		if a.AcknowledgedBy == "" {
+			data.NewAlerts++
+		}
+	}
+
+	pools := selectablePools(c)
+	if len(pools) > 1 {
+		data.Pools, data.PoolsTotal, err = h.poolSummaries(c, pools)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.Render(http.StatusOK, "index", data)
+}
+
+// poolSummaries summarizes cached dashboards of pools and sums their totals.
+// Stale dashboards are recomputed concurrently.
+func (h Handler) poolSummaries(c echo.Context, pools []Pool) ([]poolSummary,
+	balance.Totals, error) {
+	var (
+		sums  []poolSummary
+		coins [][]bestore.CoinAmount
+	)
+
+	var (
+		wg    sync.WaitGroup
+		datas = make([]dashboardPageData, len(pools))
+		errs  = make([]error, len(pools))
+	)
+
+	for i, p := range pools {
+		wg.Add(1)
+		go func(i int, src dashboardSource) {
+			defer wg.Done()
+			datas[i], errs[i] = h.cachedDashboard(src)
+		}(i, h.dashboardSource(c, p))
+	}
+
+	wg.Wait()
+
+	for i, p := range pools {
+		data, err := datas[i], errs[i]
+		if err != nil {
+			return nil, balance.Totals{}, fmt.Errorf("failed to compute "+
+				"pool %s dashboard: %v", p.Name, err)
+		}
+
+		alerts, err := h.poolMStore(c, p).OpenAlerts()
+		if err != nil {
+			return nil, balance.Totals{}, fmt.Errorf("failed to get pool %s "+
+				"open alerts from DB: %v", p.Name, err)
+		}
+
+		sums = append(sums, poolSummary{
+			Name:       p.Name,
+			Total:      data.Total,
+			Projects:   data.Projects,
+			Users:      data.Users,
+			OpenAlerts: len(alerts),
+		})
+
+		var cas []bestore.CoinAmount
+		for _, ct := range data.Total.Coins {
+			cas = append(cas, bestore.CoinAmount{
+				Coin:   ct.Coin,
+				Amount: ct.Amount.String(),
+			})
+		}
+		coins = append(coins, cas)
+	}
+
+	total, err := h.totals(c, coins...)
+	if err != nil {
+		return nil, balance.Totals{}, errors.New("failed to sum pools " +
+			"totals: " + err.Error())
+	}
+
+	return sums, total, nil
+}
+
+// dashboardSource is what pool dashboard is computed from. It is resolved
+// from request beforehand, so dashboards of pools are computed concurrently
+// without echo.Context, which is not safe for concurrent use.
+type dashboardSource struct {
+	pool   Pool
+	store  bestore.Store
+	mstore mastore.Store
+	// accounts and accountBin are of primary pool, admins are kept there.
+	accounts   bestore.Store
+	accountBin trash.Bin
+	// prices are nil if coin prices are not available.
+	prices *balance.Prices
+}
+
+// dashboardSource returns source of pool dashboard, its stores are bound to
+// request context.
+func (h Handler) dashboardSource(c echo.Context, p Pool) dashboardSource {
+	src := dashboardSource{
+		pool:       p,
+		store:      h.poolStore(c, p),
+		mstore:     h.poolMStore(c, p),
+		accounts:   h.accountStore(c),
+		accountBin: h.accountBin(c),
+	}
+
+	if prices, ok := h.coinPrices(c); ok {
+		src.prices = &prices
+	}
+
+	return src
+}
+
+// cachedDashboard returns pool dashboard computed at most dashboardTTL ago.
+// Alert counts are not cached, so acknowledged alerts disappear at once.
+func (h Handler) cachedDashboard(src dashboardSource) (dashboardPageData,
+	error) {
+	pd := h.dashboard.pool(src.pool.Name)
+
+	pd.mutex.Lock()
+	defer pd.mutex.Unlock()
+
+	if time.Since(pd.data.UpdatedAt) < dashboardTTL {
+		return pd.data, nil
+	}
+
+	data, err := computeDashboard(src)
+	if err != nil {
+		return dashboardPageData{}, err
+	}
+
+	pd.data = data
+
+	return data, nil
+}
+
+func computeDashboard(src dashboardSource) (dashboardPageData, error) {
+	data := dashboardPageData{UpdatedAt: time.Now()}
+
+	s := src.store
+	ms := src.mstore
+
+	balances, err := s.ProjectsBalances()
+	if err != nil {
+		return data, errors.New("failed to get project balances from DB: " +
+			err.Error())
+	}
+
+	trashedProjects, err := trash.NewBin(s, ms).Hidden(trash.Projects)
+	if err != nil {
+		return data, errors.New("failed to get trashed projects from DB: " +
+			err.Error())
+	}
+
+	var (
+		coins [][]bestore.CoinAmount
+		live  []bestore.ProjectBalance
+	)
+
+	for _, b := range balances {
+		if !trashedProjects[b.ProjectID] {
+			live = append(live, b)
+			coins = append(coins, b.Coins)
+		}
+	}
+
+	data.Projects = len(live)
+
+	data.Total, err = sumTotals(src.prices, coins...)
+	if err != nil {
+		return data, errors.New("failed to sum projects balances: " +
+			err.Error())
+	}
+
+	data.Top, err = topProjects(live, topProjectsShown)
+	if err != nil {
+		return data, err
+	}
+
+	users, err := s.GetUsers()
+	if err != nil {
+		return data, errors.New("failed to get users from DB: " + err.Error())
+	}
+
+	data.Users = len(users)
+
+	data.Unpaid, err = unpaidUsers(s, users, live)
+	if err != nil {
+		return data, err
+	}
+
+	admins, err := src.accounts.GetAdmins()
+	if err != nil {
+		return data, errors.New("failed to get admins from DB: " + err.Error())
+	}
+
+	trashedAdmins, err := src.accountBin.Hidden(trash.Admins)
+	if err != nil {
+		return data, errors.New("failed to get trashed admins from DB: " +
+			err.Error())
+	}
+
+	for _, a := range admins {
+		if !trashedAdmins[a.ID] {
+			data.Admins++
+		}
+	}
+
+	data.Events, err = recentEvents(ms, recentEventsShown)
+	if err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// topProjects returns for each coin at most n projects which mined most of
+// it. Coins are sorted by name.
+func topProjects(balances []bestore.ProjectBalance, n int) ([]coinTop,
+	error) {
+	byCoin := map[bestore.Coin][]projectAmount{}
+
+	for _, b := range balances {
+		for _, ca := range b.Coins {
+			d, err := balance.Parse(ca)
+			if err != nil {
+				return nil, err
+			}
+			if !d.IsPositive() {
+				continue
+			}
+			byCoin[ca.Coin] = append(byCoin[ca.Coin], projectAmount{
+				ProjectID:   b.ProjectID,
+				ProjectName: b.ProjectName,
+				Amount:      d,
+			})
+		}
+	}
+
+	var tops []coinTop
+
+	for cn, pas := range byCoin {
+		sort.SliceStable(pas, func(i, j int) bool {
+			return pas[i].Amount.GreaterThan(pas[j].Amount)
+		})
+		if len(pas) > n {
+			pas = pas[:n]
+		}
+		tops = append(tops, coinTop{Coin: cn, Projects: pas})
+	}
+
+	sort.Slice(tops, func(i, j int) bool {
+		return fmt.Sprint(tops[i].Coin) < fmt.Sprint(tops[j].Coin)
+	})
+
+	return tops, nil
+}
+
+// unpaidUsers returns users who mined coins in projects but have no payout
+// address for them. Balances know users only by email.
+func unpaidUsers(s bestore.Store, users []bestore.User,
+	projects []bestore.ProjectBalance) ([]unpaidUser, error) {
+	mined := map[string]map[string]bool{}
+
+	for _, p := range projects {
+		ubs, err := s.ProjectUsersBalances(p.ProjectID)
+		if err != nil {
+			return nil, errors.New("failed to get project users balances " +
+				"from DB: " + err.Error())
+		}
+
+		for _, ub := range ubs {
+			email := strings.ToLower(ub.Email)
+			for _, ca := range ub.Coins {
+				d, err := balance.Parse(ca)
+				if err != nil {
+					return nil, err
+				}
+				if !d.IsPositive() {
+					continue
+				}
+				if mined[email] == nil {
+					mined[email] = map[string]bool{}
+				}
+				mined[email][fmt.Sprintf("%s", ca.Coin)] = true
+			}
+		}
+	}
+
+	var unpaid []unpaidUser
+
+	for _, u := range users {
+		coins := mined[strings.ToLower(u.Email)]
+		if len(coins) == 0 {
+			continue
+		}
+
+		uas, err := s.GetUserAddresses(u.ID)
+		if err != nil {
+			return nil, errors.New("failed to get user addresses from DB: " +
+				err.Error())
+		}
+
+		paid := map[string]bool{}
+		for _, ua := range uas {
+			paid[fmt.Sprintf("%s", ua.Coin)] = true
+		}
+
+		var missing []string
+		for cn := range coins {
+			if !paid[cn] {
+				missing = append(missing, cn)
+			}
+		}
+
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			unpaid = append(unpaid, unpaidUser{User: u, Coins: missing})
+		}
+	}
+
+	return unpaid, nil
+}
+
+// recentEvents returns at most n most recent address changes and removals
+// to trash, the most recent first.
+func recentEvents(ms mastore.Store, n int) ([]dashboardEvent, error) {
+	chs, err := ms.RecentAddressChanges(n)
+	if err != nil {
+		return nil, errors.New("failed to get recent address changes from " +
+			"DB: " + err.Error())
+	}
+
+	var events []dashboardEvent
+
+	for i := range chs {
+		ev := dashboardEvent{
+			At:     chs[i].RequestedAt,
+			By:     chs[i].RequestedBy,
+			Change: &chs[i],
+		}
+		if chs[i].DecidedBy != "" {
+			ev.At = chs[i].DecidedAt
+			ev.By = chs[i].DecidedBy
+		}
+		events = append(events, ev)
+	}
+
+	for _, kind := range trash.Kinds {
+		items, err := ms.TrashItems(kind)
+		if err != nil {
+			return nil, errors.New("failed to get trash items from DB: " +
+				err.Error())
+		}
+
+		for i := range items {
+			events = append(events, dashboardEvent{
+				At:      items[i].DeletedAt,
+				By:      items[i].DeletedBy,
+				Removed: &items[i],
+			})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].At.After(events[j].At)
+	})
+
+	if len(events) > n {
+		events = events[:n]
+	}
+
+	return events, nil
+}
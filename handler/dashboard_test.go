@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/balance"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/trash"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_topProjects(t *testing.T) {
+	tops, err := topProjects([]bestore.ProjectBalance{
+		{ProjectID: 1, Coins: []bestore.CoinAmount{
+			{Coin: bestore.ETH, Amount: "1.5"},
+			{Coin: bestore.BTC, Amount: "0"}}},
+		{ProjectID: 2, Coins: []bestore.CoinAmount{
+			{Coin: bestore.ETH, Amount: "10"}}},
+		{ProjectID: 3, Coins: []bestore.CoinAmount{
+			{Coin: bestore.ETH, Amount: "2"}}},
+	}, 2)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if !assert.Len(t, tops, 1) {
+		return
+	}
+	assert.Equal(t, bestore.ETH, tops[0].Coin)
+	if assert.Len(t, tops[0].Projects, 2) {
+		assert.Equal(t, uint(2), tops[0].Projects[0].ProjectID)
+		assert.Equal(t, uint(3), tops[0].Projects[1].ProjectID)
+	}
+
+	_, err = topProjects([]bestore.ProjectBalance{{ProjectID: 1,
+		Coins: []bestore.CoinAmount{{Coin: bestore.ETH, Amount: "x"}}}}, 2)
+	assert.Error(t, err)
+}
+
+func Test_computeDashboard(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+
+	p := newDashboardTestPool(DefaultPool, release)
+
+	data, err := computeDashboard(dashboardSource{
+		pool:       p,
+		store:      p.Store,
+		mstore:     p.MStore,
+		accounts:   p.Store,
+		accountBin: trash.NewBin(p.Store, p.MStore),
+		prices:     &balance.Prices{Currency: "USD"},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, data.Total.Valued)
+	assert.Equal(t, "USD", data.Total.Currency)
+	assert.False(t, data.UpdatedAt.IsZero())
+}
+
+// newDashboardTestPool returns pool with empty dashboard, ProjectsBalances
+// waits for release to be closed.
+func newDashboardTestPool(name string, release chan struct{}) Pool {
+	s := bestore.NewMockStore()
+	ms := mastore.NewMockStore()
+
+	s.On("ProjectsBalances").Run(func(mock.Arguments) {
+		<-release
+	}).Return([]bestore.ProjectBalance{}, nil)
+	s.On("GetUsers").Return([]bestore.User{}, nil)
+	s.On("GetAdmins").Return([]bestore.Admin{}, nil)
+	for _, kind := range trash.Kinds {
+		ms.On("TrashItems", kind).Return([]mastore.TrashItem{}, nil)
+	}
+	ms.On("RecentAddressChanges", recentEventsShown).Return(
+		[]mastore.AddressChange{}, nil)
+
+	return Pool{Name: name, Store: s, MStore: ms}
+}
+
+func Test_cachedDashboard_otherPoolComputing(t *testing.T) {
+	slow := make(chan struct{})
+	fast := make(chan struct{})
+	close(fast)
+
+	primary := newDashboardTestPool(DefaultPool, slow)
+	h := NewHandler(Config{
+		Store:  primary.Store,
+		MStore: primary.MStore,
+		Pools:  []Pool{newDashboardTestPool("fast", fast)},
+	})
+
+	newContext := func() echo.Context {
+		return echo.New().NewContext(
+			httptest.NewRequest(http.MethodGet, "/", nil),
+			httptest.NewRecorder())
+	}
+
+	slowDone := make(chan error)
+	go func() {
+		_, err := h.cachedDashboard(h.dashboardSource(newContext(),
+			h.pools[0]))
+		slowDone <- err
+	}()
+
+	fastDone := make(chan error)
+	go func() {
+		_, err := h.cachedDashboard(h.dashboardSource(newContext(),
+			h.pools[1]))
+		fastDone <- err
+	}()
+
+	select {
+	case err := <-fastDone:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Error("fast pool waits for slow pool dashboard")
+	}
+
+	close(slow)
+	assert.NoError(t, <-slowDone)
+}
+
+func Test_Index(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("ProjectsBalances").Return([]bestore.ProjectBalance{
+		{ProjectID: 1, ProjectName: "Alpha", Coins: []bestore.CoinAmount{
+			{Coin: bestore.ETH, Amount: "2"}}},
+		{ProjectID: 2, ProjectName: "Trashed"},
+	}, nil)
+	th.ms.On("TrashItems", "projects").Return([]mastore.TrashItem{
+		{ID: 1, Kind: "projects", EntityID: 2, Name: "Trashed"},
+	}, nil)
+	th.ms.On("TrashItems", "addresses").Return([]mastore.TrashItem{}, nil)
+	th.ms.On("TrashItems", "admins").Return([]mastore.TrashItem{}, nil)
+	th.s.On("GetUsers").Return([]bestore.User{
+		{ID: 3, Email: "a@example.com"},
+		{ID: 4, Email: "b@example.com"},
+	}, nil)
+	th.s.On("ProjectUsersBalances", uint(1)).Return([]bestore.UserBalance{
+		{Email: "A@example.com", Coins: []bestore.CoinAmount{
+			{Coin: bestore.ETH, Amount: "2"}}},
+	}, nil)
+	th.s.On("GetUserAddresses", uint(3)).Return([]bestore.UserAddress{}, nil)
+	th.s.On("GetAdmins").Return([]bestore.Admin{{ID: 1, Login: "login"}}, nil)
+	th.ms.On("RecentAddressChanges", 10).Return([]mastore.AddressChange{}, nil)
+	th.ms.On("OpenAlerts").Return([]mastore.Alert{
+		{ID: 7, AcknowledgedBy: "login"},
+		{ID: 8},
+	}, nil)
+
+	for i := 0; i < 2; i++ {
+		res := th.serve("/", th.Index,
+			httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "index", th.rendered.name)
+
+		// Trashed project is not counted, user with balance but without
+		// addresses is unpaid.
+		data := th.rendered.data.(dashboardPageData)
+		assert.Equal(t, DefaultPool, data.Pool)
+		assert.Equal(t, 1, data.Projects)
+		assert.Equal(t, 2, data.Users)
+		assert.Equal(t, 1, data.Admins)
+		if assert.Len(t, data.Total.Coins, 1) {
+			assert.Equal(t, "2", data.Total.Coins[0].Amount.String())
+		}
+		if assert.Len(t, data.Unpaid, 1) {
+			assert.Equal(t, uint(3), data.Unpaid[0].User.ID)
+		}
+		assert.Equal(t, 2, data.OpenAlerts)
+		assert.Equal(t, 1, data.NewAlerts)
+	}
+
+	// Second request is served from cache, alerts are always read.
+	th.s.AssertNumberOfCalls(t, "ProjectsBalances", 1)
+	th.ms.AssertNumberOfCalls(t, "OpenAlerts", 2)
+	th.s.AssertNotCalled(t, "ProjectUsersBalances", uint(2))
+	th.s.AssertNotCalled(t, "GetUserAddresses", uint(4))
+}
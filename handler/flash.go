@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// Flash is one-time message shown on next rendered page, usually after
+// post/redirect/get.
+type Flash struct {
+	Kind    string
+	Message string
+	// Undo is path of trash item the flash offers to restore.
+	Undo string `json:",omitempty"`
+}
+
+const (
+	FlashSuccess = "success"
+	FlashWarning = "warning"
+	FlashError   = "error"
+)
+
+const (
+	flashCookie        = "flash"
+	flashesKey         = "flashes"
+	pendingFlashesKey  = "pending-flashes"
+	flashSigningDomain = "flash"
+)
+
+// flashKey derives flash cookie signing key from JWT secret, so the secret
+// itself is never used for anything but JWT.
+func (h Handler) flashKey() []byte {
+	mac := hmac.New(sha256.New, h.jwtSecret)
+	mac.Write([]byte(flashSigningDomain))
+	return mac.Sum(nil)
+}
+
+func (h Handler) encodeFlashes(fs []Flash) (string, error) {
+	data, err := json.Marshal(fs)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, h.flashKey())
+	mac.Write(data)
+
+	return base64.RawURLEncoding.EncodeToString(data) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (h Handler) decodeFlashes(value string) ([]Flash, error) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 2 {
+		return nil, errors.New("invalid flash cookie format")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	sum, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, h.flashKey())
+	mac.Write(data)
+
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return nil, errors.New("invalid flash cookie signature")
+	}
+
+	var fs []Flash
+
+	err = json.Unmarshal(data, &fs)
+
+	return fs, err
+}
+
+// addFlash stores flash in signed cookie to show it on next page.
+func (h Handler) addFlash(c echo.Context, kind string, message string) {
+	h.storeFlash(c, Flash{Kind: kind, Message: message})
+}
+
+func (h Handler) storeFlash(c echo.Context, f Flash) {
+	fs, _ := c.Get(pendingFlashesKey).([]Flash)
+	fs = append(fs, f)
+	c.Set(pendingFlashesKey, fs)
+
+	value, err := h.encodeFlashes(fs)
+	if err != nil {
+		c.Logger().Error("failed to encode flashes: " + err.Error())
+		return
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     flashCookie,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+	})
+}
+
+// redirectWithFlash adds flash with message translated and formatted with
+// args and redirects to path.
+func (h Handler) redirectWithFlash(c echo.Context, path string, kind string,
+	message string, args ...interface{}) error {
+	h.addFlash(c, kind, tr(c, message, args...))
+	return c.Redirect(http.StatusFound, path)
+}
+
+// redirectWithUndo adds success flash with undo button which restores trash
+// item and redirects to path.
+func (h Handler) redirectWithUndo(c echo.Context, path string, kind string,
+	itemID uint, message string, args ...interface{}) error {
+	h.storeFlash(c, Flash{
+		Kind:    FlashSuccess,
+		Message: tr(c, message, args...),
+		Undo:    buildURL("trash", kind, itemID),
+	})
+	return c.Redirect(http.StatusFound, path)
+}
+
+// redirectWithError logs err and redirects to path with error flash.
+func (h Handler) redirectWithError(c echo.Context, path string,
+	message string, err error) error {
+	c.Logger().Error(message + ": " + err.Error())
+	return h.redirectWithFlash(c, path, FlashError, message)
+}
+
+// Flashes is middleware which moves flashes from cookie to context on GET
+// requests, so they are shown on the page the user was redirected to.
+func (h Handler) Flashes(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if c.Request().Method != http.MethodGet {
+			return next(c)
+		}
+
+		cookie, err := c.Cookie(flashCookie)
+		if err != nil {
+			return next(c)
+		}
+
+		fs, err := h.decodeFlashes(cookie.Value)
+		if err == nil {
+			c.Set(flashesKey, fs)
+		}
+
+		c.SetCookie(&http.Cookie{
+			Name:     flashCookie,
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+		})
+
+		return next(c)
+	}
+}
+
+// formData holds submitted form values and field errors, so invalid form can
+// be rendered again with user input kept.
+type formData struct {
+	Values map[string]string
+	Errors map[string]string
+}
+
+func newFormData(c echo.Context, fields ...string) formData {
+	f := formData{
+		Values: map[string]string{},
+		Errors: map[string]string{},
+	}
+	for _, field := range fields {
+		f.Values[field] = c.FormValue(field)
+	}
+	return f
+}
+
+func (f formData) Value(field string) string {
+	return f.Values[field]
+}
+
+func (f formData) Error(field string) string {
+	return f.Errors[field]
+}
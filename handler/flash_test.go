@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_flashes(t *testing.T) {
+	h := NewHandler(Config{JWTSecret: "secret"})
+
+	e := echo.New()
+
+	res := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), res)
+
+	h.addFlash(c, FlashSuccess, "first")
+	h.addFlash(c, FlashError, "second")
+
+	cookies := res.Result().Cookies()
+	if !assert.NotEmpty(t, cookies) {
+		return
+	}
+	cookie := cookies[len(cookies)-1]
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	res = httptest.NewRecorder()
+	c = e.NewContext(req, res)
+
+	err := h.Flashes(func(c echo.Context) error { return nil })(c)
+	assert.NoError(t, err)
+	assert.Equal(t, []Flash{
+		{Kind: FlashSuccess, Message: "first"},
+		{Kind: FlashError, Message: "second"},
+	}, flashes(c))
+	assert.Contains(t, res.Header().Get("Set-Cookie"), "Max-Age=0")
+}
+
+func Test_decodeFlashes_tampered(t *testing.T) {
+	h := NewHandler(Config{JWTSecret: "secret"})
+
+	value, err := h.encodeFlashes([]Flash{{Kind: FlashSuccess, Message: "m"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = NewHandler(Config{JWTSecret: "other"}).decodeFlashes(value)
+	assert.Error(t, err)
+
+	parts := strings.Split(value, ".")
+	_, err = h.decodeFlashes(parts[0] + "x." + parts[1])
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/boomstarternetwork/mineradmin/balance"
+	"github.com/boomstarternetwork/mineradmin/hashrate"
+	"github.com/boomstarternetwork/mineradmin/i18n"
+	"github.com/labstack/echo"
+)
+
+// funcMap is available in all templates. Funcs which depend on request are
+// bound to request by requestFuncMap on each render.
+var funcMap = requestFuncMap(nil)
+
+func requestFuncMap(c echo.Context) template.FuncMap {
+	return template.FuncMap{
+		"date":      formatDate,
+		"url":       buildURL,
+		"csrfField": func() template.HTML { return csrfField(c) },
+		"flashes":   func() []Flash { return flashes(c) },
+		"pendingChanges": func() int {
+			return pendingChanges(c)
+		},
+		"locale":     func() string { return localeOf(c) },
+		"localeName": i18n.Name,
+		"t": func(message string, args ...interface{}) string {
+			return tr(c, message, args...)
+		},
+		"plural": func(n int, one string, many string) string {
+			return i18n.Plural(localeOf(c), n, one, many)
+		},
+		"coinAmount": func(amount string) string {
+			return i18n.FormatAmount(localeOf(c), amount)
+		},
+		"hashrate": hashrate.Format,
+		"pool":     func() string { return poolName(c) },
+		"pools":    func() []Pool { return selectablePools(c) },
+		"fiat": func(t balance.Totals) string {
+			return i18n.FormatFiat(localeOf(c), t.Value.StringFixed(2),
+				t.Currency)
+		},
+	}
+}
+
+// csrfField returns hidden form inputs with request CSRF token and pool form
+// is rendered for, PoolAccess refuses form if other pool is selected since.
+func csrfField(c echo.Context) template.HTML {
+	if c == nil {
+		return ""
+	}
+	token, _ := c.Get("csrf-token").(string)
+	field := `<input type="hidden" name="csrf-token" value="` +
+		template.HTMLEscapeString(token) + `"/>`
+	if pool := poolName(c); pool != "" {
+		field += `<input type="hidden" name="` + poolField + `" value="` +
+			template.HTMLEscapeString(pool) + `"/>`
+	}
+	return template.HTML(field)
+}
+
+// flashes returns flashes loaded from cookie by Flashes middleware.
+func flashes(c echo.Context) []Flash {
+	if c == nil {
+		return nil
+	}
+	fs, _ := c.Get(flashesKey).([]Flash)
+	return fs
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// buildURL joins escaped path segments into absolute path, e.g.
+// {{url "projects" .ProjectID "edit"}} gives /projects/123/edit.
+func buildURL(segments ...interface{}) string {
+	ss := make([]string, len(segments))
+	for i, s := range segments {
+		ss[i] = url.PathEscape(strings.Trim(fmt.Sprint(s), "/"))
+	}
+	return "/" + strings.Join(ss, "/")
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_buildURL(t *testing.T) {
+	assert.Equal(t, "/projects/123/edit", buildURL("projects", 123, "edit"))
+	assert.Equal(t, "/users/a%2Fb", buildURL("/users/", "a/b"))
+}
+
+func Test_csrfField(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil),
+		httptest.NewRecorder())
+	c.Set("csrf-token", "token")
+
+	assert.NotContains(t, string(csrfField(c)), poolField)
+
+	c.Set(poolKey, Pool{Name: "eu"})
+
+	assert.Contains(t, string(csrfField(c)),
+		`<input type="hidden" name="form-pool" value="eu"/>`)
+}
@@ -1,24 +1,117 @@
 package handler
 
 import (
-	"net/http"
+	"time"
 
 	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/balance"
+	"github.com/boomstarternetwork/mineradmin/ctxstore"
+	"github.com/boomstarternetwork/mineradmin/mail"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/trash"
+	"github.com/boomstarternetwork/mineradmin/webhook"
 	"github.com/labstack/echo"
 )
 
+// Config is handler dependencies and settings.
+type Config struct {
+	// Store, MStore and Events are of primary pool, which also keeps admin
+	// accounts.
+	Store  bestore.Store
+	MStore mastore.Store
+	// PoolName is primary pool name, DefaultPool is used if it is empty.
+	PoolName string
+	// Pools are managed along with primary pool, names are unique.
+	Pools []Pool
+	// Roles are admin roles by pool name and admin login, see Pool.Role.
+	Roles map[string]map[string]string
+	// Prices can be nil if coin prices are not configured.
+	Prices    balance.PriceSource
+	JWTSecret string
+	// AddressCooldown is how long approved new payout address is not
+	// eligible for payouts.
+	AddressCooldown time.Duration
+	// BlockDuplicateAddresses forbids adding address which belongs to
+	// another user, admins are only warned about it otherwise.
+	BlockDuplicateAddresses bool
+	// Notifier can be nil if notifications are not configured.
+	Notifier *mail.Notifier
+	// Events can be nil if events are not emitted to webhooks.
+	Events webhook.Emitter
+	// PortalJWTSecret signs miner portal tokens, it differs from JWTSecret,
+	// so miner tokens are never accepted by admin routes.
+	PortalJWTSecret string
+	// PortalURL is miner portal URL login links point to, request host is
+	// used if it is empty.
+	PortalURL string
+	// StatsToken authorizes pool to post worker reports, reports are not
+	// accepted if it is empty.
+	StatsToken string
+	// Timeouts bound requests, see Timeout middleware.
+	Timeouts Timeouts
+}
+
 type Handler struct {
-	store     bestore.Store
-	jwtSecret []byte
+	// pools are not bound to request, handlers use store and mstore
+	// instead. Primary pool is the first.
+	pools           []Pool
+	prices          balance.PriceSource
+	jwtSecret       []byte
+	addressCooldown time.Duration
+	blockDuplicates bool
+	notifier        *mail.Notifier
+	portalJWTSecret []byte
+	portalURL       string
+	statsToken      string
+	timeouts        Timeouts
+	dashboard       *dashboardCache
+	portalBalances  *portalBalancesCache
+	addresses       *addressIndexes
 }
 
-func NewHandler(s bestore.Store, jwtSecret string) Handler {
+func NewHandler(conf Config) Handler {
 	return Handler{
-		store:     s,
-		jwtSecret: []byte(jwtSecret),
+		pools:           newPools(conf),
+		prices:          conf.Prices,
+		jwtSecret:       []byte(conf.JWTSecret),
+		addressCooldown: conf.AddressCooldown,
+		blockDuplicates: conf.BlockDuplicateAddresses,
+		notifier:        conf.Notifier,
+		portalJWTSecret: []byte(conf.PortalJWTSecret),
+		portalURL:       conf.PortalURL,
+		statsToken:      conf.StatsToken,
+		timeouts:        conf.Timeouts,
+		dashboard:       &dashboardCache{},
+		portalBalances:  &portalBalancesCache{},
+		addresses:       &addressIndexes{},
 	}
 }
 
-func (h Handler) Index(c echo.Context) error {
-	return c.Render(http.StatusOK, "index", nil)
+// store returns bestore of request pool bound to request context, so reads
+// are abandoned when request times out or client disconnects.
+func (h Handler) store(c echo.Context) bestore.Store {
+	return h.poolStore(c, h.pool(c))
+}
+
+// mstore returns mastore of request pool which queries are cancelled with
+// request.
+func (h Handler) mstore(c echo.Context) mastore.Store {
+	return h.poolMStore(c, h.pool(c))
+}
+
+// bin returns trash bin on top of request stores.
+func (h Handler) bin(c echo.Context) trash.Bin {
+	return h.poolBin(c, h.pool(c))
+}
+
+func (h Handler) poolStore(c echo.Context, p Pool) bestore.Store {
+	return ctxstore.New(p.Store, c.Request().Context())
+}
+
+func (h Handler) poolMStore(c echo.Context, p Pool) mastore.Store {
+	return p.MStore.WithContext(c.Request().Context())
+}
+
+func (h Handler) poolBin(c echo.Context, p Pool) trash.Bin {
+	return trash.NewBin(h.poolStore(c, p), h.poolMStore(c, p))
 }
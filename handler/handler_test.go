@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+)
+
+// testHandler is handler with mock stores of primary pool. It serves
+// requests without router middlewares, so tests see what handler itself
+// does.
+type testHandler struct {
+	Handler
+	s  *bestore.MockStore
+	ms *mastore.MockStore
+	// rendered is the last rendered page.
+	rendered *testRenderer
+}
+
+func newTestHandler(conf Config) testHandler {
+	s := bestore.NewMockStore()
+	ms := mastore.NewMockStore()
+
+	conf.Store = s
+	conf.MStore = ms
+	conf.JWTSecret = "secret"
+
+	return testHandler{
+		Handler:  NewHandler(conf),
+		s:        s,
+		ms:       ms,
+		rendered: &testRenderer{},
+	}
+}
+
+// testRenderer remembers rendered template and its data instead of
+// rendering it.
+type testRenderer struct {
+	name string
+	data interface{}
+}
+
+func (r *testRenderer) Render(_ io.Writer, name string, data interface{},
+	_ echo.Context) error {
+	r.name, r.data = name, data
+	return nil
+}
+
+// serve routes req to f registered at route and returns response. Request
+// is authorized by admin "login" like JWT middleware does.
+func (th testHandler) serve(route string, f echo.HandlerFunc,
+	req *http.Request) *httptest.ResponseRecorder {
+	return th.serveAs(route, f, req, "login",
+		jwt.MapClaims{"login": "login"})
+}
+
+// serveMiner serves req like serve, request is authorized by miner 3 in
+// portal.
+func (th testHandler) serveMiner(route string, f echo.HandlerFunc,
+	req *http.Request) *httptest.ResponseRecorder {
+	return th.serveAs(route, f, req, PortalContextKey,
+		jwt.MapClaims{"user_id": float64(3), "email": "a@example.com"})
+}
+
+func (th testHandler) serveAs(route string, f echo.HandlerFunc,
+	req *http.Request, key string,
+	claims jwt.MapClaims) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.Logger.SetOutput(ioutil.Discard)
+	e.Renderer = th.rendered
+
+	e.Add(req.Method, route, func(c echo.Context) error {
+		c.Set(key, &jwt.Token{Claims: claims, Valid: true})
+		return f(c)
+	})
+
+	res := httptest.NewRecorder()
+
+	e.ServeHTTP(res, req)
+
+	return res
+}
+
+// flashes returns flashes response stores for the next page.
+func (th testHandler) flashes(res *httptest.ResponseRecorder) []Flash {
+	var fs []Flash
+	for _, c := range res.Result().Cookies() {
+		if c.Name == flashCookie {
+			fs, _ = th.decodeFlashes(c.Value)
+		}
+	}
+	return fs
+}
+
+// newFormRequest returns POST request of form to target.
+func newFormRequest(target string, form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, target,
+		strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+// recordingEmitter remembers emitted events instead of sending them.
+type recordingEmitter struct {
+	events []recordedEvent
+}
+
+type recordedEvent struct {
+	event string
+	by    string
+	data  interface{}
+}
+
+func (e *recordingEmitter) Emit(event string, by string,
+	data interface{}) error {
+	e.events = append(e.events, recordedEvent{event, by, data})
+	return nil
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/boomstarternetwork/mineradmin/i18n"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+)
+
+const (
+	localeCookie = "locale"
+	localeKey    = "locale"
+)
+
+// Locale is middleware which chooses request locale from locale cookie,
+// which holds admin preference, or from Accept-Language header.
+func (h Handler) Locale(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		locale := ""
+
+		cookie, err := c.Cookie(localeCookie)
+		if err == nil && i18n.Supported(cookie.Value) {
+			locale = cookie.Value
+		} else {
+			locale = i18n.Match(c.Request().Header.Get("Accept-Language"))
+		}
+
+		c.Set(localeKey, locale)
+
+		return next(c)
+	}
+}
+
+func setLocaleCookie(c echo.Context, locale string) {
+	c.SetCookie(&http.Cookie{
+		Name:     localeCookie,
+		Value:    locale,
+		Path:     "/",
+		Expires:  time.Now().AddDate(1, 0, 0),
+		HttpOnly: true,
+	})
+	c.Set(localeKey, locale)
+}
+
+// localeOf returns locale chosen by Locale middleware.
+func localeOf(c echo.Context) string {
+	if c == nil {
+		return i18n.Default
+	}
+	locale, ok := c.Get(localeKey).(string)
+	if !ok {
+		return i18n.Default
+	}
+	return locale
+}
+
+// tr translates message to request locale.
+func tr(c echo.Context, message string, args ...interface{}) string {
+	return i18n.T(localeOf(c), message, args...)
+}
+
+// adminLogin returns login of authorized admin from JWT token.
+func adminLogin(c echo.Context) string {
+	token, ok := c.Get("login").(*jwt.Token)
+	if !ok {
+		return ""
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	login, _ := claims["login"].(string)
+	return login
+}
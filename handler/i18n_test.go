@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/boomstarternetwork/mineradmin/i18n"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_handlersTranslations(t *testing.T) {
+	const literal = `("(?:[^"\\]|\\.)*"|` + "`[^`]*`)"
+
+	res := []*regexp.Regexp{
+		regexp.MustCompile(`tr\(c,\s*` + literal),
+		regexp.MustCompile(`redirectWithFlash\(c,\s*[^,]+,\s*\w+,\s*` +
+			literal),
+		regexp.MustCompile(`redirectWithError\(c,\s*[^,]+,\s*` + literal),
+		regexp.MustCompile(`redirectWithUndo\(c,\s*[^,]+,\s*[^,]+,\s*` +
+			`[^,]+,\s*` + literal),
+	}
+
+	files, err := filepath.Glob("*.go")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if !assert.NoError(t, err) {
+			return
+		}
+		for _, re := range res {
+			for _, m := range re.FindAllStringSubmatch(string(src), -1) {
+				message, err := strconv.Unquote(m[1])
+				if !assert.NoError(t, err, file) {
+					continue
+				}
+				assert.True(t, i18n.Has(i18n.Ru, message),
+					"%s: no ru translation for %q", file, message)
+			}
+		}
+	}
+}
@@ -11,8 +11,7 @@ import (
 )
 
 type loginPageData struct {
-	CSRFToken string
-	Path      string
+	Path string
 }
 
 func (h Handler) Login(c echo.Context) error {
@@ -22,8 +21,7 @@ func (h Handler) Login(c echo.Context) error {
 			path = ""
 		}
 		return c.Render(http.StatusOK, "login", loginPageData{
-			CSRFToken: c.Get("csrf-token").(string),
-			Path:      path,
+			Path: path,
 		})
 	}
 
@@ -31,15 +29,24 @@ func (h Handler) Login(c echo.Context) error {
 	password := c.FormValue("password")
 	path := c.FormValue("path")
 
-	err := h.store.CheckAdminPassword(login, password)
+	err := h.accountStore(c).CheckAdminPassword(login, password)
 	if err != nil {
 		if bestore.InvalidLoginOrPassword(err) {
 			return echo.NewHTTPError(http.StatusBadRequest,
-				"invalid login or password")
+				tr(c, "invalid login or password"))
 		}
 		return errors.New("failed to check password in DB: " + err.Error())
 	}
 
+	trashed, err := h.accountBin(c).AdminTrashed(login)
+	if err != nil {
+		return errors.New("failed to check admin in trash: " + err.Error())
+	}
+	if trashed {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid login or password"))
+	}
+
 	token := jwt.New(jwt.SigningMethodHS256)
 
 	claims := token.Claims.(jwt.MapClaims)
@@ -58,6 +65,13 @@ func (h Handler) Login(c echo.Context) error {
 
 	c.SetCookie(cookie)
 
+	locale, err := h.accountMStore(c).GetAdminLocale(login)
+	if err != nil {
+		c.Logger().Error("failed to get admin locale from DB: " + err.Error())
+	} else if locale != "" {
+		setLocaleCookie(c, locale)
+	}
+
 	if path == "" {
 		path = "/"
 	}
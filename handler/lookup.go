@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/addrindex"
+	"github.com/labstack/echo"
+)
+
+// addressIndexTTL is how long addresses of all users are reused, they are
+// read user by user.
+const addressIndexTTL = time.Minute
+
+// addressIndexes keeps address index by pool name, it is shared by handler
+// copies.
+type addressIndexes struct {
+	mutex sync.Mutex
+	pools map[string]*addrindex.Index
+}
+
+// pool returns address index of pool.
+func (ai *addressIndexes) pool(name string) *addrindex.Index {
+	ai.mutex.Lock()
+	defer ai.mutex.Unlock()
+
+	if ai.pools == nil {
+		ai.pools = map[string]*addrindex.Index{}
+	}
+	i, ok := ai.pools[name]
+	if !ok {
+		i = addrindex.NewIndex(addressIndexTTL)
+		ai.pools[name] = i
+	}
+	return i
+}
+
+// allAddresses returns addresses of all users of request pool. Result must
+// not be changed.
+func (h Handler) allAddresses(c echo.Context) ([]addrindex.Address, error) {
+	return h.addresses.pool(h.pool(c).Name).Addresses(h.store(c))
+}
+
+// addressesChanged makes address changes of request pool seen by next
+// allAddresses call.
+func (h Handler) addressesChanged(c echo.Context) {
+	h.addresses.pool(h.pool(c).Name).Invalidate()
+}
+
+// lookupAddresses returns addresses which contain query, case is ignored.
+func lookupAddresses(addrs []addrindex.Address, query string) []addrindex.Address {
+	q := strings.ToLower(query)
+
+	var found []addrindex.Address
+
+	for _, a := range addrs {
+		if strings.Contains(strings.ToLower(a.Address), q) {
+			found = append(found, a)
+		}
+	}
+
+	return found
+}
+
+// duplicateAddresses returns groups of the same address owned by different
+// users, groups are sorted by address.
+func duplicateAddresses(addrs []addrindex.Address) [][]addrindex.Address {
+	byAddr := map[string][]addrindex.Address{}
+	for _, a := range addrs {
+		key := strings.ToLower(a.Address)
+		byAddr[key] = append(byAddr[key], a)
+	}
+
+	var dups [][]addrindex.Address
+
+	for _, group := range byAddr {
+		for _, a := range group[1:] {
+			if a.User.ID != group[0].User.ID {
+				dups = append(dups, group)
+				break
+			}
+		}
+	}
+
+	sort.Slice(dups, func(i, j int) bool {
+		return strings.ToLower(dups[i][0].Address) <
+			strings.ToLower(dups[j][0].Address)
+	})
+
+	return dups
+}
+
+// otherOwners returns users other than userID who own address in any coin.
+func (h Handler) otherOwners(c echo.Context, userID uint,
+	address string) ([]bestore.User, error) {
+	addrs, err := h.allAddresses(c)
+	if err != nil {
+		return nil, err
+	}
+	return addrindex.Owners(addrs, userID, address), nil
+}
+
+// ownersEmails returns comma separated emails of users.
+func ownersEmails(users []bestore.User) string {
+	emails := make([]string, len(users))
+	for i, u := range users {
+		emails[i] = u.Email
+	}
+	return strings.Join(emails, ", ")
+}
+
+type addressLookupPageData struct {
+	Query     string
+	Addresses []addrindex.Address
+}
+
+// AddressLookup shows addresses of all users which contain query.
+func (h Handler) AddressLookup(c echo.Context) error {
+	data := addressLookupPageData{
+		Query: strings.TrimSpace(c.QueryParam("q")),
+	}
+
+	if data.Query != "" {
+		addrs, err := h.allAddresses(c)
+		if err != nil {
+			return err
+		}
+		data.Addresses = lookupAddresses(addrs, data.Query)
+	}
+
+	return c.Render(http.StatusOK, "addresses", data)
+}
+
+type apiOwnedAddress struct {
+	UserID  uint   `json:"user_id"`
+	Email   string `json:"email"`
+	Coin    string `json:"coin"`
+	Address string `json:"address"`
+}
+
+// APIAddressLookup returns addresses of all users which contain query as
+// JSON.
+func (h Handler) APIAddressLookup(c echo.Context) error {
+	q := strings.TrimSpace(c.QueryParam("q"))
+	if q == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "blank query")
+	}
+
+	addrs, err := h.allAddresses(c)
+	if err != nil {
+		return err
+	}
+
+	res := []apiOwnedAddress{}
+
+	for _, a := range lookupAddresses(addrs, q) {
+		res = append(res, apiOwnedAddress{
+			UserID:  a.User.ID,
+			Email:   a.User.Email,
+			Coin:    fmt.Sprintf("%s", a.Coin),
+			Address: a.Address,
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+type addressDuplicatesPageData struct {
+	Duplicates [][]addrindex.Address
+}
+
+// AddressDuplicates shows addresses owned by more than one user.
+func (h Handler) AddressDuplicates(c echo.Context) error {
+	addrs, err := h.allAddresses(c)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, "address-duplicates",
+		addressDuplicatesPageData{Duplicates: duplicateAddresses(addrs)})
+}
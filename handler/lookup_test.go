@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/addrindex"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_duplicateAddresses(t *testing.T) {
+	a := bestore.User{ID: 1}
+	b := bestore.User{ID: 2}
+
+	addrs := []addrindex.Address{
+		{User: a, Coin: bestore.BTC, Address: "z"},
+		{User: b, Coin: bestore.ETH, Address: "0xAB"},
+		{User: a, Coin: bestore.ETH, Address: "own"},
+		{User: a, Coin: bestore.BTC, Address: "own"},
+		{User: b, Coin: bestore.BTC, Address: "Z"},
+		{User: a, Coin: bestore.ETH, Address: "0xab"},
+	}
+
+	assert.Equal(t, [][]addrindex.Address{
+		{addrs[1], addrs[5]},
+		{addrs[0], addrs[4]},
+	}, duplicateAddresses(addrs))
+}
+
+func Test_lookupAddresses(t *testing.T) {
+	addrs := []addrindex.Address{
+		{Coin: bestore.ETH, Address: "0xAbC1"},
+		{Coin: bestore.BTC, Address: "1xyz"},
+	}
+
+	assert.Equal(t, addrs[:1], lookupAddresses(addrs, "abc"))
+	assert.Empty(t, lookupAddresses(addrs, "none"))
+}
+
+func Test_APIAddressLookup(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUsers").Return([]bestore.User{
+		{ID: 3, Email: "a@example.com"},
+		{ID: 4, Email: "b@example.com"},
+	}, nil)
+	th.s.On("GetUserAddresses", uint(3)).Return([]bestore.UserAddress{
+		{UserID: 3, Coin: bestore.ETH, Address: "0xAbc1"},
+	}, nil)
+	th.s.On("GetUserAddresses", uint(4)).Return([]bestore.UserAddress{
+		{UserID: 4, Coin: bestore.BTC, Address: "1xyz"},
+	}, nil)
+
+	res := th.serve("/api/addresses", th.APIAddressLookup,
+		httptest.NewRequest(http.MethodGet, "/api/addresses?q=abc", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `[{"user_id": 3, "email": "a@example.com",
+		"coin": "ETH", "address": "0xAbc1"}]`, res.Body.String())
+}
+
+func Test_APIAddressLookup_indexed(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUsers").Return([]bestore.User{{ID: 3}}, nil)
+	th.s.On("GetUserAddresses", uint(3)).Return([]bestore.UserAddress{}, nil)
+
+	for i := 0; i < 2; i++ {
+		res := th.serve("/api/addresses", th.APIAddressLookup,
+			httptest.NewRequest(http.MethodGet, "/api/addresses?q=abc", nil))
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `[]`, res.Body.String())
+	}
+
+	// Addresses are read once for both lookups.
+	th.s.AssertNumberOfCalls(t, "GetUsers", 1)
+	th.s.AssertNumberOfCalls(t, "GetUserAddresses", 1)
+}
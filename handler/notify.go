@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/boomstarternetwork/mineradmin/mail"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/labstack/echo"
+)
+
+// notify sends notification in background, so slow mail server does not
+// delay response. Nothing is sent if notifications are not configured,
+// failures are logged only. send outlives request, so it uses pool stores
+// instead of request ones.
+func (h Handler) notify(c echo.Context, send func(n mail.Notifier) error) {
+	if h.notifier == nil {
+		return
+	}
+
+	n := *h.notifier
+	logger := c.Logger()
+
+	go func() {
+		err := send(n)
+		if err != nil {
+			logger.Error("failed to send notification: " + err.Error())
+		}
+	}()
+}
+
+// notifyAddressChanged alerts user that address change was applied.
+func (h Handler) notifyAddressChanged(c echo.Context,
+	ch mastore.AddressChange, eligibleAt time.Time) {
+	s := h.pool(c).Store
+
+	h.notify(c, func(n mail.Notifier) error {
+		user, err := s.GetUserByID(ch.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to get user %d from DB: %v",
+				ch.UserID, err)
+		}
+
+		return n.AddressChanged(user.Email, user.Name, ch.Action, ch.Coin,
+			ch.Address, eligibleAt)
+	})
+}
+
+// notifyPasswordReset alerts admins that password of admin with ID was
+// reset.
+func (h Handler) notifyPasswordReset(c echo.Context, adminID uint) {
+	by := adminLogin(c)
+
+	h.notify(c, func(n mail.Notifier) error {
+		admins, err := h.primary().Store.GetAdmins()
+		if err != nil {
+			return fmt.Errorf("failed to get admins from DB: %v", err)
+		}
+
+		for _, a := range admins {
+			if a.ID == adminID {
+				return n.AdminPasswordReset(a.Login, by)
+			}
+		}
+
+		return fmt.Errorf("admin %d not found", adminID)
+	})
+}
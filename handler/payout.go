@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/labstack/echo"
+)
+
+// payoutAddress returns address payouts go to: primary one if it is still
+// user address or the only user address.
+func payoutAddress(addrs []string, primary string) string {
+	if hasAddress(addrs, primary) {
+		return primary
+	}
+	if len(addrs) == 1 {
+		return addrs[0]
+	}
+	return ""
+}
+
+// userPayoutAddresses returns user addresses and payout addresses by coin.
+func (h Handler) userPayoutAddresses(c echo.Context, userID uint) (
+	map[bestore.Coin][]string, map[bestore.Coin]string, error) {
+	uas, err := h.store(c).GetUserAddresses(userID)
+	if err != nil {
+		return nil, nil, errors.New("failed to get user addresses from DB: " +
+			err.Error())
+	}
+
+	primaries, err := h.mstore(c).PrimaryAddresses(userID)
+	if err != nil {
+		return nil, nil, errors.New("failed to get user primary addresses " +
+			"from DB: " + err.Error())
+	}
+
+	addrs := map[bestore.Coin][]string{}
+
+	for _, ua := range uas {
+		addrs[ua.Coin] = append(addrs[ua.Coin], ua.Address)
+	}
+
+	primary := map[bestore.Coin]string{}
+
+	for cn, as := range addrs {
+		if a := payoutAddress(as, primaries[fmt.Sprintf("%s", cn)]); a != "" {
+			primary[cn] = a
+		}
+	}
+
+	return addrs, primary, nil
+}
+
+type apiAddress struct {
+	Coin      string     `json:"coin"`
+	Address   string     `json:"address"`
+	Primary   bool       `json:"primary"`
+	Label     string     `json:"label,omitempty"`
+	Note      string     `json:"note,omitempty"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// APIUserAddresses returns user addresses as JSON.
+func (h Handler) APIUserAddresses(c echo.Context) error {
+	userID64, err := strconv.ParseUint(c.Param("user-id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	userID := uint(userID64)
+
+	_, err = h.store(c).GetUserByID(userID)
+	if err != nil {
+		if bestore.NotFound(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "user not found")
+		}
+		return errors.New("failed to get user from DB: " + err.Error())
+	}
+
+	addrs, primary, err := h.userPayoutAddresses(c, userID)
+	if err != nil {
+		return err
+	}
+
+	meta, err := h.userAddressesMeta(c, userID)
+	if err != nil {
+		return err
+	}
+
+	res := []apiAddress{}
+
+	for _, cn := range coinsOf(addrs) {
+		for _, a := range addrs[cn] {
+			m := meta[addressKey(cn, a)]
+			aa := apiAddress{
+				Coin:      fmt.Sprintf("%s", cn),
+				Address:   a,
+				Primary:   a == primary[cn],
+				Label:     m.Label,
+				Note:      m.Note,
+				CreatedBy: m.CreatedBy,
+			}
+			if !m.CreatedAt.IsZero() {
+				aa.CreatedAt = &m.CreatedAt
+			}
+			res = append(res, aa)
+		}
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// coinsOf returns coins of addresses map sorted by name.
+func coinsOf(addrs map[bestore.Coin][]string) []bestore.Coin {
+	var coins []bestore.Coin
+	for cn := range addrs {
+		coins = append(coins, cn)
+	}
+	sort.Slice(coins, func(i, j int) bool {
+		return fmt.Sprint(coins[i]) < fmt.Sprint(coins[j])
+	})
+	return coins
+}
+
+// PayoutsExport writes CSV with payout address of every user and coin.
+// Addresses in cooldown after approval are skipped. Nothing is exported if
+// some user has several addresses of coin and none of them is primary, so
+// user is not left without payout silently.
+func (h Handler) PayoutsExport(c echo.Context) error {
+	users, err := h.store(c).GetUsers()
+	if err != nil {
+		return errors.New("failed to get users from DB: " + err.Error())
+	}
+
+	cooling, err := h.mstore(c).CoolingAddressChanges(time.Now())
+	if err != nil {
+		return errors.New("failed to get cooling address changes from DB: " +
+			err.Error())
+	}
+
+	notEligible := map[string]bool{}
+	for _, ch := range cooling {
+		notEligible[fmt.Sprintf("%d/%s/%s", ch.UserID, ch.Coin,
+			ch.Address)] = true
+	}
+
+	rows := [][]string{
+		{"user_id", "email", "coin", "address", "label", "note"},
+	}
+
+	// noPrimary are user emails and coins without payout address.
+	var noPrimary []string
+
+	for _, u := range users {
+		addrs, primary, err := h.userPayoutAddresses(c, u.ID)
+		if err != nil {
+			return err
+		}
+
+		meta, err := h.userAddressesMeta(c, u.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, cn := range coinsOf(addrs) {
+			a, ok := primary[cn]
+			if !ok {
+				if len(addrs[cn]) > 1 {
+					noPrimary = append(noPrimary,
+						fmt.Sprintf("%s %s", u.Email, cn))
+				}
+				continue
+			}
+			coinStr := fmt.Sprintf("%s", cn)
+			if notEligible[fmt.Sprintf("%d/%s/%s", u.ID, coinStr, a)] {
+				continue
+			}
+			m := meta[addressKey(cn, a)]
+			rows = append(rows, []string{
+				strconv.FormatUint(uint64(u.ID), 10), u.Email, coinStr, a,
+				m.Label, m.Note})
+		}
+	}
+
+	if len(noPrimary) > 0 {
+		return echo.NewHTTPError(http.StatusConflict, tr(c,
+			"choose primary addresses before export: %s",
+			strings.Join(noPrimary, ", ")))
+	}
+
+	// Response is written only when all addresses are read, so failed
+	// export is not downloaded as partial CSV.
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		`attachment; filename="payouts.csv"`)
+	c.Response().WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(c.Response())
+
+	return w.WriteAll(rows)
+}
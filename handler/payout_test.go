@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_APIUserAddresses(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3}, nil)
+	th.s.On("GetUserAddresses", uint(3)).Return([]bestore.UserAddress{
+		{UserID: 3, Coin: bestore.ETH, Address: "addr1"},
+		{UserID: 3, Coin: bestore.ETH, Address: "addr2"},
+		{UserID: 3, Coin: bestore.BTC, Address: "addr3"},
+	}, nil)
+	th.ms.On("PrimaryAddresses", uint(3)).
+		Return(map[string]string{"ETH": "addr2"}, nil)
+	th.ms.On("UserAddressesMeta", uint(3)).Return([]mastore.AddressMeta{
+		{UserID: 3, Coin: "ETH", Address: "addr1", Label: "rig",
+			Note: "garage", CreatedBy: "admin",
+			CreatedAt: time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC)},
+	}, nil)
+
+	res := th.serve("/api/users/:user-id/addresses", th.APIUserAddresses,
+		httptest.NewRequest(http.MethodGet, "/api/users/3/addresses", nil))
+
+	// The only BTC address is primary without choosing it.
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `[
+		{"coin": "BTC", "address": "addr3", "primary": true},
+		{"coin": "ETH", "address": "addr1", "primary": false,
+			"label": "rig", "note": "garage", "created_by": "admin",
+			"created_at": "2018-07-01T00:00:00Z"},
+		{"coin": "ETH", "address": "addr2", "primary": true}
+	]`, res.Body.String())
+}
+
+func Test_PayoutsExport(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUsers").Return([]bestore.User{
+		{ID: 3, Email: "a@example.com"},
+		{ID: 4, Email: "b@example.com"},
+	}, nil)
+	th.s.On("GetUserAddresses", uint(3)).Return([]bestore.UserAddress{
+		{UserID: 3, Coin: bestore.ETH, Address: "addr1"},
+		{UserID: 3, Coin: bestore.ETH, Address: "addr2"},
+	}, nil)
+	th.s.On("GetUserAddresses", uint(4)).Return([]bestore.UserAddress{
+		{UserID: 4, Coin: bestore.BTC, Address: "addr3"},
+	}, nil)
+	th.ms.On("PrimaryAddresses", uint(3)).
+		Return(map[string]string{"ETH": "addr2"}, nil)
+	th.ms.On("PrimaryAddresses", uint(4)).Return(map[string]string{}, nil)
+	th.ms.On("UserAddressesMeta", uint(3)).Return([]mastore.AddressMeta{
+		{UserID: 3, Coin: "ETH", Address: "addr2", Label: "rig"},
+	}, nil)
+	th.ms.On("UserAddressesMeta", uint(4)).
+		Return([]mastore.AddressMeta{}, nil)
+	th.ms.On("CoolingAddressChanges", mock.Anything).
+		Return([]mastore.AddressChange{
+			{UserID: 4, Coin: "BTC", Address: "addr3"},
+		}, nil)
+
+	res := th.serve("/payouts.csv", th.PayoutsExport,
+		httptest.NewRequest(http.MethodGet, "/payouts.csv", nil))
+
+	// Only primary addresses are paid, cooling ones are not yet.
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "user_id,email,coin,address,label,note\n"+
+		"3,a@example.com,ETH,addr2,rig,\n", res.Body.String())
+}
+
+func Test_PayoutsExport_noPrimary(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUsers").Return([]bestore.User{
+		{ID: 3, Email: "a@example.com"},
+	}, nil)
+	th.s.On("GetUserAddresses", uint(3)).Return([]bestore.UserAddress{
+		{UserID: 3, Coin: bestore.ETH, Address: "addr1"},
+		{UserID: 3, Coin: bestore.ETH, Address: "addr2"},
+	}, nil)
+	th.ms.On("PrimaryAddresses", uint(3)).Return(map[string]string{}, nil)
+	th.ms.On("UserAddressesMeta", uint(3)).
+		Return([]mastore.AddressMeta{}, nil)
+	th.ms.On("CoolingAddressChanges", mock.Anything).
+		Return([]mastore.AddressChange{}, nil)
+
+	res := th.serve("/payouts.csv", th.PayoutsExport,
+		httptest.NewRequest(http.MethodGet, "/payouts.csv", nil))
+
+	assert.Equal(t, http.StatusConflict, res.Code)
+	assert.Contains(t, res.Body.String(), "a@example.com ETH")
+	assert.NotContains(t, res.Body.String(), "user_id")
+}
+
+func Test_PayoutsExport_addressesFailed(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUsers").Return([]bestore.User{
+		{ID: 3, Email: "a@example.com"},
+	}, nil)
+	th.s.On("GetUserAddresses", uint(3)).Return([]bestore.UserAddress(nil),
+		errors.New("connection refused"))
+	th.ms.On("CoolingAddressChanges", mock.Anything).
+		Return([]mastore.AddressChange{}, nil)
+
+	res := th.serve("/payouts.csv", th.PayoutsExport,
+		httptest.NewRequest(http.MethodGet, "/payouts.csv", nil))
+
+	// Partial export is not sent.
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.NotContains(t, res.Body.String(), "user_id")
+}
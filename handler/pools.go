@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/trash"
+	"github.com/boomstarternetwork/mineradmin/webhook"
+	"github.com/labstack/echo"
+)
+
+const (
+	// DefaultPool is primary pool name if none is configured.
+	DefaultPool = "default"
+
+	// RoleAdmin may view and change pool.
+	RoleAdmin = "admin"
+	// RoleViewer may only view pool.
+	RoleViewer = "viewer"
+
+	poolCookie = "pool"
+	poolKey    = "pool"
+	poolsKey   = "pools"
+	// poolField is hidden form input with pool form is rendered for.
+	poolField = "form-pool"
+)
+
+// Pool is mining pool database managed by mineradmin, it keeps both bestore
+// and mastore data.
+type Pool struct {
+	Name   string
+	Store  bestore.Store
+	MStore mastore.Store
+	// Events can be nil if events are not emitted to webhooks.
+	Events webhook.Emitter
+
+	// roles are admin roles by login.
+	roles map[string]string
+}
+
+// Role returns admin role in pool, empty string is returned if admin has
+// no access to pool. Every admin is RoleAdmin in pool without roles.
+func (p Pool) Role(login string) string {
+	if p.roles == nil {
+		return RoleAdmin
+	}
+	return p.roles[login]
+}
+
+func newPools(conf Config) []Pool {
+	name := conf.PoolName
+	if name == "" {
+		name = DefaultPool
+	}
+
+	pools := append([]Pool{{
+		Name:   name,
+		Store:  conf.Store,
+		MStore: conf.MStore,
+		Events: conf.Events,
+	}}, conf.Pools...)
+
+	for i := range pools {
+		pools[i].roles = conf.Roles[pools[i].Name]
+	}
+
+	return pools
+}
+
+// ParseRoles parses admin roles in "pool:login=role" form, e.g.
+// "eu:alice=viewer", into roles by pool name and admin login.
+func ParseRoles(specs []string) (map[string]map[string]string, error) {
+	roles := map[string]map[string]string{}
+
+	for _, spec := range specs {
+		i := strings.Index(spec, ":")
+		j := strings.LastIndex(spec, "=")
+		if i <= 0 || j < i+2 {
+			return nil, errors.New("invalid role " + spec +
+				": expected pool:login=role")
+		}
+
+		pool, login, role := spec[:i], spec[i+1:j], spec[j+1:]
+		if role != RoleAdmin && role != RoleViewer {
+			return nil, errors.New("invalid role " + spec + ": role must " +
+				"be " + RoleAdmin + " or " + RoleViewer)
+		}
+
+		if roles[pool] == nil {
+			roles[pool] = map[string]string{}
+		}
+		roles[pool][login] = role
+	}
+
+	return roles, nil
+}
+
+// primary returns pool which keeps admin accounts.
+func (h Handler) primary() Pool {
+	return h.pools[0]
+}
+
+// accountStore returns bestore of primary pool bound to request context.
+func (h Handler) accountStore(c echo.Context) bestore.Store {
+	return h.poolStore(c, h.primary())
+}
+
+// accountMStore returns mastore of primary pool bound to request context.
+func (h Handler) accountMStore(c echo.Context) mastore.Store {
+	return h.poolMStore(c, h.primary())
+}
+
+// accountBin returns trash bin of primary pool, trashed admins are kept
+// there.
+func (h Handler) accountBin(c echo.Context) trash.Bin {
+	return h.poolBin(c, h.primary())
+}
+
+// pool returns request pool chosen by PoolAccess, primary pool is returned
+// for routes without it, e.g. login and miner portal.
+func (h Handler) pool(c echo.Context) Pool {
+	if p, ok := c.Get(poolKey).(Pool); ok {
+		return p
+	}
+	return h.primary()
+}
+
+// kindPool returns pool which trash of kind is kept in, trashed admins are
+// kept with admin accounts.
+func (h Handler) kindPool(c echo.Context, kind string) Pool {
+	if kind == trash.Admins {
+		return h.primary()
+	}
+	return h.pool(c)
+}
+
+// writtenPool returns pool which request changes.
+func (h Handler) writtenPool(c echo.Context) Pool {
+	if strings.HasPrefix(c.Path(), "/admins") {
+		return h.primary()
+	}
+	return h.kindPool(c, c.Param("kind"))
+}
+
+// adminPools returns pools admin has role in.
+func (h Handler) adminPools(login string) []Pool {
+	var pools []Pool
+	for _, p := range h.pools {
+		if p.Role(login) != "" {
+			pools = append(pools, p)
+		}
+	}
+	return pools
+}
+
+// preferenceRoutes change only admin own preferences, so viewers may post
+// to them.
+var preferenceRoutes = map[string]bool{
+	"/pool":     true,
+	"/settings": true,
+}
+
+// PoolAccess is middleware which selects request pool among pools admin has
+// role in: pool named by pool cookie or the first one. Only pool admins may
+// post to pool routes, admin accounts may be changed only by admins of
+// primary pool. Trashed admins are refused, though their tokens are still
+// valid. Forms rendered for another pool than selected one are refused, so
+// form opened before switching pool in other tab does not change new pool.
+// It must run after authorization.
+func (h Handler) PoolAccess(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		login := adminLogin(c)
+
+		trashed, err := h.accountBin(c).AdminTrashed(login)
+		if err != nil {
+			return errors.New("failed to check admin in trash: " +
+				err.Error())
+		}
+		if trashed {
+			return echo.NewHTTPError(http.StatusUnauthorized,
+				tr(c, "your account is removed"))
+		}
+
+		pools := h.adminPools(login)
+		if len(pools) == 0 {
+			return echo.NewHTTPError(http.StatusForbidden,
+				tr(c, "you have no access to any pool"))
+		}
+
+		p := pools[0]
+		if cookie, err := c.Cookie(poolCookie); err == nil {
+			for _, ap := range pools {
+				if ap.Name == cookie.Value {
+					p = ap
+				}
+			}
+		}
+
+		c.Set(poolKey, p)
+		c.Set(poolsKey, pools)
+
+		switch c.Request().Method {
+		case http.MethodGet, http.MethodHead:
+		default:
+			if fp := c.FormValue(poolField); !preferenceRoutes[c.Path()] &&
+				fp != "" && fp != p.Name {
+				return echo.NewHTTPError(http.StatusConflict,
+					tr(c, "form was opened for pool %s, reload page", fp))
+			}
+
+			wp := h.writtenPool(c)
+			if !preferenceRoutes[c.Path()] && wp.Role(login) != RoleAdmin {
+				return echo.NewHTTPError(http.StatusForbidden,
+					tr(c, "you may only view pool %s", wp.Name))
+			}
+		}
+
+		return next(c)
+	}
+}
+
+// SelectPool remembers pool admin works with.
+func (h Handler) SelectPool(c echo.Context) error {
+	name := c.FormValue("pool")
+
+	for _, p := range h.adminPools(adminLogin(c)) {
+		if p.Name == name {
+			c.SetCookie(&http.Cookie{
+				Name:     poolCookie,
+				Value:    name,
+				Path:     "/",
+				HttpOnly: true,
+			})
+			return c.Redirect(http.StatusFound, "/")
+		}
+	}
+
+	return echo.NewHTTPError(http.StatusBadRequest, tr(c, "unknown pool"))
+}
+
+// poolName returns name of request pool chosen by PoolAccess.
+func poolName(c echo.Context) string {
+	if c == nil {
+		return ""
+	}
+	p, _ := c.Get(poolKey).(Pool)
+	return p.Name
+}
+
+// selectablePools returns pools admin may choose from.
+func selectablePools(c echo.Context) []Pool {
+	if c == nil {
+		return nil
+	}
+	pools, _ := c.Get(poolsKey).([]Pool)
+	return pools
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_ParseRoles(t *testing.T) {
+	roles, err := ParseRoles([]string{"eu:alice=viewer", "eu:bob=admin",
+		"us:alice=admin"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]map[string]string{
+			"eu": {"alice": RoleViewer, "bob": RoleAdmin},
+			"us": {"alice": RoleAdmin},
+		}, roles)
+	}
+
+	for _, spec := range []string{"eu", "eu:alice", ":alice=admin",
+		"eu:=admin", "eu:alice=owner"} {
+		_, err := ParseRoles([]string{spec})
+		assert.Error(t, err, spec)
+	}
+}
+
+func Test_Pool_Role(t *testing.T) {
+	pools := newPools(Config{
+		Pools: []Pool{{Name: "eu"}},
+		Roles: map[string]map[string]string{"eu": {"alice": RoleViewer}},
+	})
+
+	if !assert.Len(t, pools, 2) {
+		return
+	}
+
+	assert.Equal(t, DefaultPool, pools[0].Name)
+	assert.Equal(t, RoleAdmin, pools[0].Role("bob"))
+	assert.Equal(t, RoleViewer, pools[1].Role("alice"))
+	assert.Equal(t, "", pools[1].Role("bob"))
+}
+
+func Test_PoolAccess_trashedAdmin(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("TrashItems", "admins").Return([]mastore.TrashItem{
+		{ID: 7, Kind: "admins", EntityID: 1, Name: "login"},
+	}, nil)
+
+	res := th.serve("/users", th.PoolAccess(th.Users),
+		httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	th.s.AssertNotCalled(t, "GetUsers")
+}
+
+// poolsTestHandler is test handler with "eu" pool where admin "login" is
+// viewer.
+type poolsTestHandler struct {
+	testHandler
+	eu Pool
+}
+
+func newPoolsTestHandler() poolsTestHandler {
+	eu := Pool{Name: "eu", Store: bestore.NewMockStore(),
+		MStore: mastore.NewMockStore()}
+
+	th := newTestHandler(Config{
+		Pools: []Pool{eu},
+		Roles: map[string]map[string]string{"eu": {"login": RoleViewer}},
+	})
+	th.ms.On("TrashItems", "admins").Return([]mastore.TrashItem{}, nil)
+
+	return poolsTestHandler{testHandler: th, eu: eu}
+}
+
+// euStores returns mock stores of eu pool.
+func (th poolsTestHandler) euStores() (*bestore.MockStore,
+	*mastore.MockStore) {
+	return th.eu.Store.(*bestore.MockStore), th.eu.MStore.(*mastore.MockStore)
+}
+
+// withPool returns req with pool cookie selecting pool.
+func withPool(req *http.Request, pool string) *http.Request {
+	req.AddCookie(&http.Cookie{Name: poolCookie, Value: pool})
+	return req
+}
+
+func Test_Users_pool(t *testing.T) {
+	th := newPoolsTestHandler()
+	s, ms := th.euStores()
+
+	s.On("GetUsers").Return([]bestore.User{{ID: 3}}, nil)
+	ms.On("WorkerHashrates", mock.Anything, stats.AverageWindow).
+		Return([]mastore.WorkerHashrate{}, nil)
+
+	res := th.serve("/users", th.PoolAccess(th.Users), withPool(
+		httptest.NewRequest(http.MethodGet, "/users", nil), "eu"))
+
+	// Viewer sees users of selected pool only.
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, []userRow{{User: bestore.User{ID: 3}}},
+		th.rendered.data.(usersPageData).Users)
+
+	s.AssertExpectations(t)
+	th.s.AssertNotCalled(t, "GetUsers")
+}
+
+func Test_NewProject_poolViewer(t *testing.T) {
+	th := newPoolsTestHandler()
+	s, _ := th.euStores()
+
+	res := th.serve("/projects", th.PoolAccess(th.NewProject), withPool(
+		newFormRequest("/projects", url.Values{"name": {"Test"}}), "eu"))
+
+	assert.Equal(t, http.StatusForbidden, res.Code)
+
+	s.AssertNotCalled(t, "AddProject", mock.Anything)
+	th.s.AssertNotCalled(t, "AddProject", mock.Anything)
+}
+
+func Test_NewProject_otherPoolForm(t *testing.T) {
+	th := newPoolsTestHandler()
+	s, _ := th.euStores()
+
+	// Form was opened for eu pool, then default pool was selected in other
+	// tab.
+	res := th.serve("/projects", th.PoolAccess(th.NewProject), withPool(
+		newFormRequest("/projects", url.Values{
+			"name":      {"Test"},
+			"form-pool": {"eu"},
+		}), DefaultPool))
+
+	assert.Equal(t, http.StatusConflict, res.Code)
+
+	s.AssertNotCalled(t, "AddProject", mock.Anything)
+	th.s.AssertNotCalled(t, "AddProject", mock.Anything)
+}
+
+func Test_SelectPool(t *testing.T) {
+	th := newPoolsTestHandler()
+
+	for _, pool := range []string{"eu", DefaultPool} {
+		res := th.serve("/pool", th.SelectPool,
+			newFormRequest("/pool", url.Values{"pool": {pool}}))
+
+		assert.Equal(t, http.StatusFound, res.Code, pool)
+
+		cookies := res.Result().Cookies()
+		if assert.Len(t, cookies, 1, pool) {
+			assert.Equal(t, poolCookie, cookies[0].Name)
+			assert.Equal(t, pool, cookies[0].Value)
+		}
+	}
+
+	res := th.serve("/pool", th.SelectPool,
+		newFormRequest("/pool", url.Values{"pool": {"us"}}))
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Empty(t, res.Result().Cookies())
+}
@@ -0,0 +1,578 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/balance"
+	"github.com/boomstarternetwork/mineradmin/coin"
+	"github.com/boomstarternetwork/mineradmin/mail"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/trash"
+	"github.com/boomstarternetwork/mineradmin/webhook"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+)
+
+const (
+	// PortalContextKey is context key of miner portal JWT token.
+	PortalContextKey = "miner"
+	// PortalCookie is name of cookie miner portal JWT token is kept in.
+	PortalCookie = "portal-auth"
+
+	// loginLinkTTL is how long portal login link is valid.
+	loginLinkTTL = 15 * time.Minute
+	// portalSessionTTL is how long miner stays signed in to portal.
+	portalSessionTTL = 12 * time.Hour
+	// loginLinkCooldown is how long no new login link is sent to the same
+	// email, so portal can not be used to flood mailboxes.
+	loginLinkCooldown = time.Minute
+	// portalBalancesTTL is how long portal shows balances without
+	// rereading them, it requests balances of every project.
+	portalBalancesTTL = time.Minute
+)
+
+// portalUser returns ID and email of miner signed in to portal from JWT
+// token, zero ID is returned for admins and anonymous requests.
+func portalUser(c echo.Context) (uint, string) {
+	token, ok := c.Get(PortalContextKey).(*jwt.Token)
+	if !ok {
+		return 0, ""
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, ""
+	}
+	id, _ := claims["user_id"].(float64)
+	email, _ := claims["email"].(string)
+	return uint(id), email
+}
+
+// actor returns who makes request: admin login or "user:" followed by
+// email of miner signed in to portal.
+func actor(c echo.Context) string {
+	if login := adminLogin(c); login != "" {
+		return login
+	}
+	if id, email := portalUser(c); id != 0 {
+		return "user:" + email
+	}
+	return ""
+}
+
+// hashLoginToken returns hash login token is stored by, so tokens can not be
+// used by whoever reads DB.
+func hashLoginToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateLoginToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// portalBaseURL returns URL portal links start with, request host is used
+// if portal URL is not configured.
+func (h Handler) portalBaseURL(c echo.Context) string {
+	if h.portalURL != "" {
+		return strings.TrimRight(h.portalURL, "/")
+	}
+	return c.Scheme() + "://" + c.Request().Host
+}
+
+type portalLoginPageData struct {
+	// Token is login link token to confirm, login form is shown if it is
+	// empty.
+	Token string
+	Form  formData
+}
+
+func (h Handler) PortalLogin(c echo.Context) error {
+	if c.Request().Method == http.MethodGet {
+		return c.Render(http.StatusOK, "portal/login", portalLoginPageData{})
+	}
+
+	form := newFormData(c, "email")
+
+	email, err := ValidateUserEmail(form.Value("email"))
+	if err != nil {
+		form.Errors["email"] = tr(c, err.Error())
+		return c.Render(http.StatusBadRequest, "portal/login",
+			portalLoginPageData{Form: form})
+	}
+
+	users, err := h.store(c).GetUsers()
+	if err != nil {
+		return errors.New("failed to get users from DB: " + err.Error())
+	}
+
+	for _, u := range users {
+		if strings.EqualFold(u.Email, email) {
+			err = h.sendLoginLink(c, u)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	// The same message is shown for unknown email, so portal does not tell
+	// who is registered.
+	return h.redirectWithFlash(c, "/portal/login", FlashSuccess,
+		"If %s is registered, sign in link is sent to it", email)
+}
+
+// sendLoginLink saves new login token of user and emails login link to
+// user. Nothing is sent if link was sent to user email less than cooldown
+// ago, miner is not told so to not tell who is registered.
+func (h Handler) sendLoginLink(c echo.Context, user bestore.User) error {
+	now := time.Now()
+
+	ok, err := h.mstore(c).RequestLoginLink(strings.ToLower(user.Email), now,
+		now.Add(-loginLinkCooldown))
+	if err != nil {
+		return errors.New("failed to add login link request to DB: " +
+			err.Error())
+	}
+	if !ok {
+		return nil
+	}
+
+	err = h.mstore(c).RemoveExpiredLoginTokens(now)
+	if err != nil {
+		c.Logger().Error("failed to remove expired login tokens from DB: " +
+			err.Error())
+	}
+
+	token, err := generateLoginToken()
+	if err != nil {
+		return errors.New("failed to generate login token: " + err.Error())
+	}
+
+	err = h.mstore(c).AddLoginToken(hashLoginToken(token), user.ID,
+		now.Add(loginLinkTTL))
+	if err != nil {
+		return errors.New("failed to add login token to DB: " + err.Error())
+	}
+
+	link := h.portalBaseURL(c) + "/portal/login/" + token
+
+	h.notify(c, func(n mail.Notifier) error {
+		return n.MagicLink(user.Email, user.Name, link, loginLinkTTL)
+	})
+
+	return nil
+}
+
+// PortalMagicLogin signs miner in by login link token. Link opened by GET
+// only asks to confirm, so mail scanners following links do not use token
+// up.
+func (h Handler) PortalMagicLogin(c echo.Context) error {
+	token := c.Param("token")
+
+	if c.Request().Method == http.MethodGet {
+		return c.Render(http.StatusOK, "portal/login", portalLoginPageData{
+			Token: token,
+		})
+	}
+
+	userID, err := h.mstore(c).UseLoginToken(hashLoginToken(token), time.Now())
+	if err != nil {
+		if err == mastore.ErrNotFound {
+			return h.redirectWithFlash(c, "/portal/login", FlashError,
+				"Sign in link is invalid or expired, request new one")
+		}
+		return errors.New("failed to use login token in DB: " + err.Error())
+	}
+
+	user, err := h.store(c).GetUserByID(userID)
+	if err != nil {
+		if bestore.NotFound(err) {
+			return h.redirectWithFlash(c, "/portal/login", FlashError,
+				"Sign in link is invalid or expired, request new one")
+		}
+		return errors.New("failed to get user from DB: " + err.Error())
+	}
+
+	jwtToken := jwt.New(jwt.SigningMethodHS256)
+
+	claims := jwtToken.Claims.(jwt.MapClaims)
+	claims["user_id"] = user.ID
+	claims["email"] = user.Email
+	claims["exp"] = time.Now().Add(portalSessionTTL).Unix()
+
+	tokenEnc, err := jwtToken.SignedString(h.portalJWTSecret)
+	if err != nil {
+		return errors.New("failed to sign authorization token: " + err.Error())
+	}
+
+	cookie := new(http.Cookie)
+	cookie.Name = PortalCookie
+	cookie.Value = tokenEnc
+	cookie.Path = "/portal"
+	cookie.Expires = time.Now().Add(portalSessionTTL)
+
+	c.SetCookie(cookie)
+
+	return c.Redirect(http.StatusFound, "/portal")
+}
+
+func (h Handler) PortalLogout(c echo.Context) error {
+	cookie := new(http.Cookie)
+	cookie.Name = PortalCookie
+	cookie.Value = ""
+	cookie.Path = "/portal"
+	cookie.Expires = time.Time{}
+
+	c.SetCookie(cookie)
+
+	return c.Redirect(http.StatusFound, "/portal/login")
+}
+
+type portalBalance struct {
+	ProjectName string
+	Totals      balance.Totals
+}
+
+type portalPageData struct {
+	User  bestore.User
+	Coins []bestore.Coin
+	// Addresses are user addresses by coin.
+	Addresses map[bestore.Coin][]string
+	// Primary are addresses payouts go to by coin.
+	Primary map[bestore.Coin]string
+	// Pending are pending address changes, most recent first.
+	Pending  []mastore.AddressChange
+	Balances []portalBalance
+	Total    balance.Totals
+	Form     formData
+}
+
+// IsPrimary checks that payouts in coin go to address.
+func (d portalPageData) IsPrimary(cn bestore.Coin, address string) bool {
+	return d.Primary[cn] == address
+}
+
+// portalSessionUser returns miner signed in to portal.
+func (h Handler) portalSessionUser(c echo.Context) (bestore.User, error) {
+	userID, _ := portalUser(c)
+
+	user, err := h.store(c).GetUserByID(userID)
+	if err != nil {
+		if bestore.NotFound(err) {
+			return bestore.User{}, echo.NewHTTPError(http.StatusNotFound,
+				tr(c, "user not found"))
+		}
+		return bestore.User{}, errors.New("failed to get user from DB: " +
+			err.Error())
+	}
+
+	return user, nil
+}
+
+func (h Handler) Portal(c echo.Context) error {
+	user, err := h.portalSessionUser(c)
+	if err != nil {
+		return err
+	}
+
+	return h.renderPortal(c, http.StatusOK, user, formData{})
+}
+
+func (h Handler) renderPortal(c echo.Context, code int, user bestore.User,
+	form formData) error {
+	addrs, primary, err := h.userPayoutAddresses(c, user.ID)
+	if err != nil {
+		return err
+	}
+
+	chs, err := h.mstore(c).UserAddressChanges(user.ID)
+	if err != nil {
+		return errors.New("failed to get user address changes from DB: " +
+			err.Error())
+	}
+
+	data := portalPageData{
+		User:      user,
+		Coins:     coin.List(),
+		Addresses: addrs,
+		Primary:   primary,
+		Form:      form,
+	}
+
+	for _, ch := range chs {
+		if ch.Status == mastore.ChangePending {
+			data.Pending = append(data.Pending, ch)
+		}
+	}
+
+	data.Balances, data.Total, err = h.userBalances(c, user.Email)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(code, "portal/index", data)
+}
+
+// projectUserBalance is user balance in project.
+type projectUserBalance struct {
+	ProjectID   uint
+	ProjectName string
+	Coins       []bestore.CoinAmount
+}
+
+// portalBalancesCache keeps balances of all users by pool name, so portal
+// pages do not request balances of every project. It is shared by handler
+// copies.
+type portalBalancesCache struct {
+	mutex sync.Mutex
+	pools map[string]*poolUserBalances
+}
+
+// poolUserBalances is last read balances of pool users. Its mutex is held
+// while they are reread, so concurrent requests read them once.
+type poolUserBalances struct {
+	mutex     sync.Mutex
+	updatedAt time.Time
+	// byEmail are user balances by lower case email.
+	byEmail map[string][]projectUserBalance
+}
+
+// pool returns cached user balances of pool.
+func (bc *portalBalancesCache) pool(name string) *poolUserBalances {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	if bc.pools == nil {
+		bc.pools = map[string]*poolUserBalances{}
+	}
+	pb, ok := bc.pools[name]
+	if !ok {
+		pb = &poolUserBalances{}
+		bc.pools[name] = pb
+	}
+	return pb
+}
+
+// cachedUserBalances returns balances of user with email in every project
+// read at most portalBalancesTTL ago.
+func (h Handler) cachedUserBalances(c echo.Context, email string) (
+	[]projectUserBalance, error) {
+	pb := h.portalBalances.pool(h.pool(c).Name)
+
+	pb.mutex.Lock()
+	defer pb.mutex.Unlock()
+
+	if time.Since(pb.updatedAt) >= portalBalancesTTL {
+		byEmail, err := h.readUserBalances(c)
+		if err != nil {
+			return nil, err
+		}
+		pb.byEmail = byEmail
+		pb.updatedAt = time.Now()
+	}
+
+	return pb.byEmail[strings.ToLower(email)], nil
+}
+
+// readUserBalances returns balances of all users by lower case email.
+func (h Handler) readUserBalances(c echo.Context) (
+	map[string][]projectUserBalance, error) {
+	projects, err := h.store(c).ProjectsBalances()
+	if err != nil {
+		return nil, errors.New("failed to get project balances from DB: " +
+			err.Error())
+	}
+
+	byEmail := map[string][]projectUserBalance{}
+
+	for _, p := range projects {
+		ubs, err := h.store(c).ProjectUsersBalances(p.ProjectID)
+		if err != nil {
+			return nil, errors.New("failed to get project users balances " +
+				"from DB: " + err.Error())
+		}
+
+		for _, ub := range ubs {
+			email := strings.ToLower(ub.Email)
+			byEmail[email] = append(byEmail[email], projectUserBalance{
+				ProjectID:   p.ProjectID,
+				ProjectName: p.ProjectName,
+				Coins:       ub.Coins,
+			})
+		}
+	}
+
+	return byEmail, nil
+}
+
+// userBalances returns user balances in projects which are not in trash and
+// their total.
+func (h Handler) userBalances(c echo.Context, email string) (
+	[]portalBalance, balance.Totals, error) {
+	pubs, err := h.cachedUserBalances(c, email)
+	if err != nil {
+		return nil, balance.Totals{}, err
+	}
+
+	trashed, err := h.bin(c).Hidden(trash.Projects)
+	if err != nil {
+		return nil, balance.Totals{}, errors.New("failed to get trashed " +
+			"projects from DB: " + err.Error())
+	}
+
+	var (
+		balances []portalBalance
+		coins    [][]bestore.CoinAmount
+	)
+
+	for _, pub := range pubs {
+		if trashed[pub.ProjectID] {
+			continue
+		}
+
+		t, err := h.totals(c, pub.Coins)
+		if err != nil {
+			return nil, balance.Totals{}, errors.New("failed to sum user " +
+				"balance: " + err.Error())
+		}
+		balances = append(balances, portalBalance{
+			ProjectName: pub.ProjectName,
+			Totals:      t,
+		})
+		coins = append(coins, pub.Coins)
+	}
+
+	total, err := h.totals(c, coins...)
+	if err != nil {
+		return nil, balance.Totals{}, errors.New("failed to sum user " +
+			"balances: " + err.Error())
+	}
+
+	return balances, total, nil
+}
+
+// PortalEditAddresses requests miner address change, which lands in admin
+// approval queue.
+func (h Handler) PortalEditAddresses(c echo.Context) error {
+	user, err := h.portalSessionUser(c)
+	if err != nil {
+		return err
+	}
+
+	action := c.FormValue("action")
+	if action != mastore.AddressAdd && action != mastore.AddressRemove {
+		return echo.NewHTTPError(http.StatusBadRequest, tr(c, "unknown action"))
+	}
+
+	form := newFormData(c, "coin", "address")
+
+	cn, err := bestore.ParseCoin(form.Value("coin"))
+	if err != nil {
+		form.Errors["coin"] = tr(c, "invalid coin")
+	}
+
+	address, err := ValidateAddress(form.Value("address"))
+	if err != nil {
+		form.Errors["address"] = tr(c, err.Error())
+	}
+
+	if len(form.Errors) > 0 {
+		if action == mastore.AddressAdd {
+			return h.renderPortal(c, http.StatusBadRequest, user, form)
+		}
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid coin or address"))
+	}
+
+	addrs, primary, err := h.userPayoutAddresses(c, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if action == mastore.AddressAdd {
+		if hasAddress(addrs[cn], address) {
+			form.Errors["address"] = tr(c, "address is added already")
+			return h.renderPortal(c, http.StatusBadRequest, user, form)
+		}
+
+		if h.blockDuplicates {
+			owners, err := h.otherOwners(c, user.ID, address)
+			if err != nil {
+				return err
+			}
+			// Owners are not named, so miners can not find out who owns
+			// address.
+			if len(owners) > 0 {
+				form.Errors["address"] = tr(c,
+					"address can not be added, contact administrators")
+				return h.renderPortal(c, http.StatusBadRequest, user, form)
+			}
+		}
+	} else {
+		if !hasAddress(addrs[cn], address) {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				tr(c, "user has no such address"))
+		}
+
+		// Only admins choose new primary address, so payouts do not stop
+		// after primary one is removed.
+		if address == primary[cn] && len(addrs[cn]) > 1 {
+			return h.redirectWithFlash(c, "/portal", FlashError,
+				"Primary address can be removed by administrators only")
+		}
+	}
+
+	chs, err := h.mstore(c).UserAddressChanges(user.ID)
+	if err != nil {
+		return h.redirectWithError(c, "/portal",
+			"Failed to request address change", err)
+	}
+
+	coinStr := fmt.Sprintf("%s", cn)
+
+	if ChangePending(chs, action, coinStr, address) {
+		return h.redirectWithFlash(c, "/portal", FlashError,
+			"The same address change is pending already")
+	}
+
+	_, err = h.mstore(c).AddAddressChange(mastore.AddressChange{
+		UserID:      user.ID,
+		Action:      action,
+		Coin:        coinStr,
+		Address:     address,
+		RequestedBy: actor(c),
+	})
+	if err != nil {
+		return h.redirectWithError(c, "/portal",
+			"Failed to request address change", err)
+	}
+
+	h.emit(c, webhook.AddressesUpdated, addressEvent{UserID: user.ID,
+		Action: action, Coin: coinStr, Address: address,
+		Status: mastore.ChangePending})
+
+	if action == mastore.AddressAdd {
+		return h.redirectWithFlash(c, "/portal", FlashSuccess,
+			`Adding %s address "%s" is waiting for approval by administrators`,
+			cn, address)
+	}
+
+	return h.redirectWithFlash(c, "/portal", FlashSuccess,
+		`Removing %s address "%s" is waiting for approval by administrators`,
+		cn, address)
+}
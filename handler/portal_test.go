@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// portalConfig is config of handler with miner portal.
+var portalConfig = Config{PortalJWTSecret: "portal-secret"}
+
+// portalCookie returns portal session cookie response sets.
+func portalCookie(res *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range res.Result().Cookies() {
+		if c.Name == PortalCookie {
+			return c
+		}
+	}
+	return nil
+}
+
+func Test_PortalLogin(t *testing.T) {
+	th := newTestHandler(portalConfig)
+
+	var tokenHash string
+	th.s.On("GetUsers").Return([]bestore.User{{ID: 3,
+		Email: "a@example.com"}}, nil)
+	th.ms.On("RequestLoginLink", "a@example.com", mock.Anything,
+		mock.Anything).Return(true, nil)
+	th.ms.On("RemoveExpiredLoginTokens", mock.Anything).Return(nil)
+	th.ms.On("AddLoginToken", mock.Anything, uint(3), mock.Anything).
+		Return(nil).Run(func(args mock.Arguments) {
+		tokenHash = args.String(0)
+	})
+
+	res := th.serve("/portal/login", th.PortalLogin,
+		newFormRequest("/portal/login",
+			url.Values{"email": {"A@example.com"}}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/portal/login", res.Header().Get("Location"))
+	assert.Equal(t, []Flash{{Kind: FlashSuccess, Message: "If " +
+		"A@example.com is registered, sign in link is sent to it"}},
+		th.flashes(res))
+
+	// Only token hash is stored.
+	assert.Len(t, tokenHash, 64)
+
+	th.ms.AssertExpectations(t)
+}
+
+func Test_PortalLogin_cooldown(t *testing.T) {
+	th := newTestHandler(portalConfig)
+
+	th.s.On("GetUsers").Return([]bestore.User{{ID: 3,
+		Email: "a@example.com"}}, nil)
+	th.ms.On("RequestLoginLink", "a@example.com", mock.Anything,
+		mock.Anything).Return(false, nil)
+
+	res := th.serve("/portal/login", th.PortalLogin,
+		newFormRequest("/portal/login",
+			url.Values{"email": {"a@example.com"}}))
+
+	// Miner is not told link is not sent.
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, []Flash{{Kind: FlashSuccess, Message: "If " +
+		"a@example.com is registered, sign in link is sent to it"}},
+		th.flashes(res))
+
+	th.ms.AssertNotCalled(t, "AddLoginToken", mock.Anything, mock.Anything,
+		mock.Anything)
+}
+
+func Test_PortalLogin_unknownEmail(t *testing.T) {
+	th := newTestHandler(portalConfig)
+
+	th.s.On("GetUsers").Return([]bestore.User{{ID: 3,
+		Email: "a@example.com"}}, nil)
+
+	res := th.serve("/portal/login", th.PortalLogin,
+		newFormRequest("/portal/login",
+			url.Values{"email": {"b@example.com"}}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, []Flash{{Kind: FlashSuccess, Message: "If " +
+		"b@example.com is registered, sign in link is sent to it"}},
+		th.flashes(res))
+
+	th.ms.AssertNotCalled(t, "RequestLoginLink", mock.Anything,
+		mock.Anything, mock.Anything)
+	th.ms.AssertNotCalled(t, "AddLoginToken", mock.Anything, mock.Anything,
+		mock.Anything)
+}
+
+func Test_PortalMagicLogin(t *testing.T) {
+	th := newTestHandler(portalConfig)
+
+	th.ms.On("UseLoginToken", hashLoginToken("abc"), mock.Anything).
+		Return(uint(3), nil)
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3,
+		Email: "a@example.com"}, nil)
+
+	res := th.serve("/portal/login/:token", th.PortalMagicLogin,
+		newFormRequest("/portal/login/abc", url.Values{}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/portal", res.Header().Get("Location"))
+
+	cookie := portalCookie(res)
+	if !assert.NotNil(t, cookie) {
+		return
+	}
+	assert.Equal(t, "/portal", cookie.Path)
+
+	// Session is signed by portal secret, not admin one.
+	token, err := jwt.Parse(cookie.Value, func(*jwt.Token) (interface{},
+		error) {
+		return []byte("portal-secret"), nil
+	})
+	if assert.NoError(t, err) {
+		claims := token.Claims.(jwt.MapClaims)
+		assert.Equal(t, float64(3), claims["user_id"])
+		assert.Equal(t, "a@example.com", claims["email"])
+	}
+}
+
+func Test_PortalMagicLogin_expired(t *testing.T) {
+	th := newTestHandler(portalConfig)
+
+	th.ms.On("UseLoginToken", hashLoginToken("abc"), mock.Anything).
+		Return(uint(0), mastore.ErrNotFound)
+
+	res := th.serve("/portal/login/:token", th.PortalMagicLogin,
+		newFormRequest("/portal/login/abc", url.Values{}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/portal/login", res.Header().Get("Location"))
+	assert.Equal(t, []Flash{{Kind: FlashError, Message: "Sign in link " +
+		"is invalid or expired, request new one"}}, th.flashes(res))
+	assert.Nil(t, portalCookie(res))
+
+	th.s.AssertNotCalled(t, "GetUserByID", mock.Anything)
+}
+
+func Test_PortalEditAddresses_add(t *testing.T) {
+	th := newTestHandler(portalConfig)
+
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3,
+		Email: "a@example.com"}, nil)
+	th.userAddresses()
+	th.ms.On("UserAddressChanges", uint(3)).
+		Return([]mastore.AddressChange{}, nil)
+	th.ms.On("AddAddressChange", mastore.AddressChange{
+		UserID:      3,
+		Action:      "add",
+		Coin:        "ETH",
+		Address:     "addr",
+		RequestedBy: "user:a@example.com",
+	}).Return(uint(1), nil)
+
+	res := th.serveMiner("/portal/addresses", th.PortalEditAddresses,
+		newFormRequest("/portal/addresses", url.Values{
+			"action":  {"add"},
+			"coin":    {"ETH"},
+			"address": {"addr"},
+		}))
+
+	// Address is added after admin approves the change.
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/portal", res.Header().Get("Location"))
+	assert.Equal(t, []Flash{{Kind: FlashSuccess, Message: `Adding ETH ` +
+		`address "addr" is waiting for approval by administrators`}},
+		th.flashes(res))
+
+	th.ms.AssertExpectations(t)
+	th.s.AssertNotCalled(t, "AddUserAddress", mock.Anything, mock.Anything,
+		mock.Anything)
+}
+
+func Test_Portal_balancesCached(t *testing.T) {
+	th := newTestHandler(portalConfig)
+
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3,
+		Email: "a@example.com"}, nil)
+	th.userAddresses()
+	th.ms.On("UserAddressChanges", uint(3)).
+		Return([]mastore.AddressChange{}, nil)
+	th.s.On("ProjectsBalances").Return([]bestore.ProjectBalance{
+		{ProjectID: 1, ProjectName: "Alpha"},
+		{ProjectID: 2, ProjectName: "Beta"},
+	}, nil)
+	th.s.On("ProjectUsersBalances", uint(1)).Return([]bestore.UserBalance{
+		{Email: "A@example.com", Coins: []bestore.CoinAmount{
+			{Coin: bestore.ETH, Amount: "1.5"}}},
+	}, nil)
+	th.s.On("ProjectUsersBalances", uint(2)).Return([]bestore.UserBalance{},
+		nil)
+	th.ms.On("TrashItems", "projects").Return([]mastore.TrashItem{}, nil)
+
+	for i := 0; i < 2; i++ {
+		res := th.serveMiner("/portal", th.Portal,
+			httptest.NewRequest(http.MethodGet, "/portal", nil))
+
+		assert.Equal(t, http.StatusOK, res.Code)
+
+		// Balance is matched by email case insensitively.
+		data := th.rendered.data.(portalPageData)
+		if assert.Len(t, data.Balances, 1) {
+			assert.Equal(t, "Alpha", data.Balances[0].ProjectName)
+		}
+		if assert.Len(t, data.Total.Coins, 1) {
+			assert.Equal(t, bestore.ETH, data.Total.Coins[0].Coin)
+			assert.Equal(t, "1.5", data.Total.Coins[0].Amount.String())
+		}
+	}
+
+	// Balances of every project are read once for both pages.
+	th.s.AssertNumberOfCalls(t, "ProjectsBalances", 1)
+	th.s.AssertNumberOfCalls(t, "ProjectUsersBalances", 2)
+}
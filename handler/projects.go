@@ -9,148 +9,522 @@ import (
 	"strings"
 
 	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/balance"
+	"github.com/boomstarternetwork/mineradmin/coin"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/stats"
+	"github.com/boomstarternetwork/mineradmin/trash"
+	"github.com/boomstarternetwork/mineradmin/webhook"
 	"github.com/labstack/echo"
 )
 
+type projectBalance struct {
+	bestore.ProjectBalance
+	Meta     mastore.ProjectMeta
+	Totals   balance.Totals
+	Hashrate stats.Hashrate
+}
+
 type projectsPageData struct {
-	CSRFToken string
-	Balances  []bestore.ProjectBalance
+	Balances []projectBalance
+	Total    balance.Totals
+	// Status is projects filter: empty for all but archived projects, "all"
+	// or project status.
+	Status string
+	Form   formData
+}
+
+const allProjects = "all"
+
+type projectsFilter struct {
+	Status string
+	Label  string
+}
+
+// Filters returns projects page status filters.
+func (projectsPageData) Filters() []projectsFilter {
+	return []projectsFilter{
+		{Status: "", Label: "Current"},
+		{Status: string(mastore.ProjectActive), Label: "active"},
+		{Status: string(mastore.ProjectPaused), Label: "paused"},
+		{Status: string(mastore.ProjectArchived), Label: "archived"},
+		{Status: allProjects, Label: "All"},
+	}
 }
 
 func (h Handler) Projects(c echo.Context) error {
-	balances, err := h.store.ProjectsBalances()
+	return h.renderProjects(c, http.StatusOK, formData{})
+}
+
+// showProject checks that project with status passes projects page filter.
+func showProject(filter string, status mastore.ProjectStatus) bool {
+	switch filter {
+	case "":
+		return status != mastore.ProjectArchived
+	case allProjects:
+		return true
+	}
+	return status == mastore.ProjectStatus(filter)
+}
+
+func (h Handler) renderProjects(c echo.Context, code int,
+	form formData) error {
+	filter := c.QueryParam("status")
+	if filter != "" && filter != allProjects &&
+		!mastore.ProjectStatus(filter).Valid() {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid project status"))
+	}
+
+	balances, err := h.store(c).ProjectsBalances()
 	if err != nil {
 		return errors.New("failed to get project balances from DB: " +
 			err.Error())
 	}
 
-	return c.Render(http.StatusOK, "projects", projectsPageData{
-		CSRFToken: c.Get("csrf-token").(string),
-		Balances:  balances,
-	})
+	metas, err := h.mstore(c).ProjectsMeta()
+	if err != nil {
+		return errors.New("failed to get projects metadata from DB: " +
+			err.Error())
+	}
+
+	trashed, err := h.bin(c).Hidden(trash.Projects)
+	if err != nil {
+		return errors.New("failed to get trashed projects from DB: " +
+			err.Error())
+	}
+
+	hs, err := h.hashrates(c)
+	if err != nil {
+		return err
+	}
+
+	data := projectsPageData{Status: filter, Form: form}
+
+	var coins [][]bestore.CoinAmount
+
+	for _, b := range balances {
+		meta, ok := metas[b.ProjectID]
+		if !ok {
+			meta = mastore.ProjectMeta{
+				ProjectID: b.ProjectID,
+				Status:    mastore.ProjectActive,
+			}
+		}
+		if trashed[b.ProjectID] || !showProject(filter, meta.Status) {
+			continue
+		}
+
+		t, err := h.totals(c, b.Coins)
+		if err != nil {
+			return errors.New("failed to sum project balance: " + err.Error())
+		}
+		data.Balances = append(data.Balances, projectBalance{
+			ProjectBalance: b,
+			Meta:           meta,
+			Totals:         t,
+			Hashrate:       hs.Projects[b.ProjectID],
+		})
+		coins = append(coins, b.Coins)
+	}
+
+	data.Total, err = h.totals(c, coins...)
+	if err != nil {
+		return errors.New("failed to sum projects balances: " + err.Error())
+	}
+
+	return c.Render(code, "projects", data)
+}
+
+// projectID parses project ID route param. Trashed projects are not found
+// until they are restored.
+func (h Handler) projectID(c echo.Context) (uint, error) {
+	id64, err := strconv.ParseUint(c.Param("project-id"), 10, 64)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid project ID"))
+	}
+
+	id := uint(id64)
+
+	trashed, err := h.bin(c).Hidden(trash.Projects)
+	if err != nil {
+		return 0, errors.New("failed to get trashed projects from DB: " +
+			err.Error())
+	}
+	if trashed[id] {
+		return 0, echo.NewHTTPError(http.StatusNotFound,
+			tr(c, "project not found"))
+	}
+
+	return id, nil
 }
 
 type projectEditPageData struct {
-	CSRFToken string
-	Project   bestore.Project
+	Project  bestore.Project
+	Meta     mastore.ProjectMeta
+	Statuses []mastore.ProjectStatus
+	Coins    []bestore.Coin
+	Form     formData
+}
+
+// HasCoin checks that project targets coin.
+func (d projectEditPageData) HasCoin(c bestore.Coin) bool {
+	for _, s := range d.Meta.Coins {
+		if s == fmt.Sprint(c) {
+			return true
+		}
+	}
+	return false
 }
 
 func (h Handler) ProjectEdit(c echo.Context) error {
-	idStr := c.Param("project-id")
+	id, err := h.projectID(c)
+	if err != nil {
+		return err
+	}
 
-	id64, err := strconv.ParseUint(idStr, 10, 64)
+	meta, err := h.mstore(c).GetProjectMeta(id)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid project ID")
+		return errors.New("failed to get project metadata from DB: " +
+			err.Error())
 	}
 
-	id := uint(id64)
+	return h.renderProjectEdit(c, http.StatusOK, id, meta, formData{})
+}
 
-	project, err := h.store.GetProject(id)
+// renderProjectEdit renders project edit page with meta, which is submitted
+// one if form has errors.
+func (h Handler) renderProjectEdit(c echo.Context, code int, id uint,
+	meta mastore.ProjectMeta, form formData) error {
+	project, err := h.store(c).GetProject(id)
 	if err != nil {
 		if bestore.NotFound(err) {
-			return echo.NewHTTPError(http.StatusNotFound, "project not found")
+			return echo.NewHTTPError(http.StatusNotFound,
+				tr(c, "project not found"))
 		}
 		return errors.New("failed to get project from DB: " + err.Error())
 	}
 
-	return c.Render(http.StatusOK, "project/edit", projectEditPageData{
-		CSRFToken: c.Get("csrf-token").(string),
-		Project:   project,
+	return c.Render(code, "project/edit", projectEditPageData{
+		Project:  project,
+		Meta:     meta,
+		Statuses: mastore.ProjectStatuses,
+		Coins:    coin.List(),
+		Form:     form,
 	})
 }
 
+type userBalance struct {
+	bestore.UserBalance
+	Totals   balance.Totals
+	Workers  []mastore.Worker
+	Hashrate stats.Hashrate
+}
+
 type projectUsersPageData struct {
 	Project  bestore.Project
-	Balances []bestore.UserBalance
+	Balances []userBalance
+	Total    balance.Totals
 }
 
 func (h Handler) ProjectUsers(c echo.Context) error {
-	idStr := c.Param("project-id")
-
-	id64, err := strconv.ParseUint(idStr, 10, 64)
+	id, err := h.projectID(c)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid project ID")
+		return err
 	}
 
-	id := uint(id64)
-
-	project, err := h.store.GetProject(id)
+	project, err := h.store(c).GetProject(id)
 	if err != nil {
 		if bestore.NotFound(err) {
-			return echo.NewHTTPError(http.StatusNotFound, "project not found")
+			return echo.NewHTTPError(http.StatusNotFound,
+				tr(c, "project not found"))
 		}
 		return errors.New("failed to get project from DB: " + err.Error())
 	}
 
-	balances, err := h.store.ProjectUsersBalances(id)
+	balances, err := h.store(c).ProjectUsersBalances(id)
 	if err != nil {
 		return errors.New("failed to get project users balances from DB: " +
 			err.Error())
 	}
 
-	return c.Render(http.StatusOK, "project/users", projectUsersPageData{
-		Project:  project,
-		Balances: balances,
-	})
+	users, err := h.store(c).GetUsers()
+	if err != nil {
+		return errors.New("failed to get users from DB: " + err.Error())
+	}
+
+	userIDs := map[string]uint{}
+	for _, u := range users {
+		userIDs[strings.ToLower(u.Email)] = u.ID
+	}
+
+	workers, err := h.workersByEmail(c, users)
+	if err != nil {
+		return err
+	}
+
+	hs, err := h.hashrates(c)
+	if err != nil {
+		return err
+	}
+
+	data := projectUsersPageData{Project: project}
+
+	var coins [][]bestore.CoinAmount
+
+	for _, b := range balances {
+		t, err := h.totals(c, b.Coins)
+		if err != nil {
+			return errors.New("failed to sum user balance: " + err.Error())
+		}
+		data.Balances = append(data.Balances, userBalance{
+			UserBalance: b,
+			Totals:      t,
+			Workers:     workers[strings.ToLower(b.Email)],
+			Hashrate: hs.ProjectUsers[stats.ProjectUser{ProjectID: id,
+				UserID: userIDs[strings.ToLower(b.Email)]}],
+		})
+		coins = append(coins, b.Coins)
+	}
+
+	data.Total, err = h.totals(c, coins...)
+	if err != nil {
+		return errors.New("failed to sum project users balances: " +
+			err.Error())
+	}
+
+	return c.Render(http.StatusOK, "project/users", data)
 }
 
 func (h Handler) NewProject(c echo.Context) error {
-	name := c.FormValue("name")
-	name = strings.TrimSpace(name)
-	if name == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "blank name")
+	form := newFormData(c, "name")
+
+	name, err := ValidateProjectName(form.Value("name"))
+	if err != nil {
+		form.Errors["name"] = tr(c, err.Error())
+		return h.renderProjects(c, http.StatusBadRequest, form)
 	}
 
-	err := h.store.AddProject(name)
+	err = h.store(c).AddProject(name)
 	if err != nil {
-		return errors.New("failed to add project to DB: " + err.Error())
+		return h.redirectWithError(c, "/projects",
+			"Failed to add project", err)
 	}
 
-	return c.Redirect(http.StatusFound, "/projects")
+	// Project is created anyway, it gets default metadata until tracked.
+	err = TrackNewProject(h.store(c), h.mstore(c), name)
+	if err != nil {
+		c.Logger().Error("failed to track new project: " + err.Error())
+	}
+
+	h.emit(c, webhook.ProjectCreated, echo.Map{"name": name})
+
+	return h.redirectWithFlash(c, "/projects", FlashSuccess,
+		`Project "%s" created`, name)
 }
 
-var (
-	newNameRe = regexp.MustCompile(`\S`)
+// TrackNewProject creates metadata of just added project with name, so its
+// creation date is recorded. bestore does not return IDs of added projects,
+// so the latest untracked project with name is tracked.
+func TrackNewProject(s bestore.Store, ms mastore.Store, name string) error {
+	balances, err := s.ProjectsBalances()
+	if err != nil {
+		return errors.New("failed to get project balances from DB: " +
+			err.Error())
+	}
+
+	metas, err := ms.ProjectsMeta()
+	if err != nil {
+		return errors.New("failed to get projects metadata from DB: " +
+			err.Error())
+	}
+
+	var id uint
+	for _, b := range balances {
+		if _, ok := metas[b.ProjectID]; !ok && b.ProjectName == name &&
+			b.ProjectID > id {
+			id = b.ProjectID
+		}
+	}
+
+	if id == 0 {
+		return errors.New("project " + name + " not found")
+	}
+
+	return ms.TrackProjects([]uint{id})
+}
+
+var projectNameRe = regexp.MustCompile(`\S`)
+
+const (
+	maxProjectDescriptionLen = 2000
+	maxProjectOwnerLen       = 200
 )
 
-func (h Handler) EditProject(c echo.Context) error {
-	idStr := c.Param("project-id")
+// ValidateProjectName trims project name and checks it is not blank.
+func ValidateProjectName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if !projectNameRe.MatchString(name) {
+		return "", errors.New("invalid project name")
+	}
+	return name, nil
+}
 
-	id64, err := strconv.ParseUint(idStr, 10, 64)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid project ID")
+// ValidateProjectMeta trims project description and owner and checks
+// their length, status and coins.
+func ValidateProjectMeta(meta mastore.ProjectMeta) (mastore.ProjectMeta,
+	map[string]error) {
+	errs := map[string]error{}
+
+	meta.Description = strings.TrimSpace(meta.Description)
+	if len([]rune(meta.Description)) > maxProjectDescriptionLen {
+		errs["description"] = errors.New("description is too long")
 	}
 
-	id := uint(id64)
+	meta.Owner = strings.TrimSpace(meta.Owner)
+	if len([]rune(meta.Owner)) > maxProjectOwnerLen {
+		errs["owner"] = errors.New("owner contact is too long")
+	}
+
+	if !meta.Status.Valid() {
+		errs["status"] = errors.New("invalid project status")
+	}
+
+	for _, s := range meta.Coins {
+		_, err := bestore.ParseCoin(s)
+		if err != nil {
+			errs["coins"] = errors.New("invalid coin")
+		}
+	}
+
+	return meta, errs
+}
+
+func (h Handler) EditProject(c echo.Context) error {
+	id, err := h.projectID(c)
+	if err != nil {
+		return err
+	}
 
 	action := c.FormValue("action")
 
+	editPath := fmt.Sprintf("/projects/%d/edit", id)
+
 	switch action {
 	case "edit":
-		newName := c.FormValue("name")
-		newName = strings.TrimSpace(newName)
+		form := newFormData(c, "name")
 
-		if !newNameRe.MatchString(newName) {
-			return echo.NewHTTPError(http.StatusBadRequest,
-				"invalid project name")
+		params, err := c.FormParams()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 		}
 
-		err := h.store.SetProjectName(id, newName)
+		meta, errs := ValidateProjectMeta(mastore.ProjectMeta{
+			ProjectID:   id,
+			Description: c.FormValue("description"),
+			Owner:       c.FormValue("owner"),
+			Status:      mastore.ProjectStatus(c.FormValue("status")),
+			Coins:       params["coins"],
+		})
+		for field, err := range errs {
+			form.Errors[field] = tr(c, err.Error())
+		}
+
+		newName, err := ValidateProjectName(form.Value("name"))
 		if err != nil {
-			return errors.New("failed to set in DB: " + err.Error())
+			form.Errors["name"] = tr(c, err.Error())
+		}
+
+		if len(form.Errors) > 0 {
+			return h.renderProjectEdit(c, http.StatusBadRequest, id, meta,
+				form)
 		}
 
-		return c.Redirect(http.StatusFound,
-			fmt.Sprintf("/projects/%d/edit", id))
+		err = h.store(c).SetProjectName(id, newName)
+		if err != nil {
+			return h.redirectWithError(c, editPath,
+				"Failed to rename project", err)
+		}
+
+		err = h.mstore(c).SetProjectMeta(meta)
+		if err != nil {
+			return h.redirectWithError(c, editPath,
+				"Failed to save project", err)
+		}
+
+		h.emit(c, webhook.ProjectUpdated, echo.Map{
+			"id":          id,
+			"action":      action,
+			"name":        newName,
+			"description": meta.Description,
+			"owner":       meta.Owner,
+			"status":      meta.Status,
+			"coins":       meta.Coins,
+		})
+
+		return h.redirectWithFlash(c, editPath, FlashSuccess,
+			`Project "%s" saved`, newName)
+
+	case "archive":
+		err := h.mstore(c).SetProjectStatus(id, mastore.ProjectArchived)
+		if err != nil {
+			return h.redirectWithError(c, "/projects",
+				"Failed to archive project", err)
+		}
+
+		h.emit(c, webhook.ProjectUpdated, echo.Map{"id": id,
+			"action": action, "status": mastore.ProjectArchived})
+
+		return h.redirectWithFlash(c, "/projects", FlashSuccess,
+			"Project archived")
+
+	case "unarchive":
+		err := h.mstore(c).SetProjectStatus(id, mastore.ProjectActive)
+		if err != nil {
+			return h.redirectWithError(c, "/projects?status=archived",
+				"Failed to unarchive project", err)
+		}
+
+		h.emit(c, webhook.ProjectUpdated, echo.Map{"id": id,
+			"action": action, "status": mastore.ProjectActive})
+
+		return h.redirectWithFlash(c, "/projects", FlashSuccess,
+			"Project unarchived")
 
 	case "remove":
-		err := h.store.RemoveProject(id)
+		meta, err := h.mstore(c).GetProjectMeta(id)
+		if err != nil {
+			return h.redirectWithError(c, "/projects",
+				"Failed to remove project", err)
+		}
+
+		// Projects are archived first, so active project is never removed
+		// by mistake.
+		if meta.Status != mastore.ProjectArchived {
+			return h.redirectWithFlash(c, "/projects?status=archived",
+				FlashError, "Only archived project can be removed")
+		}
+
+		project, err := h.store(c).GetProject(id)
 		if err != nil {
-			return errors.New("failed to remove from DB: " + err.Error())
+			return h.redirectWithError(c, "/projects?status=archived",
+				"Failed to remove project", err)
 		}
 
-		return c.Redirect(http.StatusFound, "/projects")
+		itemID, err := h.bin(c).RemoveProject(id, project.Name, adminLogin(c))
+		if err != nil {
+			return h.redirectWithError(c, "/projects?status=archived",
+				"Failed to remove project", err)
+		}
+
+		h.emit(c, webhook.ProjectUpdated, echo.Map{"id": id,
+			"action": action, "name": project.Name})
+
+		return h.redirectWithUndo(c, "/projects?status=archived",
+			trash.Projects, itemID, `Project "%s" moved to trash`,
+			project.Name)
 	}
 
-	return echo.NewHTTPError(http.StatusBadRequest, "unknown action")
+	return echo.NewHTTPError(http.StatusBadRequest, tr(c, "unknown action"))
 }
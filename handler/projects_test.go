@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_projectRoutes_trashed(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("TrashItems", "projects").Return([]mastore.TrashItem{
+		{ID: 1, Kind: "projects", EntityID: 123, Name: "name"},
+	}, nil)
+
+	for _, rt := range []struct {
+		route string
+		f     echo.HandlerFunc
+		req   *http.Request
+	}{
+		{"/projects/:project-id/edit", th.ProjectEdit,
+			httptest.NewRequest(http.MethodGet, "/projects/123/edit", nil)},
+		{"/projects/:project-id/users", th.ProjectUsers,
+			httptest.NewRequest(http.MethodGet, "/projects/123/users", nil)},
+		{"/projects/:project-id", th.EditProject,
+			newFormRequest("/projects/123", url.Values{
+				"action": {"edit"},
+				"name":   {"new-name"},
+			})},
+	} {
+		res := th.serve(rt.route, rt.f, rt.req)
+		assert.Equal(t, http.StatusNotFound, res.Code, rt.req.URL.Path)
+	}
+
+	th.s.AssertNotCalled(t, "GetProject", mock.Anything)
+	th.s.AssertNotCalled(t, "SetProjectName", mock.Anything, mock.Anything)
+}
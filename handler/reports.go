@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/report"
+	"github.com/labstack/echo"
+)
+
+// reportsShown is how many recent reports reports page shows.
+const reportsShown = 100
+
+type reportsPageData struct {
+	Reports []mastore.Report
+}
+
+func (h Handler) Reports(c echo.Context) error {
+	reports, err := h.mstore(c).Reports(reportsShown)
+	if err != nil {
+		return errors.New("failed to get reports from DB: " + err.Error())
+	}
+
+	return c.Render(http.StatusOK, "reports", reportsPageData{
+		Reports: reports,
+	})
+}
+
+// DownloadReport responds with report file.
+func (h Handler) DownloadReport(c echo.Context) error {
+	id64, err := strconv.ParseUint(c.Param("report-id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid report ID"))
+	}
+
+	r, err := h.mstore(c).GetReport(uint(id64))
+	if err != nil {
+		if err == mastore.ErrNotFound {
+			return echo.NewHTTPError(http.StatusNotFound,
+				tr(c, "report not found"))
+		}
+		return errors.New("failed to get report from DB: " + err.Error())
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		`attachment; filename="`+report.FileName(r)+`"`)
+
+	return c.Blob(http.StatusOK, report.ContentType(r.Format), r.Content)
+}
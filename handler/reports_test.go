@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Reports(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	reports := []mastore.Report{
+		{ID: 3, Name: "weekly", Format: "csv", Size: 42,
+			CreatedAt: time.Date(2018, 6, 4, 6, 0, 0, 0, time.UTC)},
+	}
+	th.ms.On("Reports", 100).Return(reports, nil)
+
+	res := th.serve("/reports", th.Reports,
+		httptest.NewRequest(http.MethodGet, "/reports", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "reports", th.rendered.name)
+	assert.Equal(t, reports, th.rendered.data.(reportsPageData).Reports)
+}
+
+func Test_DownloadReport(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("GetReport", uint(3)).Return(mastore.Report{ID: 3,
+		Name: "weekly", Format: "csv", Content: []byte("project_id\n"),
+		CreatedAt: time.Date(2018, 6, 4, 6, 0, 0, 0, time.UTC)}, nil)
+
+	res := th.serve("/reports/:report-id", th.DownloadReport,
+		httptest.NewRequest(http.MethodGet, "/reports/3", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "text/csv", res.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, `attachment; filename="weekly-2018-06-04.csv"`,
+		res.Header().Get(echo.HeaderContentDisposition))
+	assert.Equal(t, "project_id\n", res.Body.String())
+}
+
+func Test_DownloadReport_notFound(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("GetReport", uint(4)).Return(mastore.Report{},
+		mastore.ErrNotFound)
+
+	res := th.serve("/reports/:report-id", th.DownloadReport,
+		httptest.NewRequest(http.MethodGet, "/reports/4", nil))
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Empty(t, res.Header().Get(echo.HeaderContentDisposition))
+}
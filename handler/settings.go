@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/boomstarternetwork/mineradmin/i18n"
+	"github.com/labstack/echo"
+)
+
+type settingsPageData struct {
+	Locales []string
+	Locale  string
+}
+
+func (h Handler) Settings(c echo.Context) error {
+	return c.Render(http.StatusOK, "settings", settingsPageData{
+		Locales: i18n.Locales(),
+		Locale:  localeOf(c),
+	})
+}
+
+func (h Handler) EditSettings(c echo.Context) error {
+	locale := c.FormValue("locale")
+	if !i18n.Supported(locale) {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid locale"))
+	}
+
+	err := h.accountMStore(c).SetAdminLocale(adminLogin(c), locale)
+	if err != nil {
+		return h.redirectWithError(c, "/settings",
+			"Failed to save settings", err)
+	}
+
+	setLocaleCookie(c, locale)
+
+	return h.redirectWithFlash(c, "/settings", FlashSuccess, "Settings saved")
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/boomstarternetwork/mineradmin/stats"
+	"github.com/labstack/echo"
+)
+
+// maxStatsBody is the largest stats reports request body accepted.
+const maxStatsBody = 32 << 20
+
+// APIIngestStats ingests worker reports in stats package format from
+// request body. Pool authorizes with bearer stats token.
+func (h Handler) APIIngestStats(c echo.Context) error {
+	auth := c.Request().Header.Get(echo.HeaderAuthorization)
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if h.statsToken == "" || token == auth || subtle.ConstantTimeCompare(
+		[]byte(token), []byte(h.statsToken)) != 1 {
+		return echo.NewHTTPError(http.StatusUnauthorized,
+			"invalid stats token")
+	}
+
+	body := http.MaxBytesReader(c.Response(), c.Request().Body, maxStatsBody)
+
+	reports, buckets, err := stats.Ingest(h.mstore(c), body)
+	if err != nil {
+		if stats.InvalidReports(err) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"reports": reports,
+		"buckets": buckets})
+}
+
+// hashrates returns current and average hashrates of users and projects.
+func (h Handler) hashrates(c echo.Context) (stats.Summary, error) {
+	hs, err := h.mstore(c).WorkerHashrates(time.Now(), stats.AverageWindow)
+	if err != nil {
+		return stats.Summary{}, errors.New("failed to get worker hashrates " +
+			"from DB: " + err.Error())
+	}
+	return stats.Summarize(hs), nil
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newStatsRequest returns stats report request authorized by auth.
+func newStatsRequest(auth string, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/stats",
+		strings.NewReader(body))
+	req.Header.Set("Authorization", auth)
+	return req
+}
+
+func Test_APIIngestStats(t *testing.T) {
+	th := newTestHandler(Config{StatsToken: "stats-token"})
+
+	th.ms.On("AddWorkerStats", []mastore.WorkerStat{{ProjectID: 1,
+		UserID: 3, Worker: "rig1", BucketStart: time.Date(2018, 10, 1, 12,
+			0, 0, 0, time.UTC), Samples: 1, HashrateSum: 100,
+		Accepted: 10}}).Return(nil)
+
+	res := th.serve("/api/stats", th.APIIngestStats,
+		newStatsRequest("Bearer stats-token",
+			`{"time":"2018-10-01T12:01:00Z","project_id":1,"user_id":3,`+
+				`"worker":"rig1","hashrate":100,"accepted":10}`))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"reports":1,"buckets":1}`, res.Body.String())
+
+	th.ms.AssertExpectations(t)
+}
+
+func Test_APIIngestStats_invalidToken(t *testing.T) {
+	for _, auth := range []string{"", "stats-token", "Bearer wrong"} {
+		th := newTestHandler(Config{StatsToken: "stats-token"})
+
+		res := th.serve("/api/stats", th.APIIngestStats,
+			newStatsRequest(auth, ""))
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code, auth)
+
+		th.ms.AssertNotCalled(t, "AddWorkerStats", mock.Anything)
+	}
+}
+
+func Test_APIIngestStats_noToken(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	// Stats are not accepted until token is configured.
+	res := th.serve("/api/stats", th.APIIngestStats,
+		newStatsRequest("Bearer ", ""))
+
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+}
+
+func Test_APIIngestStats_invalidReport(t *testing.T) {
+	th := newTestHandler(Config{StatsToken: "stats-token"})
+
+	res := th.serve("/api/stats", th.APIIngestStats,
+		newStatsRequest("Bearer stats-token",
+			`{"time":"2018-10-01T12:01:00Z"}`))
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Contains(t, res.Body.String(), "line 1: missing project_id")
+
+	th.ms.AssertNotCalled(t, "AddWorkerStats", mock.Anything)
+}
@@ -1,64 +1,110 @@
 package handler
 
 import (
+	"bytes"
 	"errors"
 	"html/template"
 	"io"
+	"io/fs"
 	"os"
-	"path/filepath"
+	"path"
 	"regexp"
 	"strings"
 
 	"github.com/labstack/echo"
 )
 
-type ProdTemplateRenderer struct {
-	templates map[string]*template.Template
-}
+const (
+	layoutsDir    = "layout"
+	partialsDir   = "partials"
+	defaultLayout = "base"
+)
 
-func NewProdTemplateRenderer(templatesPath string) (ProdTemplateRenderer,
-	error) {
-	ts := map[string]*template.Template{}
+var tmplFileExtRe = regexp.MustCompile(`\.tmpl$`)
+
+// parseTemplate parses page template with all partials and layout. Page
+// selects layout by defining "layout" template with layout name, e.g.
+// {{define "layout"}}bare{{end}}, base layout is used otherwise.
+func parseTemplate(fsys fs.FS, name string) (*template.Template, error) {
+	pagePath := name + ".tmpl"
+
+	pageSrc, err := fs.ReadFile(fsys, pagePath)
+	if err != nil {
+		return nil, err
+	}
 
-	if strings.Index(templatesPath, "./") == 0 {
-		templatesPath = templatesPath[2:]
+	page, err := template.New(pagePath).Funcs(funcMap).Parse(string(pageSrc))
+	if err != nil {
+		return nil, err
 	}
 
-	if templatesPath[len(templatesPath)-1] != '/' {
-		templatesPath += "/"
+	layout := defaultLayout
+
+	if page.Lookup("layout") != nil {
+		buf := &bytes.Buffer{}
+		err = page.ExecuteTemplate(buf, "layout", nil)
+		if err != nil {
+			return nil, err
+		}
+		layout = strings.TrimSpace(buf.String())
 	}
 
-	baseTmpl, err := template.ParseFiles(filepath.Join(
-		templatesPath, "layout/base.tmpl"))
+	layoutPath := path.Join(layoutsDir, layout+".tmpl")
+
+	tmpl, err := template.New(path.Base(layoutPath)).Funcs(funcMap).
+		ParseFS(fsys, layoutPath, path.Join(partialsDir, "*.tmpl"))
 	if err != nil {
-		return ProdTemplateRenderer{}, err
+		return nil, err
 	}
 
-	tmplFileExtRe := regexp.MustCompile(`\.tmpl$`)
+	_, err = tmpl.New(pagePath).Parse(string(pageSrc))
+	if err != nil {
+		return nil, err
+	}
 
-	err = filepath.Walk(templatesPath, func(path string, info os.FileInfo,
+	return tmpl, nil
+}
+
+// execute renders template clone with request funcs bound to c.
+func execute(tmpl *template.Template, w io.Writer, data interface{},
+	c echo.Context) error {
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return err
+	}
+	return tmpl.Funcs(requestFuncMap(c)).Execute(w, data)
+}
+
+type ProdTemplateRenderer struct {
+	templates map[string]*template.Template
+}
+
+// NewProdTemplateRenderer parses all page templates from fsys once. Each
+// template is named by its path without extension, layouts and partials are
+// not pages.
+func NewProdTemplateRenderer(fsys fs.FS) (ProdTemplateRenderer, error) {
+	ts := map[string]*template.Template{}
+
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry,
 		err error) error {
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
+		if d.IsDir() {
+			if path == layoutsDir || path == partialsDir {
+				return fs.SkipDir
+			}
 			return nil
 		}
-		if !tmplFileExtRe.MatchString(info.Name()) {
+		if !tmplFileExtRe.MatchString(d.Name()) {
 			return nil
 		}
 
-		name := strings.TrimPrefix(path, templatesPath)
-		name = strings.TrimSuffix(name, ".tmpl")
-
-		tmpl, err := baseTmpl.Clone()
-		if err != nil {
-			return err
-		}
+		name := strings.TrimSuffix(path, ".tmpl")
 
-		tmpl, err = tmpl.ParseFiles(path)
+		tmpl, err := parseTemplate(fsys, name)
 		if err != nil {
-			return err
+			return errors.New(name + ": " + err.Error())
 		}
 
 		ts[name] = tmpl
@@ -75,14 +121,16 @@ func NewProdTemplateRenderer(templatesPath string) (ProdTemplateRenderer,
 }
 
 func (t ProdTemplateRenderer) Render(w io.Writer, name string,
-	data interface{}, _ echo.Context) error {
+	data interface{}, c echo.Context) error {
 	tmpl, exists := t.templates[name]
 	if !exists {
 		return errors.New("template not found")
 	}
-	return tmpl.Execute(w, data)
+	return execute(tmpl, w, data, c)
 }
 
+// DevTemplateRenderer parses templates from disk on each render, so changes
+// are visible without restart.
 type DevTemplateRenderer struct {
 	templatesPath string
 }
@@ -94,12 +142,10 @@ func NewDevTemplateRenderer(templatesPath string) DevTemplateRenderer {
 }
 
 func (r DevTemplateRenderer) Render(w io.Writer, name string, data interface{},
-	_ echo.Context) error {
-	tmpl, err := template.ParseFiles(
-		filepath.Join(r.templatesPath, "layout/base.tmpl"),
-		filepath.Join(r.templatesPath, name+".tmpl"))
+	c echo.Context) error {
+	tmpl, err := parseTemplate(os.DirFS(r.templatesPath), name)
 	if err != nil {
 		return err
 	}
-	return tmpl.Execute(w, data)
+	return execute(tmpl, w, data, c)
 }
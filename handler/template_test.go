@@ -0,0 +1,307 @@
+package handler
+
+import (
+	"bytes"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/addrindex"
+	"github.com/boomstarternetwork/mineradmin/balance"
+	"github.com/boomstarternetwork/mineradmin/i18n"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/stats"
+	"github.com/boomstarternetwork/mineradmin/trash"
+	"github.com/boomstarternetwork/mineradmin/webhook"
+	"github.com/labstack/echo"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ProdTemplateRenderer(t *testing.T) {
+	r, err := NewProdTemplateRenderer(os.DirFS("../templates"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil),
+		httptest.NewRecorder())
+	c.Set("csrf-token", "token")
+	c.Set(flashesKey, []Flash{
+		{Kind: FlashSuccess, Message: "Done"},
+		{Kind: FlashSuccess, Message: "Removed", Undo: "/trash/admins/7"},
+	})
+
+	totals, _ := balance.Sum([]bestore.CoinAmount{
+		{Coin: bestore.BTC, Amount: "1000.50"},
+		{Coin: bestore.ETH, Amount: "2"},
+	})
+	totals = totals.Valuate(balance.Prices{
+		Currency: "USD",
+		Coins: map[bestore.Coin]decimal.Decimal{
+			bestore.BTC: decimal.New(2, 0),
+		},
+	})
+
+	pages := map[string]interface{}{
+		"index": dashboardPageData{
+			Total:    totals,
+			Projects: 2,
+			Users:    3,
+			Admins:   1,
+			Unpaid: []unpaidUser{{User: bestore.User{ID: 1,
+				Email: "a@example.com"}, Coins: []string{"BTC"}}},
+			Top: []coinTop{{Coin: bestore.BTC, Projects: []projectAmount{
+				{ProjectID: 1, ProjectName: "name",
+					Amount: decimal.New(15, -1)}}}},
+			Events: []dashboardEvent{
+				{At: time.Now(), By: "login",
+					Change: &mastore.AddressChange{UserID: 1,
+						Action: mastore.AddressAdd, Coin: "BTC",
+						Address: "addr", Status: "pending"}},
+				{At: time.Now(), By: "login",
+					Removed: &mastore.TrashItem{Kind: "admins",
+						Name: "admin"}},
+			},
+			OpenAlerts: 2,
+			NewAlerts:  1,
+			Pool:       "eu",
+			Pools: []poolSummary{
+				{Name: "eu", Total: totals, Projects: 2, Users: 3,
+					OpenAlerts: 2},
+				{Name: "us"},
+			},
+			PoolsTotal: totals,
+			UpdatedAt:  time.Now(),
+		},
+		"login":          loginPageData{Path: "/"},
+		"admins":         adminsPageData{Admins: []bestore.Admin{{ID: 1}}},
+		"admin/password": "password",
+		"projects": projectsPageData{
+			Balances: []projectBalance{{
+				ProjectBalance: bestore.ProjectBalance{ProjectID: 1,
+					ProjectName: "name"},
+				Meta: mastore.ProjectMeta{ProjectID: 1,
+					Status: mastore.ProjectArchived},
+				Totals: totals,
+			}},
+			Total: totals,
+		},
+		"project/edit": projectEditPageData{
+			Project: bestore.Project{ID: 1, Name: "name"},
+			Meta: mastore.ProjectMeta{ProjectID: 1,
+				Status: mastore.ProjectPaused, Coins: []string{"ETH"},
+				CreatedAt: time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)},
+			Statuses: mastore.ProjectStatuses,
+			Coins:    []bestore.Coin{bestore.BTC, bestore.ETH},
+		},
+		"project/users": projectUsersPageData{
+			Project: bestore.Project{ID: 1, Name: "name"},
+			Balances: []userBalance{{
+				UserBalance: bestore.UserBalance{Email: "email"},
+				Totals:      totals,
+				Workers: []mastore.Worker{{ID: 1, Name: "rig1",
+					ExpectedHashrate: 120e12}},
+				Hashrate: stats.Hashrate{Current: 1.5e9, Average: 1e9},
+			}},
+			Total: totals,
+		},
+		"users": usersPageData{
+			Users: []userRow{{
+				User: bestore.User{ID: 1},
+				Matches: []mastore.AddressMeta{{UserID: 1, Coin: "BTC",
+					Address: "addr", Label: "rig"}},
+			}},
+			Query:     "rig",
+			Hashrates: map[uint]stats.Hashrate{1: {Current: 1e6}},
+		},
+		"trash": trashPageData{
+			Kind:  "admins",
+			Kinds: trash.Kinds,
+			Items: []mastore.TrashItem{{ID: 7, Kind: "admins", Name: "admin"}},
+		},
+		"settings": settingsPageData{Locales: i18n.Locales(), Locale: "en"},
+		"user/addresses": userAddressesData{
+			Coins:     []bestore.Coin{bestore.BTC},
+			User:      bestore.User{ID: 1},
+			Addresses: map[bestore.Coin][]string{bestore.BTC: {"addr"}},
+			Meta: map[string]mastore.AddressMeta{
+				"BTC/addr": {UserID: 1, Coin: "BTC", Address: "addr",
+					Label: "rig", Note: "note", CreatedBy: "admin",
+					CreatedAt: time.Now()},
+			},
+			Changes: []mastore.AddressChange{
+				{ID: 2, UserID: 1, Action: mastore.AddressRemove,
+					Coin: "BTC", Address: "addr", Status: "pending",
+					RequestedBy: "other"},
+				{ID: 1, UserID: 1, Action: mastore.AddressAdd, Coin: "BTC",
+					Address: "addr", Status: "approved",
+					EligibleAt: time.Now().Add(time.Hour)},
+			},
+			Login: "login",
+		},
+		"addresses": addressLookupPageData{
+			Query: "ad",
+			Addresses: []addrindex.Address{{User: bestore.User{ID: 1},
+				Coin: bestore.BTC, Address: "addr"}},
+		},
+		"address-duplicates": addressDuplicatesPageData{
+			Duplicates: [][]addrindex.Address{{
+				{User: bestore.User{ID: 1}, Coin: bestore.BTC, Address: "addr"},
+				{User: bestore.User{ID: 2}, Coin: bestore.BTC, Address: "ADDR"},
+			}},
+		},
+		"webhooks": webhooksPageData{
+			Webhooks: []mastore.Webhook{{ID: 1, URL: "https://example.com",
+				Events: []string{webhook.UserCreated}, Secret: "0123456789"}},
+			Events:  webhook.Events,
+			Checked: []string{webhook.UserCreated},
+			Added: &mastore.Webhook{ID: 1, URL: "https://example.com",
+				Secret: "secret"},
+		},
+		"webhook-deliveries": webhookDeliveriesPageData{
+			Deliveries: []mastore.WebhookDelivery{
+				{ID: 2, URL: "https://example.com", Status: "failed",
+					Attempts: 10, ResponseCode: 500, LastError: "oops"},
+				{ID: 1, URL: "https://example.com", Status: "delivered",
+					DeliveredAt: time.Now()},
+			},
+		},
+		"reports": reportsPageData{Reports: []mastore.Report{
+			{ID: 1, Name: "weekly", Format: "csv", Size: 10,
+				CreatedAt: time.Now()},
+		}},
+		"alerts": alertsPageData{
+			Open: []mastore.Alert{
+				{ID: 2, Kind: mastore.AlertStale, ProjectID: 1,
+					ProjectName: "Alpha", Email: "a@example.com",
+					Coin: "ETH", Details: "no growth", OpenedAt: time.Now()},
+				{ID: 1, Kind: mastore.AlertAnomaly, ProjectID: 1,
+					Email: "b@example.com", Coin: "BTC",
+					AcknowledgedBy: "login", AcknowledgedAt: time.Now()},
+			},
+			Resolved: []mastore.Alert{{ID: 3, Kind: mastore.AlertStale,
+				ProjectID: 1, Email: "c@example.com", Coin: "ETH",
+				ResolvedAt: time.Now()}},
+		},
+		"user/workers": userWorkersPageData{
+			User: bestore.User{ID: 1, Email: "a@example.com"},
+			Workers: []mastore.Worker{{ID: 2, UserID: 1, Name: "rig1",
+				ExpectedHashrate: 120e12, Location: "garage"}},
+		},
+		"portal/login": portalLoginPageData{},
+		"portal/index": portalPageData{
+			User:      bestore.User{ID: 1, Email: "a@example.com"},
+			Coins:     []bestore.Coin{bestore.BTC, bestore.ETH},
+			Addresses: map[bestore.Coin][]string{bestore.BTC: {"addr"}},
+			Primary:   map[bestore.Coin]string{bestore.BTC: "addr"},
+			Pending: []mastore.AddressChange{{ID: 2, UserID: 1,
+				Action: mastore.AddressAdd, Coin: "ETH", Address: "addr2",
+				Status: "pending"}},
+			Balances: []portalBalance{{ProjectName: "project"}},
+		},
+		"address-changes": addressChangesPageData{
+			Changes: []addressChange{{
+				AddressChange: mastore.AddressChange{ID: 2, UserID: 1,
+					Action: mastore.AddressAdd, Coin: "BTC",
+					Address: "addr", Status: "pending"},
+				Mine: true,
+			}},
+		},
+	}
+
+	withoutForms := map[string]bool{
+		"index":              true,
+		"admin/password":     true,
+		"project/users":      true,
+		"addresses":          true,
+		"address-duplicates": true,
+	}
+
+	for name, data := range pages {
+		buf := &bytes.Buffer{}
+		err := r.Render(buf, name, data, c)
+		assert.NoError(t, err, name)
+		if !withoutForms[name] {
+			assert.Contains(t, buf.String(), `value="token"`, name)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	r.Render(buf, "projects", pages["projects"], c)
+	assert.Contains(t, buf.String(), "BTC:1,000.5")
+	assert.Contains(t, buf.String(), "≈ 2,001.00 USD*")
+	assert.Contains(t, buf.String(), `title="No price for ETH"`)
+	assert.Contains(t, buf.String(), `href="/projects/1/edit"`)
+	assert.Contains(t, buf.String(), `<div class="flash success">Done</div>`)
+	assert.Contains(t, buf.String(), `value="remove"`)
+	assert.Contains(t, buf.String(), `action="/trash/admins/7"`)
+
+	buf = &bytes.Buffer{}
+	r.Render(buf, "user/addresses", pages["user/addresses"], c)
+	assert.Contains(t, buf.String(), `value="approve"`)
+	assert.Contains(t, buf.String(), "eligible for payouts from")
+
+	buf = &bytes.Buffer{}
+	r.Render(buf, "project/users", pages["project/users"], c)
+	assert.Contains(t, buf.String(), "120 TH/s")
+	assert.Contains(t, buf.String(), "1.5 GH/s")
+	assert.Contains(t, buf.String(), "24h average 1 GH/s")
+
+	buf = &bytes.Buffer{}
+	r.Render(buf, "portal/index", pages["portal/index"], c)
+	assert.Contains(t, buf.String(), `href="/portal/logout"`)
+	assert.NotContains(t, buf.String(), `value="approve"`)
+
+	buf = &bytes.Buffer{}
+	r.Render(buf, "portal/login", portalLoginPageData{Token: "abc"}, c)
+	assert.Contains(t, buf.String(), `action="/portal/login/abc"`)
+
+	buf = &bytes.Buffer{}
+	r.Render(buf, "webhooks", webhooksPageData{
+		Webhooks: []mastore.Webhook{{ID: 1, URL: "https://example.com",
+			Secret: "0123456789"}},
+	}, c)
+	assert.Contains(t, buf.String(), "012345…")
+	assert.NotContains(t, buf.String(), "0123456789")
+
+	buf = &bytes.Buffer{}
+	r.Render(buf, "project/edit", pages["project/edit"], c)
+	assert.Contains(t, buf.String(), `<option value="paused" selected>`)
+	assert.Regexp(t, `value="ETH"\s+checked`, buf.String())
+	assert.Contains(t, buf.String(), "2018-10-01 12:00")
+}
+
+func Test_templatesTranslations(t *testing.T) {
+	tRe := regexp.MustCompile(`\{\{t ("(?:[^"\\]|\\.)*")`)
+
+	err := fs.WalkDir(os.DirFS("../templates"), ".",
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			src, err := ioutil.ReadFile(filepath.Join("../templates", path))
+			if err != nil {
+				return err
+			}
+			for _, m := range tRe.FindAllStringSubmatch(string(src), -1) {
+				message, err := strconv.Unquote(m[1])
+				if !assert.NoError(t, err, path) {
+					continue
+				}
+				assert.True(t, i18n.Has(i18n.Ru, message),
+					"%s: no ru translation for %q", path, message)
+			}
+			return nil
+		})
+	assert.NoError(t, err)
+}
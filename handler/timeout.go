@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// Timeouts is how long requests may take before they are cancelled.
+type Timeouts struct {
+	// Default is timeout of routes which have no own one, requests are not
+	// timed out if it is zero.
+	Default time.Duration
+	// Routes are timeouts by route path, e.g. "/users/:user-id/addresses".
+	Routes map[string]time.Duration
+}
+
+// ParseRouteTimeouts parses route timeouts in "path=duration" form, e.g.
+// "/payouts.csv=2m".
+func ParseRouteTimeouts(specs []string) (map[string]time.Duration, error) {
+	routes := map[string]time.Duration{}
+
+	for _, spec := range specs {
+		i := strings.LastIndex(spec, "=")
+		if i < 0 {
+			return nil, errors.New("invalid route timeout " + spec +
+				": expected path=duration")
+		}
+
+		path := spec[:i]
+		if !strings.HasPrefix(path, "/") {
+			return nil, errors.New("invalid route timeout " + spec +
+				": path must start with /")
+		}
+
+		d, err := time.ParseDuration(spec[i+1:])
+		if err != nil {
+			return nil, errors.New("invalid route timeout " + spec + ": " +
+				err.Error())
+		}
+		if d < 0 {
+			return nil, errors.New("invalid route timeout " + spec +
+				": negative duration")
+		}
+
+		routes[path] = d
+	}
+
+	return routes, nil
+}
+
+// Timeout is middleware which cancels request context when route timeout
+// passes or client disconnects, store calls made with it are abandoned
+// then. Timed out requests are responded with 503 unless handler already
+// responded, nothing is responded to disconnected clients.
+func (h Handler) Timeout(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		d, ok := h.timeouts.Routes[c.Path()]
+		if !ok {
+			d = h.timeouts.Default
+		}
+		if d <= 0 {
+			return next(c)
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request().Context(), d)
+		defer cancel()
+
+		c.SetRequest(c.Request().WithContext(ctx))
+
+		err := next(c)
+
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			if c.Response().Committed {
+				return err
+			}
+			c.Logger().Warn("request " + c.Request().URL.Path +
+				" timed out after " + d.String())
+			return echo.NewHTTPError(http.StatusServiceUnavailable,
+				tr(c, "request timed out, try again later"))
+		case context.Canceled:
+			c.Logger().Debug("client disconnected from " +
+				c.Request().URL.Path)
+			return nil
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseRouteTimeouts(t *testing.T) {
+	routes, err := ParseRouteTimeouts([]string{"/payouts.csv=2m",
+		"/projects/:project-id/users=45s"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]time.Duration{
+			"/payouts.csv":                2 * time.Minute,
+			"/projects/:project-id/users": 45 * time.Second,
+		}, routes)
+	}
+
+	for _, spec := range []string{"/payouts.csv", "payouts.csv=2m",
+		"/payouts.csv=x", "/payouts.csv=-1s"} {
+		_, err := ParseRouteTimeouts([]string{spec})
+		assert.Error(t, err, spec)
+	}
+}
+
+func Test_Timeout(t *testing.T) {
+	th := newTestHandler(Config{
+		Timeouts: Timeouts{
+			Default: time.Minute,
+			Routes:  map[string]time.Duration{"/users": 10 * time.Millisecond},
+		},
+	})
+
+	th.s.On("GetUsers").After(time.Second).Return([]bestore.User{}, nil)
+
+	start := time.Now()
+
+	res := th.serve("/users", th.Timeout(th.Users),
+		httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	// Store call is abandoned, page is not rendered.
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Empty(t, th.rendered.name)
+}
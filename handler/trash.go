@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/trash"
+	"github.com/labstack/echo"
+)
+
+type trashPageData struct {
+	Kind  string
+	Kinds []string
+	Items []mastore.TrashItem
+}
+
+func (h Handler) Trash(c echo.Context) error {
+	kind := c.Param("kind")
+	if !trash.ValidKind(kind) {
+		return echo.NewHTTPError(http.StatusNotFound,
+			tr(c, "unknown trash kind"))
+	}
+
+	items, err := h.poolMStore(c, h.kindPool(c, kind)).TrashItems(kind)
+	if err != nil {
+		return errors.New("failed to get trash items from DB: " + err.Error())
+	}
+
+	return c.Render(http.StatusOK, "trash", trashPageData{
+		Kind:  kind,
+		Kinds: trash.Kinds,
+		Items: items,
+	})
+}
+
+// restoredPath returns path of page where restored item is shown.
+func restoredPath(item mastore.TrashItem) string {
+	switch item.Kind {
+	case trash.Projects:
+		return "/projects?status=archived"
+	case trash.Admins:
+		return "/admins"
+	}
+	return fmt.Sprintf("/users/%d/addresses", item.EntityID)
+}
+
+func (h Handler) EditTrash(c echo.Context) error {
+	kind := c.Param("kind")
+	if !trash.ValidKind(kind) {
+		return echo.NewHTTPError(http.StatusNotFound,
+			tr(c, "unknown trash kind"))
+	}
+
+	id64, err := strconv.ParseUint(c.Param("item-id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid trash item ID"))
+	}
+
+	trashPath := "/trash/" + kind
+	p := h.kindPool(c, kind)
+
+	item, err := h.poolMStore(c, p).GetTrashItem(uint(id64))
+	if err != nil {
+		if err == mastore.ErrNotFound {
+			return h.redirectWithFlash(c, trashPath, FlashError,
+				"Item is not in trash anymore")
+		}
+		return errors.New("failed to get trash item from DB: " + err.Error())
+	}
+
+	if item.Kind != kind {
+		return echo.NewHTTPError(http.StatusNotFound,
+			tr(c, "trash item not found"))
+	}
+
+	switch c.FormValue("action") {
+	case "restore":
+		if item.Kind == trash.Addresses {
+			return h.restoreAddress(c, item)
+		}
+
+		err := h.poolBin(c, p).Restore(item)
+		if err != nil {
+			return h.redirectWithError(c, trashPath,
+				"Failed to restore item", err)
+		}
+
+		return h.redirectWithFlash(c, restoredPath(item), FlashSuccess,
+			`"%s" restored`, item.Name)
+
+	case "purge":
+		err := h.poolBin(c, p).Purge(item)
+		if err != nil {
+			return h.redirectWithError(c, trashPath,
+				"Failed to delete item permanently", err)
+		}
+
+		return h.redirectWithFlash(c, trashPath, FlashSuccess,
+			`"%s" deleted permanently`, item.Name)
+	}
+
+	return echo.NewHTTPError(http.StatusBadRequest, tr(c, "unknown action"))
+}
+
+// restoreAddress requests adding address back, as any other address change
+// it must be approved by another admin.
+func (h Handler) restoreAddress(c echo.Context,
+	item mastore.TrashItem) error {
+	userID, cn, address, err := trash.Address(item)
+	if err != nil {
+		return h.redirectWithError(c, "/trash/"+trash.Addresses,
+			"Failed to restore item", err)
+	}
+
+	// Item is removed from trash only when change is queued, so address
+	// can be restored again if it fails.
+	err = h.queueAddressChange(c, userID, mastore.AddressAdd, cn, address,
+		"")
+	if err == errChangePending {
+		return h.redirectWithFlash(c, "/trash/"+trash.Addresses, FlashError,
+			"The same address change is pending already")
+	}
+	if err != nil {
+		return h.redirectWithError(c, "/trash/"+trash.Addresses,
+			"Failed to restore item", err)
+	}
+
+	err = h.mstore(c).RemoveTrashItem(item.ID)
+	if err != nil {
+		c.Logger().Error("failed to remove restored address from trash: " +
+			err.Error())
+	}
+
+	return h.redirectPending(c, fmt.Sprintf("/users/%d/addresses", userID),
+		mastore.AddressAdd, cn, address)
+}
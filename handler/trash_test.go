@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_Trash(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	items := []mastore.TrashItem{
+		{ID: 7, Kind: "addresses", EntityID: 3, Name: "BTC addr"},
+	}
+	th.ms.On("TrashItems", "addresses").Return(items, nil)
+
+	res := th.serve("/trash/:kind", th.Trash,
+		httptest.NewRequest(http.MethodGet, "/trash/addresses", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "trash", th.rendered.name)
+	assert.Equal(t, items, th.rendered.data.(trashPageData).Items)
+}
+
+// trashedAddress is trash item of user 3 BTC address.
+var trashedAddress = mastore.TrashItem{
+	ID:       7,
+	Kind:     "addresses",
+	EntityID: 3,
+	Name:     "BTC addr",
+	Payload:  `{"coin":"BTC","address":"addr"}`,
+}
+
+func Test_EditTrash_restoreAddress(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("GetTrashItem", uint(7)).Return(trashedAddress, nil)
+	th.ms.On("UserAddressChanges", uint(3)).
+		Return([]mastore.AddressChange{}, nil)
+	th.ms.On("AddAddressChange", mastore.AddressChange{
+		UserID:      3,
+		Action:      "add",
+		Coin:        "BTC",
+		Address:     "addr",
+		RequestedBy: "login",
+	}).Return(uint(1), nil)
+	th.ms.On("RemoveTrashItem", uint(7)).Return(nil)
+
+	res := th.serve("/trash/:kind/:item-id", th.EditTrash,
+		newFormRequest("/trash/addresses/7",
+			url.Values{"action": {"restore"}}))
+
+	// Address is restored by approved change, not at once.
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/users/3/addresses", res.Header().Get("Location"))
+	assert.Equal(t, []Flash{{Kind: FlashSuccess, Message: `Adding BTC ` +
+		`address "addr" is waiting for approval by another admin`}},
+		th.flashes(res))
+
+	th.ms.AssertExpectations(t)
+	th.s.AssertNotCalled(t, "AddUserAddress", mock.Anything, mock.Anything,
+		mock.Anything)
+}
+
+func Test_EditTrash_restoreAddressPending(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("GetTrashItem", uint(7)).Return(trashedAddress, nil)
+	th.ms.On("UserAddressChanges", uint(3)).
+		Return([]mastore.AddressChange{{
+			UserID:  3,
+			Action:  "add",
+			Coin:    "BTC",
+			Address: "addr",
+			Status:  mastore.ChangePending,
+		}}, nil)
+
+	res := th.serve("/trash/:kind/:item-id", th.EditTrash,
+		newFormRequest("/trash/addresses/7",
+			url.Values{"action": {"restore"}}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/trash/addresses", res.Header().Get("Location"))
+	assert.Equal(t, []Flash{{Kind: FlashError,
+		Message: "The same address change is pending already"}},
+		th.flashes(res))
+
+	th.ms.AssertNotCalled(t, "AddAddressChange", mock.Anything)
+	th.ms.AssertNotCalled(t, "RemoveTrashItem", mock.Anything)
+}
+
+func Test_EditTrash_restoreAddressFailed(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("GetTrashItem", uint(7)).Return(trashedAddress, nil)
+	th.ms.On("UserAddressChanges", uint(3)).
+		Return([]mastore.AddressChange{}, nil)
+	th.ms.On("AddAddressChange", mock.Anything).
+		Return(uint(0), errors.New("connection refused"))
+
+	res := th.serve("/trash/:kind/:item-id", th.EditTrash,
+		newFormRequest("/trash/addresses/7",
+			url.Values{"action": {"restore"}}))
+
+	// Address stays in trash to be restored again.
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/trash/addresses", res.Header().Get("Location"))
+	assert.Equal(t, []Flash{{Kind: FlashError,
+		Message: "Failed to restore item"}}, th.flashes(res))
+
+	th.ms.AssertNotCalled(t, "RemoveTrashItem", mock.Anything)
+}
+
+func Test_EditTrash_kindMismatch(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("GetTrashItem", uint(7)).
+		Return(mastore.TrashItem{ID: 7, Kind: "admins"}, nil)
+
+	res := th.serve("/trash/:kind/:item-id", th.EditTrash,
+		newFormRequest("/trash/projects/7", url.Values{"action": {"purge"}}))
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+
+	th.ms.AssertNotCalled(t, "RemoveTrashItem", mock.Anything)
+	th.s.AssertNotCalled(t, "RemoveAdmin", mock.Anything)
+}
@@ -5,152 +5,512 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/addrindex"
 	"github.com/boomstarternetwork/mineradmin/coin"
+	"github.com/boomstarternetwork/mineradmin/mail"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/stats"
+	"github.com/boomstarternetwork/mineradmin/webhook"
 	"github.com/labstack/echo"
 )
 
+type userRow struct {
+	bestore.User
+	// Matches are user addresses found by search query.
+	Matches []mastore.AddressMeta
+}
+
 type usersPageData struct {
-	CSRFToken string
-	Users     []bestore.User
+	Users []userRow
+	// Query is search query, users are matched by email, name and address,
+	// address label or note.
+	Query string
+	// Hashrates are users hashrates by user ID.
+	Hashrates map[uint]stats.Hashrate
+	Form      formData
+}
+
+// Hashrate returns user current and average hashrate.
+func (d usersPageData) Hashrate(userID uint) stats.Hashrate {
+	return d.Hashrates[userID]
 }
 
 func (h Handler) Users(c echo.Context) error {
-	users, err := h.store.GetUsers()
+	return h.renderUsers(c, http.StatusOK, formData{})
+}
+
+func (h Handler) renderUsers(c echo.Context, code int, form formData) error {
+	users, err := h.store(c).GetUsers()
 	if err != nil {
 		return errors.New("failed to get users list from DB: " + err.Error())
 	}
 
-	return c.Render(http.StatusOK, "users", usersPageData{
-		CSRFToken: c.Get("csrf-token").(string),
-		Users:     users,
-	})
+	hs, err := h.hashrates(c)
+	if err != nil {
+		return err
+	}
+
+	data := usersPageData{
+		Query:     strings.TrimSpace(c.QueryParam("q")),
+		Hashrates: hs.Users,
+		Form:      form,
+	}
+
+	if data.Query == "" {
+		for _, u := range users {
+			data.Users = append(data.Users, userRow{User: u})
+		}
+		return c.Render(code, "users", data)
+	}
+
+	metas, err := h.mstore(c).SearchAddresses(data.Query)
+	if err != nil {
+		return errors.New("failed to search addresses in DB: " + err.Error())
+	}
+
+	addrs, err := h.allAddresses(c)
+	if err != nil {
+		return err
+	}
+
+	data.Users = searchUsers(users, metas, addrs, data.Query)
+
+	return c.Render(code, "users", data)
 }
 
-func (h Handler) NewUser(c echo.Context) error {
-	email := c.FormValue("email")
+// searchUsers returns users which email or name contains query or which
+// have addresses found by query, case is ignored. Addresses without meta
+// are not found in mstore, so addrs which contain query are matched too.
+func searchUsers(users []bestore.User, found []mastore.AddressMeta,
+	addrs []addrindex.Address, query string) []userRow {
+	matches := map[uint][]mastore.AddressMeta{}
+	for _, m := range found {
+		matches[m.UserID] = append(matches[m.UserID], m)
+	}
+
+	for _, a := range lookupAddresses(addrs, query) {
+		if !hasMeta(matches[a.User.ID], a) {
+			matches[a.User.ID] = append(matches[a.User.ID],
+				mastore.AddressMeta{
+					UserID:  a.User.ID,
+					Coin:    fmt.Sprintf("%s", a.Coin),
+					Address: a.Address,
+				})
+		}
+	}
+
+	q := strings.ToLower(query)
+
+	var rows []userRow
+
+	for _, u := range users {
+		if matches[u.ID] != nil ||
+			strings.Contains(strings.ToLower(u.Email), q) ||
+			strings.Contains(strings.ToLower(u.Name), q) {
+			rows = append(rows, userRow{User: u, Matches: matches[u.ID]})
+		}
+	}
+
+	return rows
+}
+
+// hasMeta checks that metas have meta of address.
+func hasMeta(metas []mastore.AddressMeta, a addrindex.Address) bool {
+	for _, m := range metas {
+		if m.Coin == fmt.Sprintf("%s", a.Coin) && m.Address == a.Address {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateUserEmail trims user email and checks its format.
+func ValidateUserEmail(email string) (string, error) {
 	email = strings.TrimSpace(email)
 	if email == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "blank email")
+		return "", errors.New("blank email")
 	}
 	if strings.Index(email, "@") == -1 {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid email format")
+		return "", errors.New("invalid email format")
 	}
+	return email, nil
+}
 
-	name := c.FormValue("name")
+// ValidateUserName trims user name and checks it is not blank.
+func ValidateUserName(name string) (string, error) {
 	name = strings.TrimSpace(name)
 	if name == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "blank name")
+		return "", errors.New("blank name")
 	}
+	return name, nil
+}
+
+func (h Handler) NewUser(c echo.Context) error {
+	form := newFormData(c, "email", "name")
 
-	userID, err := h.store.AddUser("", email, "", name, "")
+	email, err := ValidateUserEmail(form.Value("email"))
 	if err != nil {
-		return errors.New("failed to add user to DB: " + err.Error())
+		form.Errors["email"] = tr(c, err.Error())
 	}
 
-	return c.Redirect(http.StatusFound, fmt.Sprintf(
-		"/users/%d/addresses", userID))
+	name, err := ValidateUserName(form.Value("name"))
+	if err != nil {
+		form.Errors["name"] = tr(c, err.Error())
+	}
+
+	if len(form.Errors) > 0 {
+		return h.renderUsers(c, http.StatusBadRequest, form)
+	}
+
+	userID, err := h.store(c).AddUser("", email, "", name, "")
+	if err != nil {
+		return h.redirectWithError(c, "/users", "Failed to add user", err)
+	}
+
+	h.notify(c, func(n mail.Notifier) error {
+		return n.Welcome(email, name)
+	})
+
+	h.emit(c, webhook.UserCreated, echo.Map{"id": userID, "email": email,
+		"name": name})
+
+	return h.redirectWithFlash(c, fmt.Sprintf("/users/%d/addresses", userID),
+		FlashSuccess, `User "%s" created`, email)
 }
 
 type userAddressesData struct {
-	CSRFToken string
-	Coins     []bestore.Coin
-	User      bestore.User
+	Coins []bestore.Coin
+	User  bestore.User
+	// Addresses are user addresses by coin, primary one first.
 	Addresses map[bestore.Coin][]string
+	// Primary are addresses payouts go to by coin.
+	Primary map[bestore.Coin]string
+	// Meta are addresses labels, notes and creation data by coin and
+	// address.
+	Meta map[string]mastore.AddressMeta
+	// Changes are all user address changes, most recent first.
+	Changes []mastore.AddressChange
+	Login   string
+	Form    formData
+}
+
+// Pending returns pending address changes.
+func (d userAddressesData) Pending() []addressChange {
+	var chs []addressChange
+	for _, ch := range d.Changes {
+		if ch.Status == mastore.ChangePending {
+			chs = append(chs, addressChange{
+				AddressChange: ch,
+				User:          d.User,
+				Mine:          ch.RequestedBy == d.Login,
+				Back:          "user",
+			})
+		}
+	}
+	return chs
+}
+
+// IsPrimary checks that payouts in coin go to address.
+func (d userAddressesData) IsPrimary(cn bestore.Coin, address string) bool {
+	return d.Primary[cn] == address
+}
+
+// AddressMeta returns address label, note and creation data.
+func (d userAddressesData) AddressMeta(cn bestore.Coin,
+	address string) mastore.AddressMeta {
+	return d.Meta[addressKey(cn, address)]
+}
+
+// Replacements returns addresses which can replace primary address of coin.
+func (d userAddressesData) Replacements(cn bestore.Coin) []string {
+	var addrs []string
+	for _, a := range d.Addresses[cn] {
+		if a != d.Primary[cn] {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs
+}
+
+// EligibleAt returns when address becomes eligible for payouts, zero time
+// is returned if it is eligible already.
+func (d userAddressesData) EligibleAt(cn bestore.Coin,
+	address string) time.Time {
+	coinStr := fmt.Sprintf("%s", cn)
+	for _, ch := range d.Changes {
+		if ch.Status == mastore.ChangeApproved &&
+			ch.Action == mastore.AddressAdd && ch.Coin == coinStr &&
+			ch.Address == address {
+			if ch.EligibleAt.After(time.Now()) {
+				return ch.EligibleAt
+			}
+			return time.Time{}
+		}
+	}
+	return time.Time{}
 }
 
 func (h Handler) UserAddresses(c echo.Context) error {
 	userIDStr := c.Param("user-id")
 	userID64, err := strconv.ParseUint(userIDStr, 10, 64)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid user ID"))
 	}
 
 	userID := uint(userID64)
 
-	user, err := h.store.GetUserByID(userID)
+	user, err := h.store(c).GetUserByID(userID)
 	if err != nil {
 		if bestore.NotFound(err) {
-			return echo.NewHTTPError(http.StatusNotFound, "user not found")
+			return echo.NewHTTPError(http.StatusNotFound,
+				tr(c, "user not found"))
 		}
 		return errors.New("failed to get user from DB: " + err.Error())
 	}
 
-	uas, err := h.store.GetUserAddresses(userID)
+	return h.renderUserAddresses(c, http.StatusOK, user, formData{})
+}
+
+func (h Handler) renderUserAddresses(c echo.Context, code int,
+	user bestore.User, form formData) error {
+	addrs, primary, err := h.userPayoutAddresses(c, user.ID)
 	if err != nil {
-		return errors.New("failed to get user addresses from DB: " + err.Error())
+		return err
 	}
 
-	addrs := map[bestore.Coin][]string{}
+	for cn, as := range addrs {
+		sort.SliceStable(as, func(i, j int) bool {
+			return as[i] == primary[cn] && as[j] != primary[cn]
+		})
+	}
 
-	for _, ua := range uas {
-		addrs[ua.Coin] = append(addrs[ua.Coin], ua.Address)
+	meta, err := h.userAddressesMeta(c, user.ID)
+	if err != nil {
+		return err
+	}
+
+	chs, err := h.mstore(c).UserAddressChanges(user.ID)
+	if err != nil {
+		return errors.New("failed to get user address changes from DB: " +
+			err.Error())
 	}
 
-	return c.Render(http.StatusOK, "user/addresses", userAddressesData{
-		CSRFToken: c.Get("csrf-token").(string),
+	return c.Render(code, "user/addresses", userAddressesData{
 		Coins:     coin.List(),
 		User:      user,
 		Addresses: addrs,
+		Primary:   primary,
+		Meta:      meta,
+		Changes:   chs,
+		Login:     adminLogin(c),
+		Form:      form,
 	})
 }
 
 var addressRe = regexp.MustCompile(`\S`)
 
+// ValidateAddress trims payout address and checks its format.
+func ValidateAddress(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if !addressRe.MatchString(address) {
+		return "", errors.New("invalid address format")
+	}
+	return address, nil
+}
+
+const (
+	maxAddressLabelLen = 64
+	maxAddressNoteLen  = 1000
+)
+
+// ValidateAddressNote trims address label and note and checks their length.
+func ValidateAddressNote(label string, note string) (string, string,
+	error) {
+	label = strings.TrimSpace(label)
+	if len([]rune(label)) > maxAddressLabelLen {
+		return "", "", errors.New("label is too long")
+	}
+	note = strings.TrimSpace(note)
+	if len([]rune(note)) > maxAddressNoteLen {
+		return "", "", errors.New("note is too long")
+	}
+	return label, note, nil
+}
+
 func (h Handler) EditUserAddresses(c echo.Context) error {
 	userIDStr := c.Param("user-id")
 	userID64, err := strconv.ParseUint(userIDStr, 10, 64)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid user ID"))
 	}
 
 	userID := uint(userID64)
 
-	_, err = h.store.GetUserByID(userID)
+	user, err := h.store(c).GetUserByID(userID)
 	if err != nil {
 		if bestore.NotFound(err) {
-			return echo.NewHTTPError(http.StatusNotFound, "user not found")
+			return echo.NewHTTPError(http.StatusNotFound,
+				tr(c, "user not found"))
 		}
 		return errors.New("failed to get user from DB: " + err.Error())
 	}
 
 	action := c.FormValue("action")
+	if action != "add" && action != "remove" && action != "primary" &&
+		action != "note" {
+		return echo.NewHTTPError(http.StatusBadRequest, tr(c, "unknown action"))
+	}
+
+	form := newFormData(c, "coin", "address")
 
-	coinStr := c.FormValue("coin")
-	cn, err := bestore.ParseCoin(coinStr)
+	cn, err := bestore.ParseCoin(form.Value("coin"))
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid coin")
+		form.Errors["coin"] = tr(c, "invalid coin")
 	}
 
-	address := c.FormValue("address")
-	address = strings.TrimSpace(address)
-	if !addressRe.MatchString(address) {
+	address, err := ValidateAddress(form.Value("address"))
+	if err != nil {
+		form.Errors["address"] = tr(c, err.Error())
+	}
+
+	if len(form.Errors) > 0 {
+		if action == "add" {
+			return h.renderUserAddresses(c, http.StatusBadRequest, user,
+				form)
+		}
 		return echo.NewHTTPError(http.StatusBadRequest,
-			"invalid address format")
+			tr(c, "invalid coin or address"))
 	}
 
-	addrsPath := fmt.Sprintf("/users/%d/addresses", userID)
+	if action == "add" {
+		addrs, _, err := h.userPayoutAddresses(c, userID)
+		if err != nil {
+			return err
+		}
+
+		if hasAddress(addrs[cn], address) {
+			form.Errors["address"] = tr(c, "address is added already")
+			return h.renderUserAddresses(c, http.StatusBadRequest, user,
+				form)
+		}
 
-	switch action {
-	case "add":
-		err := h.store.AddUserAddress(userID, cn, address)
+		owners, err := h.otherOwners(c, userID, address)
 		if err != nil {
-			return errors.New("failed to add to DB: " + err.Error())
+			return err
 		}
 
-		return c.Redirect(http.StatusFound, addrsPath)
+		if len(owners) > 0 {
+			if h.blockDuplicates {
+				form.Errors["address"] = tr(c,
+					"address belongs to %s already", ownersEmails(owners))
+				return h.renderUserAddresses(c, http.StatusBadRequest, user,
+					form)
+			}
+			h.addFlash(c, FlashWarning, tr(c,
+				"Address belongs to %s already", ownersEmails(owners)))
+		}
 
-	case "remove":
-		err := h.store.RemoveUserAddress(userID, cn, address)
+		return h.requestAddressChange(c, userID, mastore.AddressAdd, cn,
+			address, "")
+	}
+
+	addrsPath := fmt.Sprintf("/users/%d/addresses", userID)
+
+	addrs, primary, err := h.userPayoutAddresses(c, userID)
+	if err != nil {
+		return err
+	}
+
+	if !hasAddress(addrs[cn], address) {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "user has no such address"))
+	}
+
+	if action == "note" {
+		label, note, err := ValidateAddressNote(c.FormValue("label"),
+			c.FormValue("note"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, tr(c, err.Error()))
+		}
+
+		err = h.mstore(c).SetAddressNote(userID, fmt.Sprintf("%s", cn), address,
+			label, note)
 		if err != nil {
-			return errors.New("failed to remove from DB: " + err.Error())
+			return h.redirectWithError(c, addrsPath,
+				"Failed to save address note", err)
+		}
+
+		h.emit(c, webhook.AddressesUpdated, addressEvent{UserID: userID,
+			Action: action, Coin: fmt.Sprintf("%s", cn), Address: address,
+			Label: label, Note: note})
+
+		return h.redirectWithFlash(c, addrsPath, FlashSuccess,
+			`%s address "%s" saved`, cn, address)
+	}
+
+	// Payouts go to primary address, so it is changed with approval like
+	// addresses themselves.
+	if action == "primary" {
+		if address == primary[cn] {
+			return h.redirectWithFlash(c, addrsPath, FlashError,
+				`%s address "%s" is primary already`, cn, address)
 		}
 
-		return c.Redirect(http.StatusFound, addrsPath)
+		return h.requestAddressChange(c, userID, mastore.AddressPrimary, cn,
+			address, "")
 	}
 
-	return echo.NewHTTPError(http.StatusBadRequest, "unknown action")
+	// Payouts must not stop silently, so primary address is removed only
+	// with replacement chosen, unless it is the last one. Replacement
+	// becomes primary when removal is approved.
+	var replacement string
+	if address == primary[cn] && len(addrs[cn]) > 1 {
+		replacement = c.FormValue("replacement")
+		if replacement == address || !hasAddress(addrs[cn], replacement) {
+			return h.redirectWithFlash(c, addrsPath, FlashError,
+				"Choose new primary address before removing primary one")
+		}
+	}
+
+	return h.requestAddressChange(c, userID, mastore.AddressRemove, cn,
+		address, replacement)
+}
+
+// addressKey returns key of address in maps by coin and address.
+func addressKey(cn bestore.Coin, address string) string {
+	return fmt.Sprintf("%s/%s", cn, address)
+}
+
+// userAddressesMeta returns user addresses metadata by addressKey.
+func (h Handler) userAddressesMeta(c echo.Context, userID uint) (
+	map[string]mastore.AddressMeta, error) {
+	metas, err := h.mstore(c).UserAddressesMeta(userID)
+	if err != nil {
+		return nil, errors.New("failed to get user addresses metadata " +
+			"from DB: " + err.Error())
+	}
+
+	meta := map[string]mastore.AddressMeta{}
+	for _, m := range metas {
+		meta[m.Coin+"/"+m.Address] = m
+	}
+
+	return meta, nil
+}
+
+func hasAddress(addrs []string, address string) bool {
+	for _, a := range addrs {
+		if a == address {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,377 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/addrindex"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/stats"
+	"github.com/boomstarternetwork/mineradmin/webhook"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_searchUsers(t *testing.T) {
+	users := []bestore.User{
+		{ID: 3, Email: "a@example.com", Name: "Alice"},
+		{ID: 4, Email: "b@example.com", Name: "Bob"},
+		{ID: 5, Email: "c@example.com", Name: "Carol"},
+	}
+	found := []mastore.AddressMeta{
+		{UserID: 4, Coin: "ETH", Address: "addr", Label: "Big rig"},
+	}
+
+	assert.Equal(t, []userRow{
+		{User: users[1], Matches: found},
+		{User: users[2]},
+	}, searchUsers(users, found, nil, "CAROL"))
+}
+
+func Test_searchUsers_addressesWithoutMeta(t *testing.T) {
+	users := []bestore.User{
+		{ID: 3, Email: "a@example.com"},
+		{ID: 4, Email: "b@example.com"},
+		{ID: 5, Email: "c@example.com"},
+	}
+	found := []mastore.AddressMeta{
+		{UserID: 4, Coin: "ETH", Address: "0xRig", Label: "Big rig"},
+	}
+	addrs := []addrindex.Address{
+		{User: users[0], Coin: bestore.BTC, Address: "1rig"},
+		{User: users[1], Coin: bestore.ETH, Address: "0xRig"},
+		{User: users[2], Coin: bestore.ETH, Address: "0xother"},
+	}
+
+	assert.Equal(t, []userRow{
+		{User: users[0], Matches: []mastore.AddressMeta{
+			{UserID: 3, Coin: "BTC", Address: "1rig"},
+		}},
+		{User: users[1], Matches: found},
+	}, searchUsers(users, found, addrs, "rig"))
+}
+
+func Test_ValidateAddressNote(t *testing.T) {
+	label, note, err := ValidateAddressNote(" rig ", " in garage\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "rig", label)
+	assert.Equal(t, "in garage", note)
+
+	_, _, err = ValidateAddressNote(string(make([]rune, 65)), "")
+	assert.EqualError(t, err, "label is too long")
+}
+
+func Test_EditUserAddresses_add(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3}, nil)
+	th.userAddresses()
+	th.s.On("GetUsers").Return([]bestore.User{{ID: 3}}, nil)
+	th.ms.On("UserAddressChanges", uint(3)).
+		Return([]mastore.AddressChange{}, nil)
+	th.ms.On("AddAddressChange", mastore.AddressChange{
+		UserID:      3,
+		Action:      "add",
+		Coin:        "ETH",
+		Address:     "addr",
+		RequestedBy: "login",
+	}).Return(uint(1), nil)
+
+	res := th.serve("/users/:user-id/addresses", th.EditUserAddresses,
+		newFormRequest("/users/3/addresses", url.Values{
+			"action":  {"add"},
+			"coin":    {"ETH"},
+			"address": {"addr"},
+		}))
+
+	// Address is added when another admin approves the change.
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/users/3/addresses", res.Header().Get("Location"))
+	assert.Equal(t, []Flash{{Kind: FlashSuccess, Message: `Adding ETH ` +
+		`address "addr" is waiting for approval by another admin`}},
+		th.flashes(res))
+
+	th.ms.AssertExpectations(t)
+	th.s.AssertNotCalled(t, "AddUserAddress", mock.Anything, mock.Anything,
+		mock.Anything)
+}
+
+func Test_EditUserAddresses_addOwned(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3}, nil)
+	th.userAddresses("addr")
+	th.ms.On("UserAddressesMeta", uint(3)).Return([]mastore.AddressMeta{}, nil)
+	th.ms.On("UserAddressChanges", uint(3)).
+		Return([]mastore.AddressChange{}, nil)
+
+	res := th.serve("/users/:user-id/addresses", th.EditUserAddresses,
+		newFormRequest("/users/3/addresses", url.Values{
+			"action":  {"add"},
+			"coin":    {"ETH"},
+			"address": {"addr"},
+		}))
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Equal(t, "address is added already",
+		th.rendered.data.(userAddressesData).Form.Error("address"))
+
+	th.ms.AssertNotCalled(t, "AddAddressChange", mock.Anything)
+}
+
+func Test_EditUserAddresses_primary(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3}, nil)
+	th.userAddresses("addr1", "addr2")
+	th.ms.On("UserAddressChanges", uint(3)).
+		Return([]mastore.AddressChange{}, nil)
+	th.ms.On("AddAddressChange", mastore.AddressChange{
+		UserID:      3,
+		Action:      "primary",
+		Coin:        "ETH",
+		Address:     "addr2",
+		RequestedBy: "login",
+	}).Return(uint(1), nil)
+
+	res := th.serve("/users/:user-id/addresses", th.EditUserAddresses,
+		newFormRequest("/users/3/addresses", url.Values{
+			"action":  {"primary"},
+			"coin":    {"ETH"},
+			"address": {"addr2"},
+		}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/users/3/addresses", res.Header().Get("Location"))
+
+	th.ms.AssertExpectations(t)
+	th.ms.AssertNotCalled(t, "SetPrimaryAddress", mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything)
+}
+
+func Test_EditUserAddresses_removePrimary(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3}, nil)
+	th.userAddresses("addr1", "addr2")
+	th.ms.On("UserAddressChanges", uint(3)).
+		Return([]mastore.AddressChange{}, nil)
+	th.ms.On("AddAddressChange", mastore.AddressChange{
+		UserID:      3,
+		Action:      "remove",
+		Coin:        "ETH",
+		Address:     "addr1",
+		RequestedBy: "login",
+		Replacement: "addr2",
+	}).Return(uint(1), nil)
+
+	res := th.serve("/users/:user-id/addresses", th.EditUserAddresses,
+		newFormRequest("/users/3/addresses", url.Values{
+			"action":      {"remove"},
+			"coin":        {"ETH"},
+			"address":     {"addr1"},
+			"replacement": {"addr2"},
+		}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+
+	th.ms.AssertExpectations(t)
+	th.ms.AssertNotCalled(t, "SetPrimaryAddress", mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything)
+	th.s.AssertNotCalled(t, "RemoveUserAddress", mock.Anything,
+		mock.Anything, mock.Anything)
+}
+
+func Test_EditUserAddresses_removePrimaryWithoutReplacement(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3}, nil)
+	th.userAddresses("addr1", "addr2")
+
+	res := th.serve("/users/:user-id/addresses", th.EditUserAddresses,
+		newFormRequest("/users/3/addresses", url.Values{
+			"action":  {"remove"},
+			"coin":    {"ETH"},
+			"address": {"addr1"},
+		}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/users/3/addresses", res.Header().Get("Location"))
+	assert.Equal(t, []Flash{{Kind: FlashError, Message: "Choose new " +
+		"primary address before removing primary one"}}, th.flashes(res))
+
+	th.ms.AssertNotCalled(t, "AddAddressChange", mock.Anything)
+}
+
+func Test_EditUserAddresses_note(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3}, nil)
+	th.userAddresses("addr")
+	th.ms.On("SetAddressNote", uint(3), "ETH", "addr", "rig",
+		"in garage").Return(nil)
+
+	res := th.serve("/users/:user-id/addresses", th.EditUserAddresses,
+		newFormRequest("/users/3/addresses", url.Values{
+			"action":  {"note"},
+			"coin":    {"ETH"},
+			"address": {"addr"},
+			"label":   {" rig "},
+			"note":    {"in garage"},
+		}))
+
+	// Note is saved at once, it does not change payouts.
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/users/3/addresses", res.Header().Get("Location"))
+	assert.Equal(t, []Flash{{Kind: FlashSuccess,
+		Message: `ETH address "addr" saved`}}, th.flashes(res))
+
+	th.ms.AssertExpectations(t)
+	th.ms.AssertNotCalled(t, "AddAddressChange", mock.Anything)
+}
+
+func Test_Users_search(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("WorkerHashrates", mock.Anything, stats.AverageWindow).
+		Return([]mastore.WorkerHashrate{}, nil)
+	th.s.On("GetUsers").Return([]bestore.User{
+		{ID: 3, Email: "a@example.com", Name: "Alice"},
+		{ID: 4, Email: "b@example.com", Name: "Bob"},
+		{ID: 5, Email: "c@example.com", Name: "Carol"},
+	}, nil)
+	th.ms.On("SearchAddresses", "rig").Return([]mastore.AddressMeta{
+		{UserID: 4, Coin: "ETH", Address: "addr", Label: "Big rig"},
+	}, nil)
+	th.s.On("GetUserAddresses", uint(3)).Return([]bestore.UserAddress{
+		{UserID: 3, Coin: bestore.BTC, Address: "1rig"},
+	}, nil)
+	th.s.On("GetUserAddresses", uint(4)).Return([]bestore.UserAddress{}, nil)
+	th.s.On("GetUserAddresses", uint(5)).Return([]bestore.UserAddress{}, nil)
+
+	res := th.serve("/users", th.Users,
+		httptest.NewRequest(http.MethodGet, "/users?q=rig", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "users", th.rendered.name)
+
+	data := th.rendered.data.(usersPageData)
+	assert.Equal(t, "rig", data.Query)
+	assert.Equal(t, []userRow{
+		{
+			User: bestore.User{ID: 3, Email: "a@example.com",
+				Name: "Alice"},
+			Matches: []mastore.AddressMeta{
+				{UserID: 3, Coin: "BTC", Address: "1rig"},
+			},
+		},
+		{
+			User: bestore.User{ID: 4, Email: "b@example.com", Name: "Bob"},
+			Matches: []mastore.AddressMeta{
+				{UserID: 4, Coin: "ETH", Address: "addr",
+					Label: "Big rig"},
+			},
+		},
+	}, data.Users)
+}
+
+func Test_EditUserAddresses_addDuplicate(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3}, nil)
+	th.s.On("GetUsers").Return([]bestore.User{
+		{ID: 3},
+		{ID: 4, Email: "b@example.com"},
+	}, nil)
+	th.userAddresses()
+	th.s.On("GetUserAddresses", uint(4)).Return([]bestore.UserAddress{
+		{UserID: 4, Coin: bestore.BTC, Address: "ADDR"},
+	}, nil)
+	th.ms.On("UserAddressChanges", uint(3)).
+		Return([]mastore.AddressChange{}, nil)
+	th.ms.On("AddAddressChange", mastore.AddressChange{
+		UserID:      3,
+		Action:      "add",
+		Coin:        "ETH",
+		Address:     "addr",
+		RequestedBy: "login",
+	}).Return(uint(1), nil)
+
+	res := th.serve("/users/:user-id/addresses", th.EditUserAddresses,
+		newFormRequest("/users/3/addresses", url.Values{
+			"action":  {"add"},
+			"coin":    {"ETH"},
+			"address": {"addr"},
+		}))
+
+	// Duplicate in other coin and case is only warned about.
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, []Flash{
+		{Kind: FlashWarning, Message: "Address belongs to b@example.com " +
+			"already"},
+		{Kind: FlashSuccess, Message: `Adding ETH address "addr" is ` +
+			`waiting for approval by another admin`},
+	}, th.flashes(res))
+
+	th.ms.AssertExpectations(t)
+}
+
+func Test_EditUserAddresses_addDuplicateBlocked(t *testing.T) {
+	th := newTestHandler(Config{BlockDuplicateAddresses: true})
+
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3}, nil)
+	th.s.On("GetUsers").Return([]bestore.User{
+		{ID: 3},
+		{ID: 4, Email: "b@example.com"},
+	}, nil)
+	th.userAddresses()
+	th.s.On("GetUserAddresses", uint(4)).Return([]bestore.UserAddress{
+		{UserID: 4, Coin: bestore.ETH, Address: "addr"},
+	}, nil)
+	th.ms.On("UserAddressesMeta", uint(3)).Return([]mastore.AddressMeta{}, nil)
+	th.ms.On("UserAddressChanges", uint(3)).
+		Return([]mastore.AddressChange{}, nil)
+
+	res := th.serve("/users/:user-id/addresses", th.EditUserAddresses,
+		newFormRequest("/users/3/addresses", url.Values{
+			"action":  {"add"},
+			"coin":    {"ETH"},
+			"address": {"addr"},
+		}))
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Equal(t, "user/addresses", th.rendered.name)
+	assert.Equal(t, "address belongs to b@example.com already",
+		th.rendered.data.(userAddressesData).Form.Error("address"))
+
+	th.ms.AssertNotCalled(t, "AddAddressChange", mock.Anything)
+}
+
+func Test_NewUser_emitsEvent(t *testing.T) {
+	events := &recordingEmitter{}
+	th := newTestHandler(Config{Events: events})
+
+	th.s.On("AddUser", "", "a@example.com", "", "Alice", "").
+		Return(uint(3), nil)
+
+	res := th.serve("/users", th.NewUser,
+		newFormRequest("/users", url.Values{
+			"email": {"a@example.com"},
+			"name":  {"Alice"},
+		}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/users/3/addresses", res.Header().Get("Location"))
+	assert.Equal(t, []recordedEvent{{
+		event: webhook.UserCreated,
+		by:    "login",
+		data: echo.Map{"id": uint(3), "email": "a@example.com",
+			"name": "Alice"},
+	}}, events.events)
+
+	th.s.AssertExpectations(t)
+}
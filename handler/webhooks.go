@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/webhook"
+	"github.com/labstack/echo"
+)
+
+// emit emits event to webhooks of pool request changed, failures are
+// logged only.
+func (h Handler) emit(c echo.Context, event string, data interface{}) {
+	events := h.writtenPool(c).Events
+	if events == nil {
+		return
+	}
+
+	err := events.Emit(event, actor(c), data)
+	if err != nil {
+		c.Logger().Error("failed to emit " + event + " event: " +
+			err.Error())
+	}
+}
+
+// addressEvent is data of webhook.AddressesUpdated and address change
+// events. Status is address change status for added and removed addresses.
+type addressEvent struct {
+	UserID  uint   `json:"user_id"`
+	Action  string `json:"action"`
+	Coin    string `json:"coin"`
+	Address string `json:"address"`
+	Status  string `json:"status,omitempty"`
+	Label   string `json:"label,omitempty"`
+	Note    string `json:"note,omitempty"`
+}
+
+type webhooksPageData struct {
+	Webhooks []mastore.Webhook
+	Events   []string
+	// Checked are events checked in new webhook form.
+	Checked []string
+	Form    formData
+	// Added is webhook just added, its secret is shown only then.
+	Added *mastore.Webhook
+}
+
+// shownSecretLen is how many first secret characters are shown, so webhook
+// secrets can be told apart but not used to sign events.
+const shownSecretLen = 6
+
+// MaskedSecret returns secret prefix.
+func (d webhooksPageData) MaskedSecret(secret string) string {
+	if len(secret) > shownSecretLen {
+		secret = secret[:shownSecretLen]
+	}
+	return secret + "…"
+}
+
+// IsChecked checks that event is checked in new webhook form.
+func (d webhooksPageData) IsChecked(event string) bool {
+	for _, e := range d.Checked {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (h Handler) Webhooks(c echo.Context) error {
+	return h.renderWebhooks(c, http.StatusOK, webhooksPageData{})
+}
+
+func (h Handler) renderWebhooks(c echo.Context, code int,
+	data webhooksPageData) error {
+	ws, err := h.mstore(c).Webhooks()
+	if err != nil {
+		return errors.New("failed to get webhooks from DB: " + err.Error())
+	}
+
+	data.Webhooks = ws
+	data.Events = webhook.Events
+
+	return c.Render(code, "webhooks", data)
+}
+
+// ValidateWebhook trims webhook URL and checks it and events.
+func ValidateWebhook(w mastore.Webhook) (mastore.Webhook, map[string]error) {
+	errs := map[string]error{}
+
+	w.URL = strings.TrimSpace(w.URL)
+	if err := webhook.ValidateURL(w.URL); err != nil {
+		errs["url"] = err
+	}
+
+	if len(w.Events) == 0 {
+		errs["events"] = errors.New("choose at least one event")
+	}
+	for _, e := range w.Events {
+		if !webhook.ValidEvent(e) {
+			errs["events"] = errors.New("unknown event")
+		}
+	}
+
+	return w, errs
+}
+
+func (h Handler) NewWebhook(c echo.Context) error {
+	form := newFormData(c, "url")
+
+	params, err := c.FormParams()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	w, errs := ValidateWebhook(mastore.Webhook{
+		URL:       form.Value("url"),
+		Events:    params["events"],
+		CreatedBy: adminLogin(c),
+	})
+	for field, err := range errs {
+		form.Errors[field] = tr(c, err.Error())
+	}
+
+	if len(form.Errors) > 0 {
+		return h.renderWebhooks(c, http.StatusBadRequest, webhooksPageData{
+			Checked: w.Events,
+			Form:    form,
+		})
+	}
+
+	w.Secret, err = webhook.GenerateSecret()
+	if err != nil {
+		return h.redirectWithError(c, "/webhooks", "Failed to add webhook",
+			err)
+	}
+
+	w.ID, err = h.mstore(c).AddWebhook(w)
+	if err != nil {
+		return h.redirectWithError(c, "/webhooks", "Failed to add webhook",
+			err)
+	}
+
+	// Page is rendered instead of redirect, secret is not kept anywhere
+	// but DB to show it later.
+	return h.renderWebhooks(c, http.StatusCreated, webhooksPageData{
+		Added: &w,
+	})
+}
+
+func (h Handler) EditWebhook(c echo.Context) error {
+	id64, err := strconv.ParseUint(c.Param("webhook-id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid webhook ID"))
+	}
+
+	switch c.FormValue("action") {
+	case "remove":
+		err := h.mstore(c).RemoveWebhook(uint(id64))
+		if err != nil {
+			return h.redirectWithError(c, "/webhooks",
+				"Failed to remove webhook", err)
+		}
+
+		return h.redirectWithFlash(c, "/webhooks", FlashSuccess,
+			"Webhook removed")
+	}
+
+	return echo.NewHTTPError(http.StatusBadRequest, tr(c, "unknown action"))
+}
+
+// webhookDeliveriesLimit is number of deliveries shown in delivery log.
+const webhookDeliveriesLimit = 200
+
+type webhookDeliveriesPageData struct {
+	Deliveries []mastore.WebhookDelivery
+}
+
+func (h Handler) WebhookDeliveries(c echo.Context) error {
+	ds, err := h.mstore(c).WebhookDeliveries(webhookDeliveriesLimit)
+	if err != nil {
+		return errors.New("failed to get webhook deliveries from DB: " +
+			err.Error())
+	}
+
+	return c.Render(http.StatusOK, "webhook-deliveries",
+		webhookDeliveriesPageData{Deliveries: ds})
+}
+
+func (h Handler) EditWebhookDelivery(c echo.Context) error {
+	id64, err := strconv.ParseUint(c.Param("delivery-id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid webhook delivery ID"))
+	}
+
+	const logPath = "/webhooks/deliveries"
+
+	switch c.FormValue("action") {
+	case "retry":
+		d, err := h.mstore(c).GetWebhookDelivery(uint(id64))
+		if err != nil {
+			if err == mastore.ErrNotFound {
+				return echo.NewHTTPError(http.StatusNotFound,
+					tr(c, "webhook delivery not found"))
+			}
+			return errors.New("failed to get webhook delivery from DB: " +
+				err.Error())
+		}
+
+		if d.Status == mastore.DeliveryDelivered {
+			return h.redirectWithFlash(c, logPath, FlashError,
+				"Delivery is delivered already")
+		}
+
+		d.Status = mastore.DeliveryPending
+		d.Attempts = 0
+		d.NextAttemptAt = time.Now()
+
+		err = h.mstore(c).UpdateWebhookDelivery(d)
+		if err != nil {
+			return h.redirectWithError(c, logPath,
+				"Failed to retry delivery", err)
+		}
+
+		return h.redirectWithFlash(c, logPath, FlashSuccess,
+			"Delivery %d will be retried shortly", d.ID)
+	}
+
+	return echo.NewHTTPError(http.StatusBadRequest, tr(c, "unknown action"))
+}
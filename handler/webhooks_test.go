@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_NewWebhook(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	var added mastore.Webhook
+	th.ms.On("AddWebhook", mock.Anything).Return(uint(1), nil).
+		Run(func(args mock.Arguments) {
+			added = args.Get(0).(mastore.Webhook)
+		})
+	th.ms.On("Webhooks").Return([]mastore.Webhook{}, nil)
+
+	res := th.serve("/webhooks", th.NewWebhook,
+		newFormRequest("/webhooks", url.Values{
+			"url":    {" https://example.com/hook "},
+			"events": {"user.created", "project.created"},
+		}))
+
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.Equal(t, "https://example.com/hook", added.URL)
+	assert.Equal(t, []string{"user.created", "project.created"},
+		added.Events)
+	assert.Equal(t, "login", added.CreatedBy)
+	assert.NotEmpty(t, added.Secret)
+
+	// Secret is shown on rendered page once.
+	data := th.rendered.data.(webhooksPageData)
+	if assert.NotNil(t, data.Added) {
+		assert.Equal(t, uint(1), data.Added.ID)
+		assert.Equal(t, added.Secret, data.Added.Secret)
+	}
+}
+
+func Test_NewWebhook_invalid(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.ms.On("Webhooks").Return([]mastore.Webhook{}, nil)
+
+	res := th.serve("/webhooks", th.NewWebhook,
+		newFormRequest("/webhooks", url.Values{
+			"url":    {"ftp://example.com"},
+			"events": {"unknown"},
+		}))
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	data := th.rendered.data.(webhooksPageData)
+	assert.NotEmpty(t, data.Form.Error("url"))
+	assert.Equal(t, "unknown event", data.Form.Error("events"))
+	assert.Equal(t, []string{"unknown"}, data.Checked)
+	assert.Nil(t, data.Added)
+
+	th.ms.AssertNotCalled(t, "AddWebhook", mock.Anything)
+}
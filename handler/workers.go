@@ -0,0 +1,296 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/hashrate"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/labstack/echo"
+)
+
+const (
+	maxWorkerNameLen     = 64
+	maxWorkerLocationLen = 200
+)
+
+// ValidateWorker trims worker fields, parses expected hashrate and checks
+// them. Blank hashrate means it is unknown.
+func ValidateWorker(name string, expectedHashrate string,
+	location string) (mastore.Worker, map[string]error) {
+	errs := map[string]error{}
+
+	w := mastore.Worker{
+		Name:     strings.TrimSpace(name),
+		Location: strings.TrimSpace(location),
+	}
+
+	if w.Name == "" {
+		errs["name"] = errors.New("blank worker name")
+	} else if len([]rune(w.Name)) > maxWorkerNameLen {
+		errs["name"] = errors.New("worker name is too long")
+	}
+
+	if strings.TrimSpace(expectedHashrate) != "" {
+		h, err := hashrate.Parse(expectedHashrate)
+		if err != nil {
+			errs["hashrate"] = err
+		}
+		w.ExpectedHashrate = h
+	}
+
+	if len([]rune(w.Location)) > maxWorkerLocationLen {
+		errs["location"] = errors.New("location is too long")
+	}
+
+	return w, errs
+}
+
+// workerNameTaken checks that another worker among workers has name, case
+// is ignored.
+func workerNameTaken(workers []mastore.Worker, id uint, name string) bool {
+	for _, w := range workers {
+		if w.ID != id && strings.EqualFold(w.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+type userWorkersPageData struct {
+	User    bestore.User
+	Workers []mastore.Worker
+	Form    formData
+}
+
+// userByParam returns user with ID from user-id path param.
+func (h Handler) userByParam(c echo.Context) (bestore.User, error) {
+	userID64, err := strconv.ParseUint(c.Param("user-id"), 10, 64)
+	if err != nil {
+		return bestore.User{}, echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid user ID"))
+	}
+
+	user, err := h.store(c).GetUserByID(uint(userID64))
+	if err != nil {
+		if bestore.NotFound(err) {
+			return bestore.User{}, echo.NewHTTPError(http.StatusNotFound,
+				tr(c, "user not found"))
+		}
+		return bestore.User{}, errors.New("failed to get user from DB: " +
+			err.Error())
+	}
+
+	return user, nil
+}
+
+func (h Handler) UserWorkers(c echo.Context) error {
+	user, err := h.userByParam(c)
+	if err != nil {
+		return err
+	}
+
+	return h.renderUserWorkers(c, http.StatusOK, user, formData{})
+}
+
+func (h Handler) renderUserWorkers(c echo.Context, code int,
+	user bestore.User, form formData) error {
+	ws, err := h.mstore(c).UserWorkers(user.ID)
+	if err != nil {
+		return errors.New("failed to get user workers from DB: " +
+			err.Error())
+	}
+
+	return c.Render(code, "user/workers", userWorkersPageData{
+		User:    user,
+		Workers: ws,
+		Form:    form,
+	})
+}
+
+func (h Handler) NewUserWorker(c echo.Context) error {
+	user, err := h.userByParam(c)
+	if err != nil {
+		return err
+	}
+
+	form := newFormData(c, "name", "hashrate", "location")
+
+	w, errs := ValidateWorker(form.Value("name"), form.Value("hashrate"),
+		form.Value("location"))
+	for field, err := range errs {
+		form.Errors[field] = tr(c, err.Error())
+	}
+
+	workersPath := fmt.Sprintf("/users/%d/workers", user.ID)
+
+	ws, err := h.mstore(c).UserWorkers(user.ID)
+	if err != nil {
+		return h.redirectWithError(c, workersPath, "Failed to add worker",
+			err)
+	}
+
+	if workerNameTaken(ws, 0, w.Name) {
+		form.Errors["name"] = tr(c, "worker with this name exists already")
+	}
+
+	if len(form.Errors) > 0 {
+		return h.renderUserWorkers(c, http.StatusBadRequest, user, form)
+	}
+
+	w.UserID = user.ID
+	w.CreatedBy = adminLogin(c)
+
+	_, err = h.mstore(c).AddWorker(w)
+	if err != nil {
+		return h.redirectWithError(c, workersPath, "Failed to add worker",
+			err)
+	}
+
+	return h.redirectWithFlash(c, workersPath, FlashSuccess,
+		`Worker "%s" added`, w.Name)
+}
+
+func (h Handler) EditUserWorker(c echo.Context) error {
+	user, err := h.userByParam(c)
+	if err != nil {
+		return err
+	}
+
+	id64, err := strconv.ParseUint(c.Param("worker-id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			tr(c, "invalid worker ID"))
+	}
+
+	worker, err := h.mstore(c).GetWorker(uint(id64))
+	if err != nil && err != mastore.ErrNotFound {
+		return errors.New("failed to get worker from DB: " + err.Error())
+	}
+	if err == mastore.ErrNotFound || worker.UserID != user.ID {
+		return echo.NewHTTPError(http.StatusNotFound,
+			tr(c, "worker not found"))
+	}
+
+	workersPath := fmt.Sprintf("/users/%d/workers", user.ID)
+
+	switch c.FormValue("action") {
+	case "edit":
+		w, errs := ValidateWorker(c.FormValue("name"),
+			c.FormValue("hashrate"), c.FormValue("location"))
+		for _, field := range []string{"name", "hashrate", "location"} {
+			if err := errs[field]; err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest,
+					tr(c, err.Error()))
+			}
+		}
+
+		ws, err := h.mstore(c).UserWorkers(user.ID)
+		if err != nil {
+			return h.redirectWithError(c, workersPath,
+				"Failed to save worker", err)
+		}
+
+		if workerNameTaken(ws, worker.ID, w.Name) {
+			return h.redirectWithFlash(c, workersPath, FlashError,
+				`Worker "%s" exists already`, w.Name)
+		}
+
+		worker.Name = w.Name
+		worker.ExpectedHashrate = w.ExpectedHashrate
+		worker.Location = w.Location
+
+		err = h.mstore(c).UpdateWorker(worker)
+		if err != nil {
+			return h.redirectWithError(c, workersPath,
+				"Failed to save worker", err)
+		}
+
+		return h.redirectWithFlash(c, workersPath, FlashSuccess,
+			`Worker "%s" saved`, worker.Name)
+	case "remove":
+		err := h.mstore(c).RemoveWorker(worker.ID)
+		if err != nil {
+			return h.redirectWithError(c, workersPath,
+				"Failed to remove worker", err)
+		}
+
+		return h.redirectWithFlash(c, workersPath, FlashSuccess,
+			`Worker "%s" removed`, worker.Name)
+	}
+
+	return echo.NewHTTPError(http.StatusBadRequest, tr(c, "unknown action"))
+}
+
+// workersByEmail returns workers of users by lower case user email, as
+// balances know users only by email.
+func (h Handler) workersByEmail(c echo.Context,
+	users []bestore.User) (map[string][]mastore.Worker, error) {
+	ws, err := h.mstore(c).Workers()
+	if err != nil {
+		return nil, errors.New("failed to get workers from DB: " +
+			err.Error())
+	}
+
+	emails := map[uint]string{}
+	for _, u := range users {
+		emails[u.ID] = strings.ToLower(u.Email)
+	}
+
+	byEmail := map[string][]mastore.Worker{}
+	for _, w := range ws {
+		if email, ok := emails[w.UserID]; ok {
+			byEmail[email] = append(byEmail[email], w)
+		}
+	}
+
+	return byEmail, nil
+}
+
+// WorkersExport writes CSV with workers of all users. Expected hashrate is
+// in hashes per second.
+func (h Handler) WorkersExport(c echo.Context) error {
+	users, err := h.store(c).GetUsers()
+	if err != nil {
+		return errors.New("failed to get users from DB: " + err.Error())
+	}
+
+	ws, err := h.mstore(c).Workers()
+	if err != nil {
+		return errors.New("failed to get workers from DB: " + err.Error())
+	}
+
+	emails := map[uint]string{}
+	for _, u := range users {
+		emails[u.ID] = u.Email
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		`attachment; filename="workers.csv"`)
+	c.Response().WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(c.Response())
+
+	w.Write([]string{"user_id", "email", "worker", "expected_hashrate",
+		"location"})
+
+	for _, wr := range ws {
+		w.Write([]string{
+			strconv.FormatUint(uint64(wr.UserID), 10),
+			emails[wr.UserID],
+			wr.Name,
+			strconv.FormatFloat(wr.ExpectedHashrate, 'f', -1, 64),
+			wr.Location,
+		})
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_ValidateWorker(t *testing.T) {
+	w, errs := ValidateWorker(" rig1 ", "120 TH/s", " rack 4 ")
+	assert.Empty(t, errs)
+	assert.Equal(t, mastore.Worker{Name: "rig1", ExpectedHashrate: 120e12,
+		Location: "rack 4"}, w)
+
+	w, errs = ValidateWorker("rig1", "", "")
+	assert.Empty(t, errs)
+	assert.Zero(t, w.ExpectedHashrate)
+
+	_, errs = ValidateWorker(" ", "fast", "")
+	assert.EqualError(t, errs["name"], "blank worker name")
+	assert.EqualError(t, errs["hashrate"], "invalid hashrate")
+}
+
+func Test_workerNameTaken(t *testing.T) {
+	ws := []mastore.Worker{{ID: 1, Name: "Rig1"}, {ID: 2, Name: "rig2"}}
+
+	assert.True(t, workerNameTaken(ws, 0, "rig1"))
+	assert.False(t, workerNameTaken(ws, 1, "RIG1"))
+	assert.True(t, workerNameTaken(ws, 1, "rig2"))
+	assert.False(t, workerNameTaken(ws, 0, "rig3"))
+}
+
+func Test_NewUserWorker(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3}, nil)
+	th.ms.On("UserWorkers", uint(3)).Return([]mastore.Worker{{ID: 1,
+		UserID: 3, Name: "rig1"}}, nil)
+	th.ms.On("AddWorker", mastore.Worker{
+		UserID:           3,
+		Name:             "rig2",
+		ExpectedHashrate: 1.5e9,
+		Location:         "garage",
+		CreatedBy:        "login",
+	}).Return(uint(2), nil)
+
+	res := th.serve("/users/:user-id/workers", th.NewUserWorker,
+		newFormRequest("/users/3/workers", url.Values{
+			"name":     {"rig2"},
+			"hashrate": {"1.5 GH/s"},
+			"location": {"garage"},
+		}))
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/users/3/workers", res.Header().Get("Location"))
+	assert.Equal(t, []Flash{{Kind: FlashSuccess,
+		Message: `Worker "rig2" added`}}, th.flashes(res))
+
+	th.ms.AssertExpectations(t)
+}
+
+func Test_NewUserWorker_duplicateName(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3}, nil)
+	th.ms.On("UserWorkers", uint(3)).Return([]mastore.Worker{{ID: 1,
+		UserID: 3, Name: "rig1"}}, nil)
+
+	res := th.serve("/users/:user-id/workers", th.NewUserWorker,
+		newFormRequest("/users/3/workers", url.Values{"name": {"RIG1"}}))
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Equal(t, "user/workers", th.rendered.name)
+	assert.Equal(t, "worker with this name exists already",
+		th.rendered.data.(userWorkersPageData).Form.Error("name"))
+
+	th.ms.AssertNotCalled(t, "AddWorker", mock.Anything)
+}
+
+func Test_EditUserWorker_otherUser(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUserByID", uint(3)).Return(bestore.User{ID: 3}, nil)
+	th.ms.On("GetWorker", uint(7)).Return(mastore.Worker{ID: 7, UserID: 4,
+		Name: "rig1"}, nil)
+
+	res := th.serve("/users/:user-id/workers/:worker-id", th.EditUserWorker,
+		newFormRequest("/users/3/workers/7",
+			url.Values{"action": {"remove"}}))
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+
+	th.ms.AssertNotCalled(t, "RemoveWorker", mock.Anything)
+	th.ms.AssertNotCalled(t, "UpdateWorker", mock.Anything)
+}
+
+func Test_WorkersExport(t *testing.T) {
+	th := newTestHandler(Config{})
+
+	th.s.On("GetUsers").Return([]bestore.User{{ID: 3,
+		Email: "a@example.com"}}, nil)
+	th.ms.On("Workers").Return([]mastore.Worker{{ID: 1, UserID: 3,
+		Name: "rig1", ExpectedHashrate: 120e12, Location: "rack 4"}}, nil)
+
+	res := th.serve("/workers.csv", th.WorkersExport,
+		httptest.NewRequest(http.MethodGet, "/workers.csv", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "user_id,email,worker,expected_hashrate,location\n"+
+		"3,a@example.com,rig1,120000000000000,rack 4\n", res.Body.String())
+}
@@ -0,0 +1,63 @@
+// Package hashrate parses and formats mining hashrates, which are kept as
+// hashes per second.
+package hashrate
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// units are hashrate unit prefixes from the largest one.
+var units = []struct {
+	prefix string
+	scale  float64
+}{
+	{"E", 1e18},
+	{"P", 1e15},
+	{"T", 1e12},
+	{"G", 1e9},
+	{"M", 1e6},
+	{"K", 1e3},
+	{"", 1},
+}
+
+// Parse parses hashrate with optional unit, e.g. "120 TH/s", "1.5gh" or
+// "500", to hashes per second.
+func Parse(s string) (float64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, "/S")
+	s = strings.TrimSuffix(s, "H")
+	s = strings.TrimSpace(s)
+
+	scale := 1.0
+
+	for _, u := range units {
+		if u.prefix != "" && strings.HasSuffix(s, u.prefix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.prefix))
+			scale = u.scale
+			break
+		}
+	}
+
+	h, err := strconv.ParseFloat(s, 64)
+	if err != nil || h < 0 || math.IsInf(h, 0) || math.IsNaN(h) {
+		return 0, errors.New("invalid hashrate")
+	}
+
+	return h * scale, nil
+}
+
+// Format formats hashes per second with the largest unit hashrate is at
+// least one of, e.g. 1.2e14 gives "120 TH/s".
+func Format(h float64) string {
+	for _, u := range units {
+		if h >= u.scale || u.scale == 1 {
+			v := math.Round(h/u.scale*100) / 100
+			return strconv.FormatFloat(v, 'f', -1, 64) + " " + u.prefix +
+				"H/s"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package hashrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		s string
+		h float64
+	}{
+		{"500", 500},
+		{"120 TH/s", 120e12},
+		{"1.5gh", 1.5e9},
+		{" 2 MH/s ", 2e6},
+		{"3 kh/s", 3e3},
+		{"0", 0},
+	}
+
+	for _, tt := range tests {
+		h, err := Parse(tt.s)
+		if assert.NoError(t, err, tt.s) {
+			assert.InDelta(t, tt.h, h, tt.h*1e-9, tt.s)
+		}
+	}
+
+	for _, s := range []string{"", "fast", "-1 TH/s", "TH/s", "1 XH/s"} {
+		_, err := Parse(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func Test_Format(t *testing.T) {
+	assert.Equal(t, "120 TH/s", Format(120e12))
+	assert.Equal(t, "1.5 GH/s", Format(1.5e9))
+	assert.Equal(t, "999 KH/s", Format(999e3))
+	assert.Equal(t, "12.35 H/s", Format(12.346))
+	assert.Equal(t, "0 H/s", Format(0))
+}
@@ -0,0 +1,218 @@
+// Package i18n translates admin UI messages. Messages are keyed by their
+// English text, so English needs no catalog and untranslated messages fall
+// back to English.
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	En = "en"
+	Ru = "ru"
+
+	Default = En
+)
+
+type catalog struct {
+	// name is locale name in its own language.
+	name     string
+	messages map[string]string
+	// plurals maps English singular to locale plural forms.
+	plurals map[string][]string
+	// plural returns index of plural form for n.
+	plural func(n int) int
+
+	groupSep string
+	fracSep  string
+}
+
+var catalogs = map[string]catalog{
+	En: {
+		name: "English",
+		plural: func(n int) int {
+			if n == 1 || n == -1 {
+				return 0
+			}
+			return 1
+		},
+		groupSep: ",",
+		fracSep:  ".",
+	},
+	Ru: {
+		name:     "Русский",
+		messages: ruMessages,
+		plurals:  ruPlurals,
+		plural: func(n int) int {
+			if n < 0 {
+				n = -n
+			}
+			switch {
+			case n%10 == 1 && n%100 != 11:
+				return 0
+			case n%10 >= 2 && n%10 <= 4 && (n%100 < 10 || n%100 >= 20):
+				return 1
+			}
+			return 2
+		},
+		groupSep: " ",
+		fracSep:  ",",
+	},
+}
+
+// Locales returns supported locales sorted by name.
+func Locales() []string {
+	var ls []string
+	for l := range catalogs {
+		ls = append(ls, l)
+	}
+	sort.Strings(ls)
+	return ls
+}
+
+// Supported checks that locale has catalog.
+func Supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// Name returns locale name in its own language.
+func Name(locale string) string {
+	return get(locale).name
+}
+
+func get(locale string) catalog {
+	c, ok := catalogs[locale]
+	if !ok {
+		return catalogs[Default]
+	}
+	return c
+}
+
+// Has checks that locale has translation for message. English has all
+// messages.
+func Has(locale string, message string) bool {
+	if locale == En {
+		return true
+	}
+	_, ok := get(locale).messages[message]
+	return ok
+}
+
+// T translates message and formats it with args using fmt verbs.
+func T(locale string, message string, args ...interface{}) string {
+	if tr, ok := get(locale).messages[message]; ok {
+		message = tr
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Plural returns word form for n. English forms one and many are used as key
+// for locales with more forms.
+func Plural(locale string, n int, one string, many string) string {
+	c := get(locale)
+	forms := []string{one, many}
+	if fs, ok := c.plurals[one]; ok {
+		forms = fs
+	}
+	i := c.plural(n)
+	if i >= len(forms) {
+		i = len(forms) - 1
+	}
+	return forms[i]
+}
+
+var amountRe = regexp.MustCompile(`^(-?)(\d+)(?:\.(\d+))?$`)
+
+// FormatAmount formats decimal amount with locale separators, grouping
+// integer digits by thousands and trimming trailing fraction zeros. Non
+// decimal amounts are returned as is.
+func FormatAmount(locale string, amount string) string {
+	m := amountRe.FindStringSubmatch(strings.TrimSpace(amount))
+	if m == nil {
+		return amount
+	}
+	return formatNumber(get(locale), m[1], m[2], strings.TrimRight(m[3], "0"))
+}
+
+// FormatFiat formats fiat amount like FormatAmount but keeps two fraction
+// digits and appends currency code.
+func FormatFiat(locale string, amount string, currency string) string {
+	m := amountRe.FindStringSubmatch(strings.TrimSpace(amount))
+	if m == nil {
+		return amount + " " + currency
+	}
+	fracPart := (m[3] + "00")[:2]
+	return formatNumber(get(locale), m[1], m[2], fracPart) + " " + currency
+}
+
+func formatNumber(c catalog, sign string, intPart string,
+	fracPart string) string {
+	var b strings.Builder
+
+	b.WriteString(sign)
+	for i, d := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(c.groupSep)
+		}
+		b.WriteRune(d)
+	}
+	if fracPart != "" {
+		b.WriteString(c.fracSep)
+		b.WriteString(fracPart)
+	}
+
+	return b.String()
+}
+
+// Match chooses supported locale from Accept-Language header value, Default
+// is returned if nothing matches.
+func Match(acceptLanguage string) string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	var ws []weighted
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				v, err := strconv.ParseFloat(f[2:], 64)
+				if err == nil {
+					q = v
+				}
+			}
+		}
+
+		ws = append(ws, weighted{
+			locale: strings.SplitN(tag, "-", 2)[0],
+			q:      q,
+		})
+	}
+
+	sort.SliceStable(ws, func(i, j int) bool { return ws[i].q > ws[j].q })
+
+	for _, w := range ws {
+		if w.q > 0 && Supported(w.locale) {
+			return w.locale
+		}
+	}
+
+	return Default
+}
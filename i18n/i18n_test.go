@@ -0,0 +1,51 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FormatAmount(t *testing.T) {
+	cases := map[string]string{
+		"0":              "0",
+		"0.10000000":     "0.1",
+		"1234.5":         "1,234.5",
+		"-1234567.000":   "-1,234,567",
+		"123":            "123",
+		"not-a-number":   "not-a-number",
+		"1000000.000001": "1,000,000.000001",
+	}
+	for in, out := range cases {
+		assert.Equal(t, out, FormatAmount(En, in), in)
+	}
+
+	assert.Equal(t, "1 234,5", FormatAmount(Ru, "1234.50"))
+}
+
+func Test_Plural(t *testing.T) {
+	assert.Equal(t, "user", Plural(En, 1, "user", "users"))
+	assert.Equal(t, "users", Plural(En, 0, "user", "users"))
+	assert.Equal(t, "users", Plural(En, 5, "user", "users"))
+
+	assert.Equal(t, "адрес", Plural(Ru, 21, "address", "addresses"))
+	assert.Equal(t, "адреса", Plural(Ru, 3, "address", "addresses"))
+	assert.Equal(t, "адресов", Plural(Ru, 11, "address", "addresses"))
+	assert.Equal(t, "адресов", Plural(Ru, 25, "address", "addresses"))
+}
+
+func Test_T(t *testing.T) {
+	assert.Equal(t, "Projects", T(En, "Projects"))
+	assert.Equal(t, "Проекты", T(Ru, "Projects"))
+	assert.Equal(t, "untranslated", T(Ru, "untranslated"))
+	assert.Equal(t, "untranslated", T("xx", "untranslated"))
+	assert.Equal(t, `Проект "a" создан`, T(Ru, `Project "%s" created`, "a"))
+}
+
+func Test_Match(t *testing.T) {
+	assert.Equal(t, Ru, Match("ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7"))
+	assert.Equal(t, En, Match("de-DE,en;q=0.5,ru;q=0.4"))
+	assert.Equal(t, Ru, Match("de, ru;q=0.1"))
+	assert.Equal(t, En, Match(""))
+	assert.Equal(t, En, Match("ru;q=0"))
+}
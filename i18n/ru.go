@@ -0,0 +1,383 @@
+package i18n
+
+var ruMessages = map[string]string{
+	// Navigation and page titles.
+	"Projects":        "Проекты",
+	"Project":         "Проект",
+	"Users":           "Пользователи",
+	"Admins":          "Администраторы",
+	"Settings":        "Настройки",
+	"Logout":          "Выйти",
+	"Login":           "Вход",
+	"Password":        "Пароль",
+	"Addresses":       "Адреса",
+	"Edit":            "Изменить",
+	"Trash":           "Корзина",
+	"Webhooks":        "Вебхуки",
+	"Alerts":          "Оповещения",
+	"Workers":         "Воркеры",
+	"Pool":            "Пул",
+	"All pools":       "Все пулы",
+	"Switch pool":     "Сменить пул",
+	"Reports":         "Отчёты",
+	"Report":          "Отчёт",
+	"Format":          "Формат",
+	"Size, bytes":     "Размер, байт",
+	"No reports":      "Нет отчётов",
+	"projects":        "проекты",
+	"admins":          "администраторы",
+	"addresses":       "адреса",
+	"Restore":         "Восстановить",
+	"My account":      "Мой аккаунт",
+	"Miner account":   "Аккаунт майнера",
+	"Balances":        "Балансы",
+	"Undo":            "Отменить",
+	"Address changes": "Изменения адресов",
+	"Approve":         "Одобрить",
+	"Reject":          "Отклонить",
+	"Cancel":          "Отменить",
+	"User":            "Пользователь",
+	"Change":          "Изменение",
+	"Requested by":    "Запросил",
+	"Requested at":    "Запрошено",
+	"Decided by":      "Решил",
+	"Decided at":      "Решено",
+	"Pending changes": "Ожидающие изменения",
+	"History":         "История",
+	"pending":         "ожидает",
+	"approved":        "одобрено",
+	"delivered":       "доставлено",
+	"failed":          "не доставлено",
+	"rejected":        "отклонено",
+	"cancelled":       "отменено",
+	"Archive":         "В архив",
+	"Unarchive":       "Вернуть из архива",
+	"Status":          "Статус",
+	"Owner":           "Владелец",
+	"Created":         "Создан",
+	"Current":         "Текущие",
+	"All":             "Все",
+	"active":          "активен",
+	"paused":          "приостановлен",
+	"archived":        "в архиве",
+	"stale":           "нет роста",
+	"anomaly":         "аномалия",
+
+	// Forms.
+	"Login:":            "Логин:",
+	"Password:":         "Пароль:",
+	"Name:":             "Имя:",
+	"Email:":            "Email:",
+	"Type email":        "Введите email",
+	"Send sign in link": "Отправить ссылку для входа",
+	"Sign in link will be sent to your email.": "Ссылка для входа будет " +
+		"отправлена на ваш email.",
+	"Address changes take effect after approval by administrators.": "Изменения " +
+		"адресов вступают в силу после одобрения администраторами.",
+	"Coin:":                      "Монета:",
+	"Address:":                   "Адрес:",
+	"Language:":                  "Язык:",
+	"Description:":               "Описание:",
+	"Owner contact:":             "Контакт владельца:",
+	"Status:":                    "Статус:",
+	"Target coins:":              "Целевые монеты:",
+	"Created:":                   "Создан:",
+	"No projects":                "Нет проектов",
+	"Deleted by":                 "Удалил",
+	"Deleted at":                 "Удалено",
+	"Trash is empty":             "Корзина пуста",
+	"No pending address changes": "Нет ожидающих изменений адресов",
+	"Address changes take effect after approval by another admin.": "Изменения " +
+		"адресов вступают в силу после одобрения другим администратором.",
+	"eligible for payouts from %s": "выплаты возможны с %s",
+	"add %s address \"%s\"":        "добавить адрес %s \"%s\"",
+	"remove %s address \"%s\"":     "удалить адрес %s \"%s\"",
+	"Delete permanently":           "Удалить навсегда",
+	"make %s address \"%s\" primary": "сделать адрес %s \"%s\" " +
+		"основным",
+	"remove %s address \"%s\", make \"%s\" primary": "удалить адрес " +
+		"%s \"%s\", сделать \"%s\" основным",
+	"Are you sure you want to delete \"%s\" permanently?": "Вы уверены, " +
+		"что хотите удалить \"%s\" навсегда?",
+	"Type owner email or phone": "Введите email или телефон " +
+		"владельца",
+	"Type login":              "Введите логин",
+	"Type password":           "Введите пароль",
+	"Type admin login":        "Введите логин администратора",
+	"Type project name":       "Введите название проекта",
+	"Type user email":         "Введите email пользователя",
+	"Type user name":          "Введите имя пользователя",
+	"Type address":            "Введите адрес",
+	"Sign in":                 "Войти",
+	"Create":                  "Создать",
+	"Save":                    "Сохранить",
+	"Add":                     "Добавить",
+	"Remove":                  "Удалить",
+	"Reset password":          "Сбросить пароль",
+	"New admin":               "Новый администратор",
+	"New user":                "Новый пользователь",
+	"New project":             "Новый проект",
+	"Edit project":            "Изменение проекта",
+	"Add address":             "Добавление адреса",
+	"Email":                   "Email",
+	"Name":                    "Имя",
+	"Mined coins":             "Добыто монет",
+	"Miner address":           "Адрес майнера",
+	"No coins mined":          "Монеты не добыты",
+	"no coins mined":          "монеты не добыты",
+	"No %s address":           "Нет адреса %s",
+	"No price for %s":         "Нет цены для %s",
+	"Total":                   "Итого",
+	"primary":                 "основной",
+	"Make primary":            "Сделать основным",
+	"New primary address":     "Новый основной адрес",
+	"Export payout addresses": "Выгрузить адреса выплат",
+	"Search":                  "Найти",
+	"Reset":                   "Сбросить",
+	"Search by email, name, address, label or note": "Поиск по email, " +
+		"имени, адресу, метке или заметке",
+	"Found addresses":          "Найденные адреса",
+	"No users found":           "Пользователи не найдены",
+	"Edit label and note":      "Изменить метку и заметку",
+	"Label:":                   "Метка:",
+	"Note:":                    "Заметка:",
+	"Type address label":       "Введите метку адреса",
+	"Address lookup":           "Поиск адреса",
+	"Duplicate addresses":      "Повторяющиеся адреса",
+	"No duplicate addresses":   "Нет повторяющихся адресов",
+	"No addresses found":       "Адреса не найдены",
+	"Type address or its part": "Введите адрес или его часть",
+	"Coin":                     "Монета",
+	"Address":                  "Адрес",
+	"New webhook":              "Новый вебхук",
+	"URL:":                     "URL:",
+	"URL":                      "URL",
+	"Events:":                  "События:",
+	"Events":                   "События",
+	"Event":                    "Событие",
+	"Delivery log":             "Журнал доставки",
+	"Secret":                   "Секрет",
+	"Created by":               "Создал",
+	"Attempts":                 "Попытки",
+	"Response":                 "Ответ",
+	"Next attempt":             "Следующая попытка",
+	"Retry":                    "Повторить",
+	"No webhooks":              "Нет вебхуков",
+	"No webhook deliveries":    "Нет доставок вебхуков",
+	"Are you sure you want to remove webhook \"%s\"?": "Вы уверены, " +
+		"что хотите удалить вебхук \"%s\"?",
+	"Webhook \"%s\" added. Copy its secret now, it is not shown again:": "Вебхук " +
+		"\"%s\" добавлен. Скопируйте его секрет сейчас, он больше не " +
+		"будет показан:",
+	"added by %s at %s":  "добавил %s %s",
+	"Add worker":         "Добавление воркера",
+	"Type rig name":      "Введите имя рига",
+	"Expected hashrate:": "Ожидаемый хешрейт:",
+	"Location:":          "Расположение:",
+	"Type rig location":  "Введите расположение рига",
+	"Expected hashrate":  "Ожидаемый хешрейт",
+	"Location":           "Расположение",
+	"No workers":         "Нет воркеров",
+	"Hashrate":           "Хешрейт",
+	"Current hashrate":   "Текущий хешрейт",
+	"24h average %s":     "в среднем за 24 ч %s",
+	"Export workers":     "Экспорт воркеров",
+	"Are you sure you want to remove worker \"%s\"?": "Вы уверены, " +
+		"что хотите удалить воркер \"%s\"?",
+	"Alert":             "Оповещение",
+	"Details":           "Подробности",
+	"Opened":            "Открыто",
+	"Resolved":          "Закрыто",
+	"Acknowledged by":   "Просмотрел",
+	"Acknowledge":       "Отметить просмотренным",
+	"No open alerts":    "Нет открытых оповещений",
+	"Recently resolved": "Недавно закрытые",
+	"Not acknowledged":  "Не просмотрено",
+	"Missing addresses": "Нет адресов",
+	"Top projects":      "Лучшие проекты",
+	"Mined":             "Добыто",
+	"Recent events":     "Последние события",
+	"When":              "Когда",
+	"Who":               "Кто",
+	"No events":         "Нет событий",
+	"Updated at %s":     "Обновлено %s",
+	"Users without payout address": "Пользователи без адреса " +
+		"выплат",
+	"All miners can be paid": "Всем майнерам можно выплатить",
+	"\"%s\" moved to trash":  "\"%s\" перемещён в корзину",
+
+	// Validation errors.
+	"invalid project ID":            "неверный ID проекта",
+	"invalid user ID":               "неверный ID пользователя",
+	"invalid admin ID":              "неверный ID администратора",
+	"project not found":             "проект не найден",
+	"user not found":                "пользователь не найден",
+	"unknown action":                "неизвестное действие",
+	"invalid project name":          "неверное название проекта",
+	"blank email":                   "пустой email",
+	"invalid email format":          "неверный формат email",
+	"blank name":                    "пустое имя",
+	"blank login":                   "пустой логин",
+	"invalid login format":          "неверный формат логина",
+	"invalid coin":                  "неверная монета",
+	"invalid address format":        "неверный формат адреса",
+	"invalid coin or address":       "неверная монета или адрес",
+	"invalid login or password":     "неверный логин или пароль",
+	"invalid locale":                "неверный язык",
+	"unknown trash kind":            "неизвестный тип корзины",
+	"invalid trash item ID":         "неверный ID элемента корзины",
+	"trash item not found":          "элемент корзины не найден",
+	"admin not found":               "администратор не найден",
+	"invalid address change ID":     "неверный ID изменения адреса",
+	"address change not found":      "изменение адреса не найдено",
+	"user has no such address":      "у пользователя нет такого адреса",
+	"label is too long":             "слишком длинная метка",
+	"note is too long":              "слишком длинная заметка",
+	"address belongs to %s already": "адрес уже принадлежит %s",
+	"invalid webhook URL":           "неверный URL вебхука",
+	"choose at least one event":     "выберите хотя бы одно событие",
+	"unknown event":                 "неизвестное событие",
+	"invalid webhook ID":            "неверный ID вебхука",
+	"invalid webhook delivery ID":   "неверный ID доставки вебхука",
+	"webhook delivery not found":    "доставка вебхука не найдена",
+	"invalid project status":        "неверный статус проекта",
+	"description is too long":       "слишком длинное описание",
+	"owner contact is too long":     "слишком длинный контакт владельца",
+	"blank worker name":             "пустое имя воркера",
+	"worker name is too long":       "слишком длинное имя воркера",
+	"invalid hashrate":              "неверный хешрейт",
+	"location is too long":          "слишком длинное расположение",
+	"worker with this name exists already": "воркер с таким именем уже " +
+		"существует",
+	"invalid worker ID":        "неверный ID воркера",
+	"worker not found":         "воркер не найден",
+	"address is added already": "адрес уже добавлен",
+	"address can not be added, contact administrators": "адрес нельзя " +
+		"добавить, свяжитесь с администраторами",
+	"invalid alert ID": "неверный ID оповещения",
+	"alert not found":  "оповещение не найдено",
+	"request timed out, try again later": "время запроса истекло, " +
+		"попробуйте позже",
+	"your account is removed": "ваша учётная запись удалена",
+	"you have no access to any pool": "у вас нет доступа ни к одному " +
+		"пулу",
+	"form was opened for pool %s, reload page": "форма открыта для " +
+		"пула %s, обновите страницу",
+	"you may only view pool %s": "вы можете только просматривать пул %s",
+	"unknown pool":              "неизвестный пул",
+	"invalid report ID":         "неверный ID отчёта",
+	"report not found":          "отчёт не найден",
+	"choose primary addresses before export: %s": "выберите основные " +
+		"адреса перед выгрузкой: %s",
+
+	// Flash messages.
+	"Project \"%s\" created": "Проект \"%s\" создан",
+	"Project \"%s\" saved":   "Проект \"%s\" сохранён",
+	"Project archived":       "Проект перенесён в архив",
+	"Project unarchived":     "Проект возвращён из архива",
+	"Only archived project can be removed": "Удалить можно только " +
+		"проект в архиве",
+	"Failed to save project":        "Не удалось сохранить проект",
+	"Failed to archive project":     "Не удалось перенести проект в архив",
+	"Failed to unarchive project":   "Не удалось вернуть проект из архива",
+	"User \"%s\" created":           "Пользователь \"%s\" создан",
+	"%s address \"%s\" added":       "Адрес %s \"%s\" добавлен",
+	"%s address \"%s\" removed":     "Адрес %s \"%s\" удалён",
+	"Settings saved":                "Настройки сохранены",
+	"Project \"%s\" moved to trash": "Проект \"%s\" перемещён в корзину",
+	"Admin \"%s\" moved to trash": "Администратор \"%s\" перемещён в " +
+		"корзину",
+	"\"%s\" restored":              "\"%s\" восстановлен",
+	"\"%s\" deleted permanently":   "\"%s\" удалён навсегда",
+	"Item is not in trash anymore": "Элемента уже нет в корзине",
+	"You can not remove yourself":  "Нельзя удалить самого себя",
+	"Failed to request address change": "Не удалось запросить изменение " +
+		"адреса",
+	"The same address change is pending already": "Такое же изменение " +
+		"адреса уже ожидает одобрения",
+	"Adding %s address \"%s\" is waiting for approval by another admin": "Добавление " +
+		"адреса %s \"%s\" ожидает одобрения другим администратором",
+	"Removing %s address \"%s\" is waiting for approval by another admin": "Удаление " +
+		"адреса %s \"%s\" ожидает одобрения другим администратором",
+	"Making %s address \"%s\" primary is waiting for approval by another admin": "Назначение " +
+		"адреса %s \"%s\" основным ожидает одобрения другим администратором",
+	"%s address \"%s\" is primary already": "Адрес %s \"%s\" уже " +
+		"основной",
+	"User has no such address anymore, reject the change": "У " +
+		"пользователя больше нет такого адреса, отклоните изменение",
+	"User has this address already, reject the change": "У " +
+		"пользователя уже есть этот адрес, отклоните изменение",
+	"Address is primary now, reject the change and choose a replacement primary address": "Адрес " +
+		"теперь основной, отклоните изменение и выберите новый основной " +
+		"адрес",
+	"Adding %s address \"%s\" is waiting for approval by administrators": "Добавление " +
+		"адреса %s \"%s\" ожидает одобрения администраторами",
+	"Removing %s address \"%s\" is waiting for approval by administrators": "Удаление " +
+		"адреса %s \"%s\" ожидает одобрения администраторами",
+	"Primary address can be removed by administrators only": "Основной " +
+		"адрес могут удалить только администраторы",
+	"If %s is registered, sign in link is sent to it": "Если %s " +
+		"зарегистрирован, на него отправлена ссылка для входа",
+	"Sign in link is invalid or expired, request new one": "Ссылка для " +
+		"входа неверна или устарела, запросите новую",
+	"Address change must be approved by another admin": "Изменение адреса " +
+		"должен одобрить другой администратор",
+	"Cancel your own address change instead of rejecting it": "Отмените " +
+		"своё изменение адреса вместо отклонения",
+	"Only admin who requested address change can cancel it": "Отменить " +
+		"изменение адреса может только запросивший его администратор",
+	"Address change rejected":          "Изменение адреса отклонено",
+	"%s address \"%s\" is primary now": "Адрес %s \"%s\" теперь основной",
+	"Choose new primary address before removing primary one": "Выберите " +
+		"новый основной адрес перед удалением основного",
+	"%s address \"%s\" saved":       "Адрес %s \"%s\" сохранён",
+	"Failed to save address note":   "Не удалось сохранить заметку адреса",
+	"Address belongs to %s already": "Адрес уже принадлежит %s",
+	"Failed to add webhook":         "Не удалось добавить вебхук",
+	"Webhook removed":               "Вебхук удалён",
+	"Failed to remove webhook":      "Не удалось удалить вебхук",
+	"Delivery is delivered already": "Доставка уже выполнена",
+	"Failed to retry delivery":      "Не удалось повторить доставку",
+	"Delivery %d will be retried shortly": "Доставка %d скоро будет " +
+		"повторена",
+	"Worker \"%s\" added":          "Воркер \"%s\" добавлен",
+	"Worker \"%s\" saved":          "Воркер \"%s\" сохранён",
+	"Worker \"%s\" removed":        "Воркер \"%s\" удалён",
+	"Worker \"%s\" exists already": "Воркер \"%s\" уже существует",
+	"Failed to add worker":         "Не удалось добавить воркер",
+	"Failed to save worker":        "Не удалось сохранить воркер",
+	"Failed to remove worker":      "Не удалось удалить воркер",
+	"Address change cancelled":     "Изменение адреса отменено",
+	"Address change is not pending anymore": "Изменение адреса уже не " +
+		"ожидает решения",
+	"Failed to decide address change": "Не удалось принять решение по " +
+		"изменению адреса",
+	"Failed to apply address change": "Не удалось применить изменение " +
+		"адреса",
+	"Failed to restore item": "Не удалось восстановить элемент",
+	"Failed to delete item permanently": "Не удалось удалить элемент " +
+		"навсегда",
+	"Failed to add project":    "Не удалось добавить проект",
+	"Failed to rename project": "Не удалось переименовать проект",
+	"Failed to remove project": "Не удалось удалить проект",
+	"Failed to add user":       "Не удалось добавить пользователя",
+	"Failed to add address":    "Не удалось добавить адрес",
+	"Failed to remove address": "Не удалось удалить адрес",
+	"Failed to add admin":      "Не удалось добавить администратора",
+	"Failed to remove admin":   "Не удалось удалить администратора",
+	"Failed to reset admin password": "Не удалось сбросить пароль " +
+		"администратора",
+	"Failed to save settings": "Не удалось сохранить настройки",
+	"Alert about %s acknowledged": "Оповещение о %s отмечено " +
+		"просмотренным",
+	"Alert is resolved already":   "Оповещение уже закрыто",
+	"Failed to acknowledge alert": "Не удалось отметить оповещение",
+}
+
+var ruPlurals = map[string][]string{
+	"admin":   {"администратор", "администратора", "администраторов"},
+	"address": {"адрес", "адреса", "адресов"},
+	"user":    {"пользователь", "пользователя", "пользователей"},
+	"project": {"проект", "проекта", "проектов"},
+	"item":    {"элемент", "элемента", "элементов"},
+}
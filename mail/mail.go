@@ -0,0 +1,139 @@
+// Package mail sends notifications to miners and admins by email.
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is plain text email with optional attachments.
+type Message struct {
+	To          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Attachment is file attached to message.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Sender sends messages.
+type Sender interface {
+	Send(m Message) error
+}
+
+// format returns message in RFC 5322 format.
+func format(from string, m Message, date time.Time) []byte {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "From: %s\r\n", from)
+	fmt.Fprintf(buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(buf, "Subject: %s\r\n",
+		mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	body := strings.Replace(m.Body, "\r\n", "\n", -1)
+	body = strings.Replace(body, "\n", "\r\n", -1)
+
+	if len(m.Attachments) == 0 {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+		buf.WriteString("\r\n")
+		buf.WriteString(body)
+		return buf.Bytes()
+	}
+
+	// Writes to buffer do not fail, so multipart errors are not checked.
+	mw := multipart.NewWriter(buf)
+
+	fmt.Fprintf(buf, "Content-Type: multipart/mixed; boundary=%s\r\n",
+		mw.Boundary())
+	buf.WriteString("\r\n")
+
+	w, _ := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	io.WriteString(w, body)
+
+	for _, a := range m.Attachments {
+		w, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition": {mime.FormatMediaType("attachment",
+				map[string]string{"filename": a.Name})},
+		})
+
+		data := base64.StdEncoding.EncodeToString(a.Data)
+		for len(data) > 76 {
+			io.WriteString(w, data[:76]+"\r\n")
+			data = data[76:]
+		}
+		io.WriteString(w, data+"\r\n")
+	}
+
+	mw.Close()
+
+	return buf.Bytes()
+}
+
+// SMTPSender sends messages through SMTP server.
+type SMTPSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPSender returns sender which sends messages from address through
+// SMTP server at addr, plain authentication is used if username is set.
+func NewSMTPSender(addr string, from string, username string,
+	password string) SMTPSender {
+	s := SMTPSender{addr: addr, from: from}
+	if username != "" {
+		host := addr
+		if i := strings.LastIndex(addr, ":"); i != -1 {
+			host = addr[:i]
+		}
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+func (s SMTPSender) Send(m Message) error {
+	return smtp.SendMail(s.addr, s.auth, s.from, m.To,
+		format(s.from, m, time.Now()))
+}
+
+// LogSender writes messages to writer instead of sending them, it is meant
+// for development.
+type LogSender struct {
+	mu   *sync.Mutex
+	w    io.Writer
+	from string
+}
+
+func NewLogSender(w io.Writer, from string) LogSender {
+	return LogSender{mu: &sync.Mutex{}, w: w, from: from}
+}
+
+func (s LogSender) Send(m Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := fmt.Fprintf(s.w, "%s\r\n\r\n", format(s.from, m, time.Now()))
+	return err
+}
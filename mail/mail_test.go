@@ -0,0 +1,256 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// smtpStandIn is minimal SMTP server which accepts one message.
+type smtpStandIn struct {
+	ln       net.Listener
+	from     string
+	rcpts    []string
+	data     string
+	received chan struct{}
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &smtpStandIn{ln: ln, received: make(chan struct{})}
+	go s.serve()
+
+	return s
+}
+
+func (s *smtpStandIn) serve() {
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP stand-in")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch cmd {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250 localhost")
+		case "MAIL":
+			s.from = line
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			s.rcpts = append(s.rcpts, line)
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.data = string(data)
+			tp.PrintfLine("250 OK")
+			close(s.received)
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func Test_SMTPSender(t *testing.T) {
+	s := newSMTPStandIn(t)
+	defer s.ln.Close()
+
+	sender := NewSMTPSender(s.ln.Addr().String(), "admin@example.com", "",
+		"")
+
+	err := sender.Send(Message{
+		To:      []string{"miner@example.com"},
+		Subject: "Привет",
+		Body:    "line 1\nline 2\n",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	select {
+	case <-s.received:
+	case <-time.After(time.Second):
+		t.Fatal("message is not received")
+	}
+
+	assert.Equal(t, "MAIL FROM:<admin@example.com>", s.from)
+	assert.Equal(t, []string{"RCPT TO:<miner@example.com>"}, s.rcpts)
+	assert.Contains(t, s.data, "To: miner@example.com\n")
+	assert.Contains(t, s.data, "Subject: =?utf-8?q?")
+	assert.True(t, strings.HasSuffix(s.data, "\nline 1\nline 2\n"), s.data)
+}
+
+type recordingSender struct {
+	messages []Message
+}
+
+func (s *recordingSender) Send(m Message) error {
+	s.messages = append(s.messages, m)
+	return nil
+}
+
+func Test_Notifier(t *testing.T) {
+	s := &recordingSender{}
+	n := NewNotifier(s, []string{"root@example.com"})
+
+	assert.NoError(t, n.Welcome("miner@example.com", "Bob"))
+	assert.NoError(t, n.Welcome("", "Nobody"))
+	assert.NoError(t, n.AddressChanged("miner@example.com", "", "add", "ETH",
+		"0xabc", time.Date(2018, 7, 1, 12, 0, 0, 0, time.UTC)))
+	assert.NoError(t, n.AddressChanged("miner@example.com", "", "remove",
+		"BTC", "1xyz", time.Time{}))
+	assert.NoError(t, n.AdminCreated("alice", "bob"))
+	assert.NoError(t, n.AdminPasswordReset("alice", "bob"))
+	assert.NoError(t, n.AlertOpened("stale", "Alpha", "miner@example.com",
+		"ETH", "no growth since 2018-07-01 12:00 UTC"))
+
+	if !assert.Len(t, s.messages, 6) {
+		return
+	}
+
+	assert.Equal(t, []string{"miner@example.com"}, s.messages[0].To)
+	assert.Equal(t, "Welcome to Boomstarter mining", s.messages[0].Subject)
+	assert.True(t, strings.HasPrefix(s.messages[0].Body, "Hello, Bob!\n"))
+
+	assert.Equal(t, "Your ETH payout address added", s.messages[1].Subject)
+	assert.Contains(t, s.messages[1].Body, "ETH 0xabc")
+	assert.Contains(t, s.messages[1].Body, "2018-07-01 12:00 UTC")
+
+	assert.Equal(t, "Your BTC payout address removed",
+		s.messages[2].Subject)
+	assert.NotContains(t, s.messages[2].Body, "Payouts to it")
+
+	assert.Equal(t, []string{"root@example.com"}, s.messages[3].To)
+	assert.Equal(t, "Admin alice created", s.messages[3].Subject)
+	assert.Equal(t, "Password of admin alice reset", s.messages[4].Subject)
+
+	assert.Equal(t, []string{"root@example.com"}, s.messages[5].To)
+	assert.Equal(t, "Alert: miner@example.com ETH balance is not growing "+
+		"in Alpha", s.messages[5].Subject)
+	assert.Contains(t, s.messages[5].Body, "ETH: no growth since")
+
+	assert.NoError(t, n.AddressChanged("miner@example.com", "", "primary",
+		"ETH", "0xdef", time.Time{}))
+
+	if assert.Len(t, s.messages, 7) {
+		assert.Equal(t, "Your ETH payout address changed",
+			s.messages[6].Subject)
+		assert.Contains(t, s.messages[6].Body, "another address")
+	}
+}
+
+func Test_LogSender(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	err := NewLogSender(buf, "admin@example.com").Send(Message{
+		To:      []string{"miner@example.com"},
+		Subject: "Hi",
+		Body:    "text",
+	})
+	assert.NoError(t, err)
+
+	r := textproto.NewReader(bufio.NewReader(buf))
+	h, err := r.ReadMIMEHeader()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "admin@example.com", h.Get("From"))
+	assert.Equal(t, "Hi", h.Get("Subject"))
+}
+
+func Test_format_attachments(t *testing.T) {
+	data := format("admin@example.com", Message{
+		To:      []string{"finance@example.com"},
+		Subject: "Report",
+		Body:    "See attached.\n",
+		Attachments: []Attachment{{
+			Name:        "balances.csv",
+			ContentType: "text/csv",
+			Data:        []byte(strings.Repeat("a,b\n", 30)),
+		}},
+	}, time.Now())
+
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(data)))
+	h, err := r.ReadMIMEHeader()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	mr := multipart.NewReader(r.R, params["boundary"])
+
+	p, err := mr.NextPart()
+	if !assert.NoError(t, err) {
+		return
+	}
+	body, _ := ioutil.ReadAll(p)
+	assert.Equal(t, "See attached.\r\n", string(body))
+
+	p, err = mr.NextPart()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "balances.csv", p.FileName())
+	encoded, _ := ioutil.ReadAll(p)
+	decoded, err := base64.StdEncoding.DecodeString(
+		strings.Replace(string(encoded), "\r\n", "", -1))
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat("a,b\n", 30), string(decoded))
+}
+
+func Test_Notifier_Report(t *testing.T) {
+	s := &recordingSender{}
+	n := NewNotifier(s, []string{"root@example.com"})
+
+	at := time.Date(2018, 7, 2, 6, 0, 0, 0, time.UTC)
+	a := Attachment{Name: "weekly.csv", ContentType: "text/csv",
+		Data: []byte("a,b\n")}
+
+	assert.NoError(t, n.Report(nil, "weekly", at, a))
+	assert.NoError(t, n.Report([]string{"finance@example.com"}, "weekly",
+		at, a))
+
+	if !assert.Len(t, s.messages, 1) {
+		return
+	}
+
+	assert.Equal(t, []string{"finance@example.com"}, s.messages[0].To)
+	assert.Equal(t, "Report weekly for 2018-07-02", s.messages[0].Subject)
+	assert.Equal(t, []Attachment{a}, s.messages[0].Attachments)
+}
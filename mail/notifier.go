@@ -0,0 +1,211 @@
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Notification templates define "subject" and "body".
+var templates = map[string]string{
+	"welcome": `{{define "subject"}}Welcome to Boomstarter mining{{end}}
+{{define "body"}}Hello{{with .Name}}, {{.}}{{end}}!
+
+Your miner account {{.Email}} is created. Payout addresses will be added
+to it by administrators, you will be notified about every change.
+{{end}}`,
+
+	"address-changed": `{{define "subject"}}Your {{.Coin}} payout address ` +
+		`{{if eq .Action "add"}}added{{else if eq .Action "primary"}}` +
+		`changed{{else}}removed{{end}}{{end}}
+{{define "body"}}Hello{{with .Name}}, {{.}}{{end}}!
+
+{{if eq .Action "add"}}Payout address was added to your account:{{else if ` +
+		`eq .Action "primary"}}Payouts go to another address of your ` +
+		`account now:{{else}}Payout address was removed from your ` +
+		`account:{{end}}
+
+    {{.Coin}} {{.Address}}
+{{if not .EligibleAt.IsZero}}
+Payouts to it are possible from {{.EligibleAt.UTC.Format "2006-01-02 15:04 MST"}}.
+{{end}}
+If you did not ask for this change, contact us immediately.
+{{end}}`,
+
+	"magic-link": `{{define "subject"}}Sign in to Boomstarter mining{{end}}
+{{define "body"}}Hello{{with .Name}}, {{.}}{{end}}!
+
+Follow the link to sign in to your miner account:
+
+    {{.Link}}
+
+The link works once and expires in {{.TTL}}. If you did not try to sign
+in, ignore this email.
+{{end}}`,
+
+	"alert-opened": `{{define "subject"}}Alert: {{.Email}} {{.Coin}} ` +
+		`balance {{if eq .Kind "stale"}}is not growing{{else}}growth is ` +
+		`unusual{{end}} in {{.Project}}{{end}}
+{{define "body"}}Balance of miner {{.Email}} in project {{.Project}} needs
+attention:
+
+    {{.Coin}}: {{.Details}}
+
+See alerts page for all open alerts.
+{{end}}`,
+
+	"report": `{{define "subject"}}Report {{.Name}} for ` +
+		`{{.At.UTC.Format "2006-01-02"}}{{end}}
+{{define "body"}}Report {{.Name}} generated at ` +
+		`{{.At.UTC.Format "2006-01-02 15:04 MST"}} is attached.
+{{end}}`,
+
+	"admin-created": `{{define "subject"}}Admin {{.Login}} created{{end}}
+{{define "body"}}Admin {{.Login}} was created by {{.By}}.
+{{end}}`,
+
+	"admin-password-reset": `{{define "subject"}}Password of admin ` +
+		`{{.Login}} reset{{end}}
+{{define "body"}}Password of admin {{.Login}} was reset by {{.By}}.
+{{end}}`,
+}
+
+var parsed = map[string]*template.Template{}
+
+func init() {
+	for name, text := range templates {
+		parsed[name] = template.Must(template.New(name).Parse(text))
+	}
+}
+
+// render returns message with subject and body from notification template.
+func render(name string, to []string, data interface{}) (Message, error) {
+	t, ok := parsed[name]
+	if !ok {
+		return Message{}, errors.New("unknown notification " + name)
+	}
+
+	subject := &bytes.Buffer{}
+	err := t.ExecuteTemplate(subject, "subject", data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	body := &bytes.Buffer{}
+	err = t.ExecuteTemplate(body, "body", data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Body:    strings.TrimLeft(body.String(), "\n"),
+	}, nil
+}
+
+// Notifier sends templated notifications to miners and admins.
+type Notifier struct {
+	sender Sender
+	// adminEmails receive admin alerts.
+	adminEmails []string
+}
+
+func NewNotifier(s Sender, adminEmails []string) Notifier {
+	return Notifier{sender: s, adminEmails: adminEmails}
+}
+
+func (n Notifier) send(name string, to []string, data interface{}) error {
+	if len(to) == 0 {
+		return nil
+	}
+
+	m, err := render(name, to, data)
+	if err != nil {
+		return err
+	}
+
+	return n.sender.Send(m)
+}
+
+// recipient returns email as recipients list, miners without email get
+// nothing.
+func recipient(email string) []string {
+	if email == "" {
+		return nil
+	}
+	return []string{email}
+}
+
+// Welcome sends welcome email to new miner.
+func (n Notifier) Welcome(email string, name string) error {
+	return n.send("welcome", recipient(email), struct {
+		Email, Name string
+	}{email, name})
+}
+
+// AddressChanged alerts miner that payout address was added or removed,
+// action is mastore address change action. eligibleAt can be zero.
+func (n Notifier) AddressChanged(email string, name string, action string,
+	coin string, address string, eligibleAt time.Time) error {
+	return n.send("address-changed", recipient(email), struct {
+		Email, Name, Action, Coin, Address string
+		EligibleAt                         time.Time
+	}{email, name, action, coin, address, eligibleAt})
+}
+
+// MagicLink sends miner one-time portal login link which expires after ttl.
+func (n Notifier) MagicLink(email string, name string, link string,
+	ttl time.Duration) error {
+	return n.send("magic-link", recipient(email), struct {
+		Name, Link string
+		TTL        time.Duration
+	}{name, link, ttl})
+}
+
+// AlertOpened alerts admins that balance alert of kind was opened about
+// miner with email in project.
+func (n Notifier) AlertOpened(kind string, project string, email string,
+	coin string, details string) error {
+	return n.send("alert-opened", n.adminEmails, struct {
+		Kind, Project, Email, Coin, Details string
+	}{kind, project, email, coin, details})
+}
+
+// Report sends report generated at time as attachment, recipients are
+// given by report schedule.
+func (n Notifier) Report(to []string, name string, at time.Time,
+	a Attachment) error {
+	if len(to) == 0 {
+		return nil
+	}
+
+	m, err := render("report", to, struct {
+		Name string
+		At   time.Time
+	}{name, at})
+	if err != nil {
+		return err
+	}
+
+	m.Attachments = []Attachment{a}
+
+	return n.sender.Send(m)
+}
+
+// AdminCreated alerts admins that admin with login was created by another
+// one.
+func (n Notifier) AdminCreated(login string, by string) error {
+	return n.send("admin-created", n.adminEmails, struct {
+		Login, By string
+	}{login, by})
+}
+
+// AdminPasswordReset alerts admins that admin password was reset.
+func (n Notifier) AdminPasswordReset(login string, by string) error {
+	return n.send("admin-password-reset", n.adminEmails, struct {
+		Login, By string
+	}{login, by})
+}
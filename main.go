@@ -1,14 +1,28 @@
 package main
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/alert"
+	"github.com/boomstarternetwork/mineradmin/balance"
+	"github.com/boomstarternetwork/mineradmin/cache"
 	"github.com/boomstarternetwork/mineradmin/handler"
+	"github.com/boomstarternetwork/mineradmin/mail"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/migration"
+	"github.com/boomstarternetwork/mineradmin/replica"
+	"github.com/boomstarternetwork/mineradmin/report"
+	"github.com/boomstarternetwork/mineradmin/trash"
+	"github.com/boomstarternetwork/mineradmin/webhook"
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
 	"github.com/labstack/gommon/log"
@@ -21,6 +35,9 @@ var (
 	jwtAuthError = errors.New("invalid or expired jwt")
 )
 
+// statsPath is where pool posts worker reports.
+const statsPath = "/api/stats"
+
 func main() {
 	app := cli.NewApp()
 	app.Name = "mineradmin"
@@ -40,6 +57,43 @@ func main() {
 			Name:  "postgres-cs",
 			Usage: "postgres connection string",
 		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name: "postgres-replica-cs",
+			Usage: "postgres read replica connection string, projects and " +
+				"users balances are read from replica if it is set",
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name: "replica-max-lag",
+			Usage: "how far read replica may be behind before balances " +
+				"are read from primary",
+			Value: 30 * time.Second,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name:  "replica-check-interval",
+			Usage: "how often read replica lag is checked",
+			Value: 10 * time.Second,
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name: "pool-name",
+			Usage: "name of pool in postgres-cs database, it keeps admin " +
+				"accounts and is served by miner portal and stats API",
+			Value: handler.DefaultPool,
+		}),
+		altsrc.NewStringSliceFlag(cli.StringSliceFlag{
+			Name: "pools",
+			Usage: "other pools managed along with postgres-cs one in " +
+				"name=postgres connection string form",
+		}),
+		altsrc.NewStringSliceFlag(cli.StringSliceFlag{
+			Name: "pool-replicas",
+			Usage: "read replicas of other pools in name=postgres " +
+				"connection string form",
+		}),
+		altsrc.NewStringSliceFlag(cli.StringSliceFlag{
+			Name: "pool-roles",
+			Usage: "admin roles in pools in pool:login=role form, role is " +
+				"admin or viewer, every admin is admin of pool without roles",
+		}),
 		altsrc.NewStringFlag(cli.StringFlag{
 			Name:  "bind-addr",
 			Usage: "web server bind address",
@@ -54,6 +108,156 @@ func main() {
 			Usage: "run mode: production or development",
 			Value: "production",
 		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name: "templates-dir",
+			Usage: "directory to load templates from instead of " +
+				"built-in ones, development run mode uses ./templates " +
+				"by default",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name: "prices-file",
+			Usage: "YAML file with coin prices in fiat currency to value " +
+				"balances",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name: "prices-url",
+			Usage: "URL of JSON document with coin prices in fiat currency " +
+				"to value balances",
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name:  "prices-ttl",
+			Usage: "how long prices fetched from prices URL are cached",
+			Value: 5 * time.Minute,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name: "trash-retention",
+			Usage: "how long removed projects, admins and addresses " +
+				"are kept in trash",
+			Value: 30 * 24 * time.Hour,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name: "address-cooldown",
+			Usage: "how long approved new payout address is not eligible " +
+				"for payouts",
+		}),
+		altsrc.NewBoolFlag(cli.BoolFlag{
+			Name: "block-duplicate-addresses",
+			Usage: "forbid adding address which belongs to another user " +
+				"instead of warning",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "smtp-addr",
+			Usage: "SMTP server host:port notifications are sent through",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "smtp-username",
+			Usage: "SMTP server username, no authentication if empty",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "smtp-password",
+			Usage: "SMTP server password",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "mail-from",
+			Usage: "notifications sender address",
+			Value: "mineradmin@localhost",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name: "mail-file",
+			Usage: "file notifications are written to instead of sending " +
+				"for development, - for stdout",
+		}),
+		altsrc.NewStringSliceFlag(cli.StringSliceFlag{
+			Name:  "admin-emails",
+			Usage: "addresses admin alerts are sent to",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name: "portal-jwt-secret",
+			Usage: "JWT secret of miner portal, it must differ from JWT " +
+				"secret, portal is disabled if empty",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name: "portal-url",
+			Usage: "miner portal URL used in login links, request host " +
+				"is used if empty",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name: "stats-token",
+			Usage: "token pool posts worker reports to /api/stats with, " +
+				"reports are not accepted if empty",
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name:  "stats-retention",
+			Usage: "how long worker stats buckets are kept",
+			Value: 30 * 24 * time.Hour,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name:  "cache-projects-ttl",
+			Usage: "how long projects are cached, 0 disables caching",
+			Value: 5 * time.Minute,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name:  "cache-users-ttl",
+			Usage: "how long users list is cached, 0 disables caching",
+			Value: time.Minute,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name: "cache-balances-ttl",
+			Usage: "how long project and user balances are cached, 0 " +
+				"disables caching",
+			Value: time.Minute,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name: "alert-stale-period",
+			Usage: "how long user balance in project may not grow before " +
+				"alert, 0 disables stale alerts",
+			Value: 24 * time.Hour,
+		}),
+		altsrc.NewFloat64Flag(cli.Float64Flag{
+			Name: "alert-deviation-factor",
+			Usage: "how many times recent balance growth may be above or " +
+				"below usual before alert, 0 disables anomaly alerts",
+			Value: 3,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name:  "alert-window",
+			Usage: "period recent balance growth is computed over",
+			Value: 6 * time.Hour,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name: "alert-baseline",
+			Usage: "period before alert window usual balance growth is " +
+				"computed over",
+			Value: 7 * 24 * time.Hour,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name: "alert-check-interval",
+			Usage: "how often balances are snapshotted and checked for " +
+				"alerts",
+			Value: time.Hour,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name: "request-timeout",
+			Usage: "how long request may take before 503 is responded, " +
+				"0 disables timeouts",
+			Value: 30 * time.Second,
+		}),
+		altsrc.NewStringSliceFlag(cli.StringSliceFlag{
+			Name: "route-timeouts",
+			Usage: "timeouts of routes which differ from request timeout " +
+				"in path=duration form, e.g. /payouts.csv=2m",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name: "reports-file",
+			Usage: "YAML file of scheduled balances reports, reports are " +
+				"not generated if empty",
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name: "report-retention",
+			Usage: "how long generated reports are kept, 0 keeps them " +
+				"forever",
+			Value: 90 * 24 * time.Hour,
+		}),
 		altsrc.NewStringFlag(cli.StringFlag{
 			Name:  "log-level",
 			Usage: "log level: debug, info, warn, error, off",
@@ -91,11 +295,16 @@ func main() {
 				},
 			},
 		},
+		migrateCommand,
+		projectsCommand,
+		usersCommand,
+		addressesCommand,
+		statsCommand,
 	}
 
 	err := app.Run(os.Args)
 	if err != nil {
-		fmt.Errorf("Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
@@ -106,25 +315,538 @@ func webServer(c *cli.Context) error {
 	jwtSecret := c.String("jwt-secret")
 	runMode := c.String("run-mode")
 	logLevel := c.String("log-level")
+	templatesDir := c.String("templates-dir")
 
-	s, err := bestore.NewDBStore(connStr, runMode)
+	pools, err := openPools(c, connStr)
 	if err != nil {
-		return cli.NewExitError("failed to create new DB store: "+
-			err.Error(), 1)
+		return err
 	}
 
-	e, err := initWebServer(s, jwtSecret, runMode, logLevel)
+	roles, err := handler.ParseRoles(c.StringSlice("pool-roles"))
+	if err != nil {
+		return cli.NewExitError("failed to init pool roles: "+err.Error(), 2)
+	}
+
+	if c.Duration("replica-check-interval") <= 0 {
+		return cli.NewExitError("replica check interval must be positive",
+			2)
+	}
+
+	for name := range roles {
+		if !hasPool(pools, name) {
+			return cli.NewExitError("unknown pool "+name+" in pool roles", 2)
+		}
+	}
+
+	primary := pools[0]
+
+	ps, err := newPriceSource(c)
+	if err != nil {
+		return cli.NewExitError("failed to init price source: "+
+			err.Error(), 2)
+	}
+
+	n, err := newNotifier(c)
+	if err != nil {
+		return cli.NewExitError("failed to init notifications: "+
+			err.Error(), 2)
+	}
+
+	portalJWTSecret := c.String("portal-jwt-secret")
+
+	if portalJWTSecret != "" {
+		if portalJWTSecret == jwtSecret {
+			return cli.NewExitError("portal JWT secret must differ from "+
+				"JWT secret", 2)
+		}
+		if n == nil {
+			return cli.NewExitError("miner portal requires notifications "+
+				"to send login links", 2)
+		}
+	}
+
+	alerts, err := alertConfig(c)
+	if err != nil {
+		return cli.NewExitError("failed to init alerts: "+err.Error(), 2)
+	}
+
+	timeouts, err := timeoutsConfig(c)
+	if err != nil {
+		return cli.NewExitError("failed to init timeouts: "+err.Error(), 2)
+	}
+
+	jobs, err := reportJobs(c, pools, n)
+	if err != nil {
+		return cli.NewExitError("failed to init reports: "+err.Error(), 2)
+	}
+
+	var others []handler.Pool
+	for _, p := range pools[1:] {
+		others = append(others, p.Pool)
+	}
+
+	e, err := initWebServer(handler.Config{
+		Store:                   primary.Store,
+		MStore:                  primary.MStore,
+		PoolName:                primary.Name,
+		Pools:                   others,
+		Roles:                   roles,
+		Prices:                  ps,
+		JWTSecret:               jwtSecret,
+		AddressCooldown:         c.Duration("address-cooldown"),
+		BlockDuplicateAddresses: c.Bool("block-duplicate-addresses"),
+		Notifier:                n,
+		Events:                  primary.Events,
+		PortalJWTSecret:         portalJWTSecret,
+		PortalURL:               c.String("portal-url"),
+		StatsToken:              c.String("stats-token"),
+		Timeouts:                timeouts,
+	}, runMode, logLevel, templatesDir)
 	if err != nil {
 		return cli.NewExitError("failed to init web server: "+
 			err.Error(), 2)
 	}
 
+	for _, p := range pools {
+		go purgeTrash(p.Name, trash.NewBin(p.Store, p.MStore),
+			c.Duration("trash-retention"), e.Logger)
+
+		go purgeStats(p.Name, p.MStore, c.Duration("stats-retention"),
+			e.Logger)
+
+		// Snapshots are taken of fresh balances, not cached ones, replica
+		// lag is bounded by max lag.
+		go checkAlerts(p.Name, alert.NewChecker(p.dbStore, p.MStore, alerts,
+			n, p.Events, e.Logger), c.Duration("alert-check-interval"),
+			e.Logger)
+
+		go deliverWebhooks(p.Name, webhook.NewDeliverer(p.MStore,
+			&http.Client{Timeout: webhookTimeout}), e.Logger)
+
+		if p.replica != nil {
+			go watchReplica(p.Name, p.replica,
+				c.Duration("replica-check-interval"), e.Logger)
+		}
+
+		if c.Duration("report-retention") > 0 {
+			go purgeReports(p.Name, p.MStore, c.Duration("report-retention"),
+				e.Logger)
+		}
+
+		// Reports are generated of fresh balances, not cached ones.
+		r := report.NewRunner(p.dbStore, p.MStore, n)
+		for _, j := range jobs {
+			if j.Pool == p.Name || (j.Pool == "" && p.Name == primary.Name) {
+				go scheduleReport(p.Name, j, r, e.Logger)
+			}
+		}
+	}
+
 	err = e.Start(bindAddr)
 
 	return cli.NewExitError("failed to start echo server: "+
 		err.Error(), 3)
 }
 
+// dbPool is pool which database is opened by web server.
+type dbPool struct {
+	handler.Pool
+	// dbStore is pool bestore without cache, it reads balances from
+	// replica if pool has one.
+	dbStore bestore.Store
+	// replica is nil if pool has no read replica.
+	replica *replica.Store
+}
+
+// splitPoolSpec splits spec in name=postgres connection string form.
+func splitPoolSpec(spec string) (string, string, error) {
+	i := strings.Index(spec, "=")
+	if i <= 0 {
+		return "", "", errors.New("invalid pool " + spec + ": expected " +
+			"name=postgres connection string")
+	}
+	return spec[:i], spec[i+1:], nil
+}
+
+// openPools opens primary pool database and databases of pools flag.
+func openPools(c *cli.Context, connStr string) ([]dbPool, error) {
+	replicas := map[string]string{}
+
+	for _, spec := range c.StringSlice("pool-replicas") {
+		name, cs, err := splitPoolSpec(spec)
+		if err != nil {
+			return nil, cli.NewExitError(err.Error(), 2)
+		}
+		replicas[name] = cs
+	}
+
+	primary, err := openPool(c, c.String("pool-name"), connStr,
+		c.String("postgres-replica-cs"))
+	if err != nil {
+		return nil, err
+	}
+
+	pools := []dbPool{primary}
+
+	for _, spec := range c.StringSlice("pools") {
+		name, cs, err := splitPoolSpec(spec)
+		if err != nil {
+			return nil, cli.NewExitError(err.Error(), 2)
+		}
+
+		if hasPool(pools, name) {
+			return nil, cli.NewExitError("duplicate pool "+name, 2)
+		}
+
+		p, err := openPool(c, name, cs, replicas[name])
+		if err != nil {
+			return nil, err
+		}
+
+		pools = append(pools, p)
+	}
+
+	for name := range replicas {
+		if name == primary.Name || !hasPool(pools, name) {
+			return nil, cli.NewExitError("unknown pool "+name+
+				" in pool replicas", 2)
+		}
+	}
+
+	return pools, nil
+}
+
+// openPool opens pool database and checks its schema, pool bestore is
+// cached. Balances are read from replica if replicaConnStr is not empty.
+func openPool(c *cli.Context, name string, connStr string,
+	replicaConnStr string) (dbPool, error) {
+	var (
+		dbs bestore.Store
+		rs  *replica.Store
+	)
+
+	dbs, err := bestore.NewDBStore(connStr, c.String("run-mode"))
+	if err != nil {
+		return dbPool{}, cli.NewExitError("failed to create new pool "+name+
+			" DB store: "+err.Error(), 1)
+	}
+
+	if replicaConnStr != "" {
+		replicaStore, err := bestore.NewDBStore(replicaConnStr,
+			c.String("run-mode"))
+		if err != nil {
+			return dbPool{}, cli.NewExitError("failed to create new pool "+
+				name+" replica DB store: "+err.Error(), 1)
+		}
+
+		replicaDB, err := sql.Open("postgres", replicaConnStr)
+		if err != nil {
+			return dbPool{}, cli.NewExitError("failed to open pool "+name+
+				" replica DB: "+err.Error(), 4)
+		}
+
+		rs = replica.NewStore(dbs, replicaStore, replica.DBLag(replicaDB),
+			c.Duration("replica-max-lag"))
+		dbs = rs
+	}
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return dbPool{}, cli.NewExitError("failed to open pool "+name+
+			" DB: "+err.Error(), 4)
+	}
+
+	err = migration.Check(db)
+	if err != nil {
+		return dbPool{}, cli.NewExitError("failed to check pool "+name+
+			" DB schema: "+err.Error(), 4)
+	}
+
+	ms := mastore.NewDBStore(db)
+
+	return dbPool{
+		Pool: handler.Pool{
+			Name: name,
+			Store: cache.NewStore(dbs, cache.TTLs{
+				Projects: c.Duration("cache-projects-ttl"),
+				Users:    c.Duration("cache-users-ttl"),
+				Balances: c.Duration("cache-balances-ttl"),
+			}),
+			MStore: ms,
+			Events: webhook.NewQueue(ms),
+		},
+		dbStore: dbs,
+		replica: rs,
+	}, nil
+}
+
+func hasPool(pools []dbPool, name string) bool {
+	for _, p := range pools {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// newPriceSource creates price source from prices flags, nil is returned if
+// prices are not configured.
+func newPriceSource(c *cli.Context) (balance.PriceSource, error) {
+	file := c.String("prices-file")
+	pricesURL := c.String("prices-url")
+
+	switch {
+	case file != "" && pricesURL != "":
+		return nil, errors.New("prices file and prices URL are mutually " +
+			"exclusive")
+	case file != "":
+		return balance.LoadStaticPriceSource(file)
+	case pricesURL != "":
+		return balance.NewHTTPPriceSource(pricesURL,
+			c.Duration("prices-ttl")), nil
+	}
+
+	return nil, nil
+}
+
+// newNotifier returns notifier sending through SMTP server or writing to
+// file, nil is returned if neither is configured.
+func newNotifier(c *cli.Context) (*mail.Notifier, error) {
+	addr := c.String("smtp-addr")
+	file := c.String("mail-file")
+	from := c.String("mail-from")
+
+	var sender mail.Sender
+
+	switch {
+	case addr != "" && file != "":
+		return nil, errors.New("SMTP server and mail file are mutually " +
+			"exclusive")
+	case addr != "":
+		sender = mail.NewSMTPSender(addr, from, c.String("smtp-username"),
+			c.String("smtp-password"))
+	case file == "-":
+		sender = mail.NewLogSender(os.Stdout, from)
+	case file != "":
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY,
+			0600)
+		if err != nil {
+			return nil, err
+		}
+		sender = mail.NewLogSender(f, from)
+	default:
+		return nil, nil
+	}
+
+	n := mail.NewNotifier(sender, c.StringSlice("admin-emails"))
+
+	return &n, nil
+}
+
+// timeoutsConfig returns request timeouts from timeout flags.
+func timeoutsConfig(c *cli.Context) (handler.Timeouts, error) {
+	if c.Duration("request-timeout") < 0 {
+		return handler.Timeouts{}, errors.New("negative request timeout")
+	}
+
+	routes, err := handler.ParseRouteTimeouts(c.StringSlice("route-timeouts"))
+	if err != nil {
+		return handler.Timeouts{}, err
+	}
+
+	return handler.Timeouts{
+		Default: c.Duration("request-timeout"),
+		Routes:  routes,
+	}, nil
+}
+
+// alertConfig returns alert thresholds from alert flags.
+func alertConfig(c *cli.Context) (alert.Config, error) {
+	conf := alert.Config{
+		StalePeriod: c.Duration("alert-stale-period"),
+		Factor:      c.Float64("alert-deviation-factor"),
+		Window:      c.Duration("alert-window"),
+		Baseline:    c.Duration("alert-baseline"),
+	}
+
+	switch {
+	case conf.StalePeriod < 0:
+		return alert.Config{}, errors.New("negative alert stale period")
+	case conf.Factor != 0 && conf.Factor <= 1:
+		return alert.Config{}, errors.New("alert deviation factor must be " +
+			"greater than 1")
+	case conf.Factor != 0 && (conf.Window <= 0 ||
+		conf.Baseline < 2*conf.Window):
+		return alert.Config{}, errors.New("alert baseline must be at " +
+			"least two alert windows")
+	case c.Duration("alert-check-interval") <= 0:
+		return alert.Config{}, errors.New("alert check interval must be " +
+			"positive")
+	}
+
+	return conf, nil
+}
+
+// reportJobs loads scheduled reports from reports file, nil is returned if
+// reports are not configured.
+func reportJobs(c *cli.Context, pools []dbPool, n *mail.Notifier) (
+	[]report.Job, error) {
+	file := c.String("reports-file")
+	if file == "" {
+		return nil, nil
+	}
+
+	jobs, err := report.LoadJobs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, j := range jobs {
+		if j.Pool != "" && !hasPool(pools, j.Pool) {
+			return nil, errors.New("unknown pool " + j.Pool + " in report " +
+				j.Name)
+		}
+		if len(j.Emails) > 0 && n == nil {
+			return nil, errors.New("report " + j.Name + " requires " +
+				"notifications to be emailed")
+		}
+	}
+
+	return jobs, nil
+}
+
+// scheduleReport generates job report at its scheduled times.
+func scheduleReport(pool string, j report.Job, r report.Runner,
+	logger echo.Logger) {
+	for {
+		next := j.Next(time.Now())
+		if next.IsZero() {
+			logger.Warn("pool " + pool + " report " + j.Name +
+				" is never scheduled")
+			return
+		}
+
+		time.Sleep(time.Until(next))
+
+		id, err := r.Run(j, next)
+		if err != nil {
+			logger.Error("failed to generate pool " + pool + " report " +
+				j.Name + ": " + err.Error())
+		}
+		if id > 0 {
+			logger.Infof("generated pool %s report %s #%d", pool, j.Name, id)
+		}
+	}
+}
+
+// purgeReports removes reports older than retention periodically.
+func purgeReports(pool string, ms mastore.Store, retention time.Duration,
+	logger echo.Logger) {
+	for {
+		err := ms.RemoveReports(time.Now().Add(-retention))
+		if err != nil {
+			logger.Error("failed to purge pool " + pool + " reports: " +
+				err.Error())
+		}
+		time.Sleep(trashPurgeInterval)
+	}
+}
+
+// checkAlerts checks balances for alerts periodically.
+func checkAlerts(pool string, ch alert.Checker, interval time.Duration,
+	logger echo.Logger) {
+	for {
+		opened, resolved, err := ch.Check(time.Now())
+		if err != nil {
+			logger.Error("failed to check pool " + pool + " alerts: " +
+				err.Error())
+		}
+		if opened > 0 || resolved > 0 {
+			logger.Infof("opened %d and resolved %d pool %s alerts", opened,
+				resolved, pool)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// watchReplica checks pool read replica lag periodically, so balances are
+// read from primary while replica lags.
+func watchReplica(pool string, rs *replica.Store, interval time.Duration,
+	logger echo.Logger) {
+	healthy := true
+	for {
+		lag, err := rs.Check()
+		switch {
+		case err != nil:
+			logger.Error("failed to check pool " + pool + " replica lag: " +
+				err.Error())
+		case healthy && !rs.Healthy():
+			logger.Warnf("pool %s replica lags %s, reading from primary",
+				pool, lag)
+		case !healthy && rs.Healthy():
+			logger.Infof("pool %s replica caught up, reading from it", pool)
+		}
+		healthy = rs.Healthy()
+		time.Sleep(interval)
+	}
+}
+
+// trashPurgeInterval is how often expired trash items are purged.
+const trashPurgeInterval = time.Hour
+
+// purgeTrash purges trash items older than retention periodically.
+func purgeTrash(pool string, bin trash.Bin, retention time.Duration,
+	logger echo.Logger) {
+	for {
+		n, err := bin.PurgeExpired(retention)
+		if err != nil {
+			logger.Error("failed to purge pool " + pool + " trash: " +
+				err.Error())
+		}
+		if n > 0 {
+			logger.Infof("purged %d pool %s trash items", n, pool)
+		}
+		time.Sleep(trashPurgeInterval)
+	}
+}
+
+// purgeStats removes worker stats buckets older than retention
+// periodically.
+func purgeStats(pool string, ms mastore.Store, retention time.Duration,
+	logger echo.Logger) {
+	for {
+		err := ms.RemoveWorkerStats(time.Now().Add(-retention))
+		if err != nil {
+			logger.Error("failed to purge pool " + pool + " worker stats: " +
+				err.Error())
+		}
+		time.Sleep(trashPurgeInterval)
+	}
+}
+
+const (
+	// webhookDeliveryInterval is how often due webhook deliveries are
+	// attempted.
+	webhookDeliveryInterval = 10 * time.Second
+	// webhookTimeout is how long webhook endpoint may respond.
+	webhookTimeout = 10 * time.Second
+)
+
+// deliverWebhooks attempts due webhook deliveries periodically.
+func deliverWebhooks(pool string, d webhook.Deliverer, logger echo.Logger) {
+	for {
+		n, err := d.DeliverDue()
+		if err != nil {
+			logger.Error("failed to deliver pool " + pool + " webhooks: " +
+				err.Error())
+		}
+		if n > 0 {
+			logger.Debugf("attempted %d pool %s webhook deliveries", n, pool)
+		}
+		time.Sleep(webhookDeliveryInterval)
+	}
+}
+
 func addAdmin(c *cli.Context) error {
 	connStr := c.String("postgres-cs")
 	login := c.String("login")
@@ -149,14 +871,52 @@ func addAdmin(c *cli.Context) error {
 	return nil
 }
 
-func initWebServer(s bestore.Store, jwtSecret string,
-	runMode string, logLevel string) (*echo.Echo, error) {
+// initPortal registers miner portal routes. Portal tokens are signed with
+// own secret and kept in own cookie, so they are never mistaken for admin
+// ones.
+func initPortal(e *echo.Echo, h handler.Handler, secret string) {
+	e.GET("/portal/login", h.PortalLogin)
+	e.POST("/portal/login", h.PortalLogin)
+	e.GET("/portal/login/:token", h.PortalMagicLogin)
+	e.POST("/portal/login/:token", h.PortalMagicLogin)
+
+	withJWT := middleware.JWTWithConfig(middleware.JWTConfig{
+		ErrorHandler: func(e error) error {
+			return jwtAuthError
+		},
+		SigningKey:    []byte(secret),
+		SigningMethod: middleware.AlgorithmHS256,
+		ContextKey:    handler.PortalContextKey,
+		TokenLookup:   "cookie:" + handler.PortalCookie,
+	})
+
+	withAuth := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return withJWT(func(c echo.Context) error {
+			err := next(c)
+			if err == jwtAuthError {
+				c.Redirect(http.StatusFound, "/portal/login")
+			}
+			return err
+		})
+	}
+
+	e.GET("/portal", withAuth(h.Portal))
+	e.GET("/portal/logout", withAuth(h.PortalLogout))
+	e.POST("/portal/addresses", withAuth(h.PortalEditAddresses))
+}
+
+func initWebServer(conf handler.Config, runMode string, logLevel string,
+	templatesDir string) (*echo.Echo, error) {
 	e := echo.New()
 
 	e.Use(middleware.RemoveTrailingSlashWithConfig(middleware.TrailingSlashConfig{
 		RedirectCode: http.StatusMovedPermanently,
 	}))
 	e.Use(middleware.CSRFWithConfig(middleware.CSRFConfig{
+		// Pool posts stats with bearer token, not from browser.
+		Skipper: func(c echo.Context) bool {
+			return c.Request().URL.Path == statsPath
+		},
 		TokenLookup:  "form:csrf-token",
 		ContextKey:   "csrf-token",
 		CookieSecure: true,
@@ -167,7 +927,12 @@ func initWebServer(s bestore.Store, jwtSecret string,
 	switch runMode {
 	case "production":
 		e.Use(middleware.Recover())
-		e.Renderer, err = handler.NewProdTemplateRenderer("./templates")
+		var fsys fs.FS
+		fsys, err = templatesFS(templatesDir)
+		if err != nil {
+			return nil, errors.New("failed to open templates: " + err.Error())
+		}
+		e.Renderer, err = handler.NewProdTemplateRenderer(fsys)
 		if err != nil {
 			return nil, errors.New("failed to create production template " +
 				"renderer: " + err.Error())
@@ -175,7 +940,10 @@ func initWebServer(s bestore.Store, jwtSecret string,
 	case "development":
 		e.Use(middleware.Recover())
 		e.Debug = true
-		e.Renderer = handler.NewDevTemplateRenderer("./templates")
+		if templatesDir == "" {
+			templatesDir = "./templates"
+		}
+		e.Renderer = handler.NewDevTemplateRenderer(templatesDir)
 	case "testing":
 	default:
 		return nil, errors.New("invalid run mode")
@@ -200,7 +968,12 @@ func initWebServer(s bestore.Store, jwtSecret string,
 		return nil, errors.New("invalid log level")
 	}
 
-	h := handler.NewHandler(s, jwtSecret)
+	h := handler.NewHandler(conf)
+
+	e.Use(h.Locale)
+	e.Use(h.Flashes)
+	e.Use(h.PendingChanges)
+	e.Use(h.Timeout)
 
 	e.GET("/login", h.Login)
 	e.POST("/login", h.Login)
@@ -209,7 +982,7 @@ func initWebServer(s bestore.Store, jwtSecret string,
 		ErrorHandler: func(e error) error {
 			return jwtAuthError
 		},
-		SigningKey:    []byte(jwtSecret),
+		SigningKey:    []byte(conf.JWTSecret),
 		SigningMethod: middleware.AlgorithmHS256,
 		ContextKey:    "login",
 		TokenLookup:   "cookie:auth",
@@ -217,7 +990,7 @@ func initWebServer(s bestore.Store, jwtSecret string,
 
 	withAuth := func(next echo.HandlerFunc) echo.HandlerFunc {
 		return withJWT(func(c echo.Context) error {
-			err := next(c)
+			err := h.PoolAccess(next)(c)
 			if err == jwtAuthError {
 				redirectPath := "/login"
 				path := c.Request().URL.Path + "?" + c.Request().URL.RawQuery
@@ -234,6 +1007,17 @@ func initWebServer(s bestore.Store, jwtSecret string,
 
 	e.GET("/logout", withAuth(h.Logout))
 
+	e.GET("/address-changes", withAuth(h.AddressChanges))
+	e.POST("/address-changes/:change-id", withAuth(h.EditAddressChange))
+
+	e.GET("/trash/:kind", withAuth(h.Trash))
+	e.POST("/trash/:kind/:item-id", withAuth(h.EditTrash))
+
+	e.GET("/settings", withAuth(h.Settings))
+	e.POST("/settings", withAuth(h.EditSettings))
+
+	e.POST("/pool", withAuth(h.SelectPool))
+
 	e.GET("/projects", withAuth(h.Projects))
 	e.GET("/projects/:project-id/edit", withAuth(h.ProjectEdit))
 	e.GET("/projects/:project-id/users", withAuth(h.ProjectUsers))
@@ -248,6 +1032,39 @@ func initWebServer(s bestore.Store, jwtSecret string,
 	e.POST("/users", withAuth(h.NewUser))
 	e.GET("/users/:user-id/addresses", withAuth(h.UserAddresses))
 	e.POST("/users/:user-id/addresses", withAuth(h.EditUserAddresses))
+	e.GET("/users/:user-id/workers", withAuth(h.UserWorkers))
+	e.POST("/users/:user-id/workers", withAuth(h.NewUserWorker))
+	e.POST("/users/:user-id/workers/:worker-id", withAuth(h.EditUserWorker))
+
+	e.GET("/addresses", withAuth(h.AddressLookup))
+	e.GET("/addresses/duplicates", withAuth(h.AddressDuplicates))
+
+	e.GET("/payouts.csv", withAuth(h.PayoutsExport))
+	e.GET("/workers.csv", withAuth(h.WorkersExport))
+
+	e.GET("/alerts", withAuth(h.Alerts))
+	e.POST("/alerts/:alert-id", withAuth(h.EditAlert))
+
+	e.GET("/reports", withAuth(h.Reports))
+	e.GET("/reports/:report-id", withAuth(h.DownloadReport))
+
+	e.GET("/webhooks", withAuth(h.Webhooks))
+	e.POST("/webhooks", withAuth(h.NewWebhook))
+	e.POST("/webhooks/:webhook-id", withAuth(h.EditWebhook))
+	e.GET("/webhooks/deliveries", withAuth(h.WebhookDeliveries))
+	e.POST("/webhooks/deliveries/:delivery-id",
+		withAuth(h.EditWebhookDelivery))
+
+	e.GET("/api/users/:user-id/addresses", withAuth(h.APIUserAddresses))
+	e.GET("/api/addresses", withAuth(h.APIAddressLookup))
+
+	if conf.StatsToken != "" {
+		e.POST(statsPath, h.APIIngestStats)
+	}
+
+	if conf.PortalJWTSecret != "" {
+		initPortal(e, h, conf.PortalJWTSecret)
+	}
 
 	return e, nil
 }
@@ -10,28 +10,49 @@ import (
 	"time"
 
 	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/handler"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/stats"
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/labstack/echo"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 const (
-	jwtSecret = "secret"
-	runMode   = "testing"
-	logLevel  = "off"
+	jwtSecret       = "secret"
+	portalJWTSecret = "portal-secret"
+	runMode         = "testing"
+	logLevel        = "off"
 )
 
-func initTestWebServer() (*bestore.MockStore, *echo.Echo, error) {
+func initTestWebServer() (*bestore.MockStore, *mastore.MockStore,
+	*echo.Echo, error) {
+	return initTestWebServerWith(handler.Config{})
+}
+
+// initTestWebServerWith inits web server with conf, stores and JWT secret
+// are set to testing ones. Testing admin is not trashed.
+func initTestWebServerWith(conf handler.Config) (*bestore.MockStore,
+	*mastore.MockStore, *echo.Echo, error) {
 	s := bestore.NewMockStore()
+	ms := mastore.NewMockStore()
+
+	conf.Store = s
+	conf.MStore = ms
+	conf.JWTSecret = jwtSecret
 
-	e, err := initWebServer(s, jwtSecret, runMode, logLevel)
+	// Every admin request checks that admin is not trashed.
+	ms.On("TrashItems", "admins").Return([]mastore.TrashItem{}, nil)
+
+	e, err := initWebServer(conf, runMode, logLevel, "")
 	if err != nil {
-		return s, e, err
+		return s, ms, e, err
 	}
 
 	e.Renderer = testRenderer{}
 
-	return s, e, nil
+	return s, ms, e, nil
 }
 
 type testRenderer struct{}
@@ -53,12 +74,37 @@ func makeTestingJWTToken() string {
 	return tokenEnc
 }
 
+func makeTestingPortalJWTToken() string {
+	claims := jwt.MapClaims{
+		"user_id": 3,
+		"email":   "a@example.com",
+		"exp":     time.Now().Add(12 * time.Hour).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenEnc, _ := token.SignedString([]byte(portalJWTSecret))
+
+	return tokenEnc
+}
+
+func hasCookie(res *httptest.ResponseRecorder, name string) bool {
+	for _, c := range res.Result().Cookies() {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func Test_Projects(t *testing.T) {
-	s, e, err := initTestWebServer()
+	s, ms, e, err := initTestWebServer()
 	if !assert.NoError(t, err) {
 		return
 	}
 
+	ms.On("WorkerHashrates", mock.Anything, stats.AverageWindow).
+		Return([]mastore.WorkerHashrate{}, nil)
+
 	s.On("ProjectsBalances").Return([]bestore.ProjectBalance{
 		{
 			ProjectID:   123,
@@ -69,6 +115,11 @@ func Test_Projects(t *testing.T) {
 		},
 	}, nil)
 
+	ms.On("ProjectsMeta").Return(map[uint]mastore.ProjectMeta{
+		123: {ProjectID: 123, Status: mastore.ProjectArchived},
+	}, nil)
+	ms.On("TrashItems", "projects").Return([]mastore.TrashItem{}, nil)
+
 	req := httptest.NewRequest(http.MethodGet, "/projects", nil)
 	req.AddCookie(&http.Cookie{Name: "auth", Value: makeTestingJWTToken()})
 
@@ -79,17 +130,40 @@ func Test_Projects(t *testing.T) {
 	assert.Equal(t, http.StatusOK, res.Code)
 
 	s.AssertExpectations(t)
+	ms.AssertExpectations(t)
+}
+
+func Test_Projects_invalidStatus(t *testing.T) {
+	_, _, e, err := initTestWebServer()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/projects?status=x", nil)
+	req.AddCookie(&http.Cookie{Name: "auth", Value: makeTestingJWTToken()})
+
+	res := httptest.NewRecorder()
+
+	e.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
 }
 
 func Test_ProjectEdit(t *testing.T) {
-	s, e, err := initTestWebServer()
+	s, ms, e, err := initTestWebServer()
 	if !assert.NoError(t, err) {
 		return
 	}
 
+	ms.On("TrashItems", "projects").Return([]mastore.TrashItem{}, nil)
+
 	s.On("GetProject", uint(123)).
 		Return(bestore.Project{ID: 123, Name: "name"}, nil)
 
+	ms.On("GetProjectMeta", uint(123)).
+		Return(mastore.ProjectMeta{ProjectID: 123,
+			Status: mastore.ProjectActive}, nil)
+
 	req := httptest.NewRequest(http.MethodGet, "/projects/123/edit", nil)
 	req.AddCookie(&http.Cookie{Name: "auth", Value: makeTestingJWTToken()})
 
@@ -103,11 +177,16 @@ func Test_ProjectEdit(t *testing.T) {
 }
 
 func Test_ProjectUsers(t *testing.T) {
-	s, e, err := initTestWebServer()
+	s, ms, e, err := initTestWebServer()
 	if !assert.NoError(t, err) {
 		return
 	}
 
+	ms.On("TrashItems", "projects").Return([]mastore.TrashItem{}, nil)
+
+	ms.On("WorkerHashrates", mock.Anything, stats.AverageWindow).
+		Return([]mastore.WorkerHashrate{}, nil)
+
 	s.On("GetProject", uint(123)).
 		Return(bestore.Project{ID: 123, Name: "name"}, nil)
 
@@ -120,6 +199,9 @@ func Test_ProjectUsers(t *testing.T) {
 				},
 			},
 		}, nil)
+	s.On("GetUsers").Return([]bestore.User{{ID: 3, Email: "Email"}}, nil)
+	ms.On("Workers").Return([]mastore.Worker{{ID: 1, UserID: 3,
+		Name: "rig1"}}, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/projects/123/users", nil)
 	req.AddCookie(&http.Cookie{Name: "auth", Value: makeTestingJWTToken()})
@@ -134,13 +216,22 @@ func Test_ProjectUsers(t *testing.T) {
 }
 
 func Test_NewProject(t *testing.T) {
-	s, e, err := initTestWebServer()
+	s, ms, e, err := initTestWebServer()
 	if !assert.NoError(t, err) {
 		return
 	}
 
 	s.On("AddProject", "Test").
 		Return(nil)
+	s.On("ProjectsBalances").Return([]bestore.ProjectBalance{
+		{ProjectID: 1, ProjectName: "Test"},
+		{ProjectID: 2, ProjectName: "Other"},
+		{ProjectID: 3, ProjectName: "Test"},
+	}, nil)
+	ms.On("ProjectsMeta").Return(map[uint]mastore.ProjectMeta{
+		1: {ProjectID: 1, Status: mastore.ProjectActive},
+	}, nil)
+	ms.On("TrackProjects", []uint{3}).Return(nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/projects",
 		strings.NewReader("name=Test&csrf-token=token"))
@@ -156,21 +247,64 @@ func Test_NewProject(t *testing.T) {
 	t.Log(string(body))
 	assert.Equal(t, http.StatusFound, res.Code)
 	assert.Equal(t, "/projects", res.Header().Get("Location"))
+	assert.True(t, hasCookie(res, "flash"))
+
+	s.AssertExpectations(t)
+	ms.AssertExpectations(t)
+}
+
+func Test_NewProject_blankName(t *testing.T) {
+	s, ms, e, err := initTestWebServer()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ms.On("WorkerHashrates", mock.Anything, stats.AverageWindow).
+		Return([]mastore.WorkerHashrate{}, nil)
+
+	s.On("ProjectsBalances").Return([]bestore.ProjectBalance{}, nil)
+	ms.On("ProjectsMeta").Return(map[uint]mastore.ProjectMeta{}, nil)
+	ms.On("TrashItems", "projects").Return([]mastore.TrashItem{}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/projects",
+		strings.NewReader("name=+&csrf-token=token"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "auth", Value: makeTestingJWTToken()})
+	req.AddCookie(&http.Cookie{Name: "_csrf", Value: "token"})
+
+	res := httptest.NewRecorder()
+
+	e.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
 
 	s.AssertExpectations(t)
+	s.AssertNotCalled(t, "AddProject", " ")
 }
 
 func Test_EditProject_editAction(t *testing.T) {
-	s, e, err := initTestWebServer()
+	s, ms, e, err := initTestWebServer()
 	if !assert.NoError(t, err) {
 		return
 	}
 
+	ms.On("TrashItems", "projects").Return([]mastore.TrashItem{}, nil)
+
 	s.On("SetProjectName", uint(123), "new-name").
 		Return(nil)
 
+	ms.On("SetProjectMeta", mastore.ProjectMeta{
+		ProjectID:   123,
+		Description: "description",
+		Owner:       "owner@example.com",
+		Status:      mastore.ProjectPaused,
+		Coins:       []string{"BTC", "ETH"},
+	}).Return(nil)
+
 	req := httptest.NewRequest(http.MethodPost, "/projects/123",
-		strings.NewReader("action=edit&name=new-name&csrf-token=token"))
+		strings.NewReader("action=edit&name=new-name"+
+			"&description=+description+&owner=owner%40example.com"+
+			"&status=paused&coins=BTC&coins=ETH&csrf-token=token"))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.AddCookie(&http.Cookie{Name: "auth", Value: makeTestingJWTToken()})
 	req.AddCookie(&http.Cookie{Name: "_csrf", Value: "token"})
@@ -188,17 +322,111 @@ func Test_EditProject_editAction(t *testing.T) {
 		res.Header().Get("Location"))
 
 	s.AssertExpectations(t)
+	ms.AssertExpectations(t)
 }
 
-func Test_EditProject_removeAction(t *testing.T) {
-	s, e, err := initTestWebServer()
+func Test_EditProject_editActionInvalidStatus(t *testing.T) {
+	s, ms, e, err := initTestWebServer()
 	if !assert.NoError(t, err) {
 		return
 	}
 
-	s.On("RemoveProject", uint(123)).
+	ms.On("TrashItems", "projects").Return([]mastore.TrashItem{}, nil)
+
+	s.On("GetProject", uint(123)).
+		Return(bestore.Project{ID: 123, Name: "name"}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/projects/123",
+		strings.NewReader("action=edit&name=new-name&status=closed"+
+			"&csrf-token=token"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "auth", Value: makeTestingJWTToken()})
+	req.AddCookie(&http.Cookie{Name: "_csrf", Value: "token"})
+
+	res := httptest.NewRecorder()
+
+	e.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	s.AssertNotCalled(t, "SetProjectName", uint(123), "new-name")
+}
+
+func Test_EditProject_archiveAction(t *testing.T) {
+	_, ms, e, err := initTestWebServer()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ms.On("TrashItems", "projects").Return([]mastore.TrashItem{}, nil)
+
+	ms.On("SetProjectStatus", uint(123), mastore.ProjectArchived).
 		Return(nil)
 
+	req := httptest.NewRequest(http.MethodPost, "/projects/123",
+		strings.NewReader("action=archive&csrf-token=token"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "auth", Value: makeTestingJWTToken()})
+	req.AddCookie(&http.Cookie{Name: "_csrf", Value: "token"})
+
+	res := httptest.NewRecorder()
+
+	e.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/projects", res.Header().Get("Location"))
+
+	ms.AssertExpectations(t)
+}
+
+func Test_EditProject_removeActiveProject(t *testing.T) {
+	s, ms, e, err := initTestWebServer()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ms.On("TrashItems", "projects").Return([]mastore.TrashItem{}, nil)
+
+	ms.On("GetProjectMeta", uint(123)).
+		Return(mastore.ProjectMeta{ProjectID: 123,
+			Status: mastore.ProjectActive}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/projects/123",
+		strings.NewReader("action=remove&csrf-token=token"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "auth", Value: makeTestingJWTToken()})
+	req.AddCookie(&http.Cookie{Name: "_csrf", Value: "token"})
+
+	res := httptest.NewRecorder()
+
+	e.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.True(t, hasCookie(res, "flash"))
+
+	s.AssertNotCalled(t, "RemoveProject", uint(123))
+}
+
+func Test_EditProject_removeAction(t *testing.T) {
+	s, ms, e, err := initTestWebServer()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ms.On("TrashItems", "projects").Return([]mastore.TrashItem{}, nil)
+
+	ms.On("GetProjectMeta", uint(123)).
+		Return(mastore.ProjectMeta{ProjectID: 123,
+			Status: mastore.ProjectArchived}, nil)
+
+	s.On("GetProject", uint(123)).
+		Return(bestore.Project{ID: 123, Name: "name"}, nil)
+
+	ms.On("AddTrashItem", mock.MatchedBy(func(item mastore.TrashItem) bool {
+		return item.Kind == "projects" && item.EntityID == 123 &&
+			item.Name == "name" && item.DeletedBy == "login"
+	})).Return(uint(7), nil)
+
 	req := httptest.NewRequest(http.MethodPost, "/projects/123",
 		strings.NewReader("action=remove&csrf-token=token"))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -214,5 +442,88 @@ func Test_EditProject_removeAction(t *testing.T) {
 	body, _ := ioutil.ReadAll(res.Body)
 	t.Log(string(body))
 	assert.Equal(t, http.StatusFound, res.Code)
-	assert.Equal(t, "/projects", res.Header().Get("Location"))
+	assert.Equal(t, "/projects?status=archived",
+		res.Header().Get("Location"))
+
+	s.AssertExpectations(t)
+	ms.AssertExpectations(t)
+	s.AssertNotCalled(t, "RemoveProject", uint(123))
+}
+
+func Test_Portal_tokensNotInterchangeable(t *testing.T) {
+	_, _, e, err := initTestWebServerWith(handler.Config{
+		PortalJWTSecret: portalJWTSecret,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/portal", nil)
+	req.AddCookie(&http.Cookie{Name: handler.PortalCookie,
+		Value: makeTestingJWTToken()})
+
+	res := httptest.NewRecorder()
+
+	e.ServeHTTP(res, req)
+
+	assert.NotEqual(t, http.StatusOK, res.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.AddCookie(&http.Cookie{Name: "auth",
+		Value: makeTestingPortalJWTToken()})
+
+	res = httptest.NewRecorder()
+
+	e.ServeHTTP(res, req)
+
+	assert.NotEqual(t, http.StatusOK, res.Code)
+}
+
+func Test_Portal_disabled(t *testing.T) {
+	_, _, e, err := initTestWebServer()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/portal/login", nil)
+
+	res := httptest.NewRecorder()
+
+	e.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func Test_embeddedTemplates(t *testing.T) {
+	fsys, err := templatesFS("")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = handler.NewProdTemplateRenderer(fsys)
+	assert.NoError(t, err)
+}
+
+func Test_EditSettings(t *testing.T) {
+	_, ms, e, err := initTestWebServer()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ms.On("SetAdminLocale", "login", "ru").Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/settings",
+		strings.NewReader("locale=ru&csrf-token=token"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "auth", Value: makeTestingJWTToken()})
+	req.AddCookie(&http.Cookie{Name: "_csrf", Value: "token"})
+
+	res := httptest.NewRecorder()
+
+	e.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.True(t, hasCookie(res, "locale"))
+
+	ms.AssertExpectations(t)
 }
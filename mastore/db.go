@@ -0,0 +1,987 @@
+package mastore
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DBStore is Store implementation on top of postgres database.
+type DBStore struct {
+	db  *sql.DB
+	ctx context.Context
+}
+
+func NewDBStore(db *sql.DB) DBStore {
+	return DBStore{db: db, ctx: context.Background()}
+}
+
+func (s DBStore) WithContext(ctx context.Context) Store {
+	return DBStore{db: s.db, ctx: ctx}
+}
+
+func (s DBStore) GetAdminLocale(login string) (string, error) {
+	var locale string
+	err := s.db.QueryRowContext(s.ctx, `SELECT locale FROM admin_settings
+		WHERE login = $1`, login).Scan(&locale)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return locale, err
+}
+
+func (s DBStore) SetAdminLocale(login string, locale string) error {
+	_, err := s.db.ExecContext(s.ctx, `INSERT INTO admin_settings (login, locale)
+		VALUES ($1, $2)
+		ON CONFLICT (login) DO UPDATE SET locale = excluded.locale`,
+		login, locale)
+	return err
+}
+
+func (s DBStore) TrackProjects(projectIDs []uint) error {
+	ids := make([]int64, len(projectIDs))
+	for i, id := range projectIDs {
+		ids[i] = int64(id)
+	}
+	_, err := s.db.ExecContext(s.ctx, `INSERT INTO project_meta (project_id)
+		SELECT unnest($1::bigint[])
+		ON CONFLICT (project_id) DO NOTHING`, pq.Array(ids))
+	return err
+}
+
+const projectMetaColumns = `project_id, description, owner, status, coins,
+	created_at`
+
+func scanProjectMeta(row interface {
+	Scan(dest ...interface{}) error
+}) (ProjectMeta, error) {
+	var (
+		m  ProjectMeta
+		id int64
+	)
+	err := row.Scan(&id, &m.Description, &m.Owner, &m.Status,
+		pq.Array(&m.Coins), &m.CreatedAt)
+	m.ProjectID = uint(id)
+	return m, err
+}
+
+func (s DBStore) GetProjectMeta(projectID uint) (ProjectMeta, error) {
+	m, err := scanProjectMeta(s.db.QueryRowContext(s.ctx,
+		`SELECT `+projectMetaColumns+`
+		FROM project_meta WHERE project_id = $1`, projectID))
+	if err == sql.ErrNoRows {
+		return ProjectMeta{ProjectID: projectID, Status: ProjectActive}, nil
+	}
+	return m, err
+}
+
+func (s DBStore) ProjectsMeta() (map[uint]ProjectMeta, error) {
+	rows, err := s.db.QueryContext(s.ctx, `SELECT `+projectMetaColumns+
+		` FROM project_meta`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ms := map[uint]ProjectMeta{}
+
+	for rows.Next() {
+		m, err := scanProjectMeta(rows)
+		if err != nil {
+			return nil, err
+		}
+		ms[m.ProjectID] = m
+	}
+
+	return ms, rows.Err()
+}
+
+func (s DBStore) SetProjectMeta(m ProjectMeta) error {
+	coins := m.Coins
+	if coins == nil {
+		coins = []string{}
+	}
+	_, err := s.db.ExecContext(s.ctx, `INSERT INTO project_meta
+		(project_id, description, owner, status, coins)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (project_id) DO UPDATE SET
+			description = excluded.description,
+			owner = excluded.owner,
+			status = excluded.status,
+			coins = excluded.coins`,
+		m.ProjectID, m.Description, m.Owner, m.Status, pq.Array(coins))
+	return err
+}
+
+func (s DBStore) SetProjectStatus(projectID uint,
+	status ProjectStatus) error {
+	_, err := s.db.ExecContext(s.ctx, `INSERT INTO project_meta
+		(project_id, status) VALUES ($1, $2)
+		ON CONFLICT (project_id) DO UPDATE SET status = excluded.status`,
+		projectID, status)
+	return err
+}
+
+func (s DBStore) AddTrashItem(item TrashItem) (uint, error) {
+	var id int64
+	err := s.db.QueryRowContext(s.ctx, `INSERT INTO trash
+		(kind, entity_id, name, payload, deleted_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		item.Kind, item.EntityID, item.Name, item.Payload,
+		item.DeletedBy).Scan(&id)
+	return uint(id), err
+}
+
+const trashItemColumns = `id, kind, entity_id, name, payload, deleted_by,
+	deleted_at`
+
+func scanTrashItem(row interface {
+	Scan(dest ...interface{}) error
+}) (TrashItem, error) {
+	var (
+		item         TrashItem
+		id, entityID int64
+	)
+	err := row.Scan(&id, &item.Kind, &entityID, &item.Name, &item.Payload,
+		&item.DeletedBy, &item.DeletedAt)
+	item.ID = uint(id)
+	item.EntityID = uint(entityID)
+	return item, err
+}
+
+func (s DBStore) GetTrashItem(id uint) (TrashItem, error) {
+	item, err := scanTrashItem(s.db.QueryRowContext(s.ctx,
+		`SELECT `+trashItemColumns+` FROM trash WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return TrashItem{}, ErrNotFound
+	}
+	return item, err
+}
+
+func (s DBStore) queryTrashItems(query string,
+	args ...interface{}) ([]TrashItem, error) {
+	rows, err := s.db.QueryContext(s.ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []TrashItem
+
+	for rows.Next() {
+		item, err := scanTrashItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (s DBStore) TrashItems(kind string) ([]TrashItem, error) {
+	return s.queryTrashItems(`SELECT `+trashItemColumns+` FROM trash
+		WHERE kind = $1 ORDER BY deleted_at DESC, id DESC`, kind)
+}
+
+func (s DBStore) ExpiredTrashItems(before time.Time) ([]TrashItem, error) {
+	return s.queryTrashItems(`SELECT `+trashItemColumns+` FROM trash
+		WHERE deleted_at < $1 ORDER BY deleted_at`, before)
+}
+
+func (s DBStore) RemoveTrashItem(id uint) error {
+	_, err := s.db.ExecContext(s.ctx, `DELETE FROM trash WHERE id = $1`, id)
+	return err
+}
+
+func (s DBStore) AddAddressChange(ch AddressChange) (uint, error) {
+	var id int64
+	err := s.db.QueryRowContext(s.ctx, `INSERT INTO address_changes
+		(user_id, action, coin, address, replacement, requested_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		ch.UserID, ch.Action, ch.Coin, ch.Address, ch.Replacement,
+		ch.RequestedBy).Scan(&id)
+	return uint(id), err
+}
+
+const addressChangeColumns = `id, user_id, action, coin, address,
+	replacement, status, requested_by, requested_at, decided_by, decided_at,
+	eligible_at`
+
+func scanAddressChange(row interface {
+	Scan(dest ...interface{}) error
+}) (AddressChange, error) {
+	var (
+		ch                    AddressChange
+		id, userID            int64
+		decidedAt, eligibleAt pq.NullTime
+	)
+	err := row.Scan(&id, &userID, &ch.Action, &ch.Coin, &ch.Address,
+		&ch.Replacement, &ch.Status, &ch.RequestedBy, &ch.RequestedAt,
+		&ch.DecidedBy, &decidedAt, &eligibleAt)
+	ch.ID = uint(id)
+	ch.UserID = uint(userID)
+	ch.DecidedAt = decidedAt.Time
+	ch.EligibleAt = eligibleAt.Time
+	return ch, err
+}
+
+func (s DBStore) GetAddressChange(id uint) (AddressChange, error) {
+	ch, err := scanAddressChange(s.db.QueryRowContext(s.ctx, `SELECT `+
+		addressChangeColumns+` FROM address_changes WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return AddressChange{}, ErrNotFound
+	}
+	return ch, err
+}
+
+func (s DBStore) queryAddressChanges(query string,
+	args ...interface{}) ([]AddressChange, error) {
+	rows, err := s.db.QueryContext(s.ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chs []AddressChange
+
+	for rows.Next() {
+		ch, err := scanAddressChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		chs = append(chs, ch)
+	}
+
+	return chs, rows.Err()
+}
+
+func (s DBStore) PendingAddressChanges() ([]AddressChange, error) {
+	return s.queryAddressChanges(`SELECT ` + addressChangeColumns + `
+		FROM address_changes WHERE status = 'pending'
+		ORDER BY requested_at, id`)
+}
+
+func (s DBStore) CountPendingAddressChanges() (int, error) {
+	var n int
+	err := s.db.QueryRowContext(s.ctx, `SELECT count(*) FROM address_changes
+		WHERE status = 'pending'`).Scan(&n)
+	return n, err
+}
+
+func (s DBStore) UserAddressChanges(userID uint) ([]AddressChange, error) {
+	return s.queryAddressChanges(`SELECT `+addressChangeColumns+`
+		FROM address_changes WHERE user_id = $1
+		ORDER BY requested_at DESC, id DESC`, userID)
+}
+
+func (s DBStore) RecentAddressChanges(limit int) ([]AddressChange, error) {
+	return s.queryAddressChanges(`SELECT `+addressChangeColumns+`
+		FROM address_changes
+		ORDER BY coalesce(decided_at, requested_at) DESC, id DESC
+		LIMIT $1`, limit)
+}
+
+func (s DBStore) DecideAddressChange(id uint, status string, by string,
+	eligibleAt time.Time) error {
+	var eligible pq.NullTime
+	if !eligibleAt.IsZero() {
+		eligible = pq.NullTime{Time: eligibleAt, Valid: true}
+	}
+
+	res, err := s.db.ExecContext(s.ctx, `UPDATE address_changes
+		SET status = $2, decided_by = $3, decided_at = now(),
+			eligible_at = $4
+		WHERE id = $1 AND status = 'pending'`, id, status, by, eligible)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s DBStore) ReopenAddressChange(id uint) error {
+	_, err := s.db.ExecContext(s.ctx, `UPDATE address_changes
+		SET status = 'pending', decided_by = '', decided_at = NULL,
+			eligible_at = NULL
+		WHERE id = $1`, id)
+	return err
+}
+
+func (s DBStore) CoolingAddressChanges(at time.Time) ([]AddressChange,
+	error) {
+	return s.queryAddressChanges(`SELECT `+addressChangeColumns+`
+		FROM address_changes
+		WHERE status = 'approved' AND action = 'add' AND eligible_at > $1
+		ORDER BY eligible_at`, at)
+}
+
+func (s DBStore) PrimaryAddresses(userID uint) (map[string]string, error) {
+	rows, err := s.db.QueryContext(s.ctx, `SELECT coin, address
+		FROM primary_addresses WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addrs := map[string]string{}
+
+	for rows.Next() {
+		var coin, address string
+		err := rows.Scan(&coin, &address)
+		if err != nil {
+			return nil, err
+		}
+		addrs[coin] = address
+	}
+
+	return addrs, rows.Err()
+}
+
+func (s DBStore) SetPrimaryAddress(userID uint, coin string, address string,
+	by string) error {
+	_, err := s.db.ExecContext(s.ctx, `INSERT INTO primary_addresses
+		(user_id, coin, address, set_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, coin) DO UPDATE SET
+			address = excluded.address,
+			set_by = excluded.set_by,
+			set_at = now()`,
+		userID, coin, address, by)
+	return err
+}
+
+func (s DBStore) RemovePrimaryAddress(userID uint, coin string) error {
+	_, err := s.db.ExecContext(s.ctx, `DELETE FROM primary_addresses
+		WHERE user_id = $1 AND coin = $2`, userID, coin)
+	return err
+}
+
+func (s DBStore) TrackAddress(userID uint, coin string, address string,
+	by string) error {
+	_, err := s.db.ExecContext(s.ctx, `INSERT INTO address_meta
+		(user_id, coin, address, created_by, created_at)
+		VALUES ($1, $2, $3, $4, now())
+		ON CONFLICT (user_id, coin, address) DO UPDATE SET
+			created_by = excluded.created_by,
+			created_at = excluded.created_at`,
+		userID, coin, address, by)
+	return err
+}
+
+func (s DBStore) UserAddressesMeta(userID uint) ([]AddressMeta, error) {
+	return s.queryAddressesMeta(`SELECT `+addressMetaColumns+`
+		FROM address_meta WHERE user_id = $1
+		ORDER BY coin, address`, userID)
+}
+
+func (s DBStore) SetAddressNote(userID uint, coin string, address string,
+	label string, note string) error {
+	_, err := s.db.ExecContext(s.ctx, `INSERT INTO address_meta
+		(user_id, coin, address, label, note)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, coin, address) DO UPDATE SET
+			label = excluded.label,
+			note = excluded.note`,
+		userID, coin, address, label, note)
+	return err
+}
+
+func (s DBStore) SearchAddresses(query string) ([]AddressMeta, error) {
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	return s.queryAddressesMeta(`SELECT `+addressMetaColumns+`
+		FROM address_meta
+		WHERE address ILIKE $1 OR label ILIKE $1 OR note ILIKE $1
+		ORDER BY user_id, coin, address`, pattern)
+}
+
+// likeEscaper escapes LIKE pattern special characters.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+const addressMetaColumns = `user_id, coin, address, label, note, created_by,
+	created_at`
+
+func (s DBStore) queryAddressesMeta(query string,
+	args ...interface{}) ([]AddressMeta, error) {
+	rows, err := s.db.QueryContext(s.ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var metas []AddressMeta
+
+	for rows.Next() {
+		var (
+			m         AddressMeta
+			userID    int64
+			createdAt pq.NullTime
+		)
+		err := rows.Scan(&userID, &m.Coin, &m.Address, &m.Label, &m.Note,
+			&m.CreatedBy, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		m.UserID = uint(userID)
+		m.CreatedAt = createdAt.Time
+		metas = append(metas, m)
+	}
+
+	return metas, rows.Err()
+}
+
+func (s DBStore) AddWebhook(w Webhook) (uint, error) {
+	var id int64
+	err := s.db.QueryRowContext(s.ctx, `INSERT INTO webhooks
+		(url, events, secret, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`,
+		w.URL, pq.Array(w.Events), w.Secret, w.CreatedBy).Scan(&id)
+	return uint(id), err
+}
+
+func (s DBStore) Webhooks() ([]Webhook, error) {
+	rows, err := s.db.QueryContext(s.ctx, `SELECT id, url, events, secret,
+		created_by, created_at FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ws []Webhook
+
+	for rows.Next() {
+		var (
+			w  Webhook
+			id int64
+		)
+		err := rows.Scan(&id, &w.URL, pq.Array(&w.Events), &w.Secret,
+			&w.CreatedBy, &w.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		w.ID = uint(id)
+		ws = append(ws, w)
+	}
+
+	return ws, rows.Err()
+}
+
+func (s DBStore) RemoveWebhook(id uint) error {
+	_, err := s.db.ExecContext(s.ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	return err
+}
+
+func (s DBStore) AddWebhookDeliveries(event string,
+	payload string) (int, error) {
+	res, err := s.db.ExecContext(s.ctx, `INSERT INTO webhook_deliveries
+		(webhook_id, event, payload)
+		SELECT id, $1, $2 FROM webhooks WHERE $1 = ANY (events)`,
+		event, payload)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+const webhookDeliveryColumns = `d.id, d.webhook_id, w.url, w.secret, d.event,
+	d.payload, d.status, d.attempts, d.response_code, d.last_error,
+	d.created_at, d.next_attempt_at, d.delivered_at`
+
+func scanWebhookDelivery(row interface {
+	Scan(dest ...interface{}) error
+}) (WebhookDelivery, error) {
+	var (
+		d             WebhookDelivery
+		id, webhookID int64
+		deliveredAt   pq.NullTime
+	)
+	err := row.Scan(&id, &webhookID, &d.URL, &d.Secret, &d.Event,
+		&d.Payload, &d.Status, &d.Attempts, &d.ResponseCode, &d.LastError,
+		&d.CreatedAt, &d.NextAttemptAt, &deliveredAt)
+	d.ID = uint(id)
+	d.WebhookID = uint(webhookID)
+	d.DeliveredAt = deliveredAt.Time
+	return d, err
+}
+
+func (s DBStore) GetWebhookDelivery(id uint) (WebhookDelivery, error) {
+	d, err := scanWebhookDelivery(s.db.QueryRowContext(s.ctx, `SELECT `+
+		webhookDeliveryColumns+` FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return WebhookDelivery{}, ErrNotFound
+	}
+	return d, err
+}
+
+func (s DBStore) queryWebhookDeliveries(query string,
+	args ...interface{}) ([]WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(s.ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ds []WebhookDelivery
+
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		ds = append(ds, d)
+	}
+
+	return ds, rows.Err()
+}
+
+func (s DBStore) DueWebhookDeliveries(before time.Time,
+	limit int) ([]WebhookDelivery, error) {
+	return s.queryWebhookDeliveries(`SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= $1
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT $2`, before, limit)
+}
+
+func (s DBStore) WebhookDeliveries(limit int) ([]WebhookDelivery, error) {
+	return s.queryWebhookDeliveries(`SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		ORDER BY d.id DESC
+		LIMIT $1`, limit)
+}
+
+func (s DBStore) UpdateWebhookDelivery(d WebhookDelivery) error {
+	deliveredAt := pq.NullTime{Time: d.DeliveredAt,
+		Valid: !d.DeliveredAt.IsZero()}
+	_, err := s.db.ExecContext(s.ctx, `UPDATE webhook_deliveries SET
+			status = $2,
+			attempts = $3,
+			response_code = $4,
+			last_error = $5,
+			next_attempt_at = $6,
+			delivered_at = $7
+		WHERE id = $1`,
+		d.ID, d.Status, d.Attempts, d.ResponseCode, d.LastError,
+		d.NextAttemptAt, deliveredAt)
+	return err
+}
+
+func (s DBStore) AddLoginToken(tokenHash string, userID uint,
+	expiresAt time.Time) error {
+	_, err := s.db.ExecContext(s.ctx, `INSERT INTO portal_login_tokens
+		(token_hash, user_id, expires_at)
+		VALUES ($1, $2, $3)`, tokenHash, userID, expiresAt)
+	return err
+}
+
+func (s DBStore) UseLoginToken(tokenHash string, at time.Time) (uint,
+	error) {
+	var userID int64
+	err := s.db.QueryRowContext(s.ctx, `UPDATE portal_login_tokens SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING user_id`, tokenHash, at).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrNotFound
+	}
+	return uint(userID), err
+}
+
+func (s DBStore) RemoveExpiredLoginTokens(before time.Time) error {
+	_, err := s.db.ExecContext(s.ctx, `DELETE FROM portal_login_tokens
+		WHERE expires_at < $1`, before)
+	return err
+}
+
+func (s DBStore) RequestLoginLink(email string, at time.Time,
+	since time.Time) (bool, error) {
+	err := s.db.QueryRowContext(s.ctx, `INSERT INTO portal_login_requests
+		(email, requested_at)
+		VALUES ($1, $2)
+		ON CONFLICT (email) DO UPDATE SET requested_at = $2
+		WHERE portal_login_requests.requested_at <= $3
+		RETURNING email`, email, at, since).Scan(&email)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s DBStore) AddWorker(w Worker) (uint, error) {
+	var id int64
+	err := s.db.QueryRowContext(s.ctx, `INSERT INTO workers
+		(user_id, name, expected_hashrate, location, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		w.UserID, w.Name, w.ExpectedHashrate, w.Location,
+		w.CreatedBy).Scan(&id)
+	return uint(id), err
+}
+
+const workerColumns = `id, user_id, name, expected_hashrate, location,
+	created_by, created_at`
+
+func scanWorker(row interface {
+	Scan(dest ...interface{}) error
+}) (Worker, error) {
+	var (
+		w          Worker
+		id, userID int64
+	)
+	err := row.Scan(&id, &userID, &w.Name, &w.ExpectedHashrate, &w.Location,
+		&w.CreatedBy, &w.CreatedAt)
+	w.ID = uint(id)
+	w.UserID = uint(userID)
+	return w, err
+}
+
+func (s DBStore) GetWorker(id uint) (Worker, error) {
+	w, err := scanWorker(s.db.QueryRowContext(s.ctx, `SELECT `+workerColumns+`
+		FROM workers WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return Worker{}, ErrNotFound
+	}
+	return w, err
+}
+
+func (s DBStore) queryWorkers(query string,
+	args ...interface{}) ([]Worker, error) {
+	rows, err := s.db.QueryContext(s.ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ws []Worker
+
+	for rows.Next() {
+		w, err := scanWorker(rows)
+		if err != nil {
+			return nil, err
+		}
+		ws = append(ws, w)
+	}
+
+	return ws, rows.Err()
+}
+
+func (s DBStore) UserWorkers(userID uint) ([]Worker, error) {
+	return s.queryWorkers(`SELECT `+workerColumns+` FROM workers
+		WHERE user_id = $1 ORDER BY lower(name)`, userID)
+}
+
+func (s DBStore) Workers() ([]Worker, error) {
+	return s.queryWorkers(`SELECT ` + workerColumns + ` FROM workers
+		ORDER BY user_id, lower(name)`)
+}
+
+func (s DBStore) UpdateWorker(w Worker) error {
+	_, err := s.db.ExecContext(s.ctx, `UPDATE workers
+		SET name = $2, expected_hashrate = $3, location = $4
+		WHERE id = $1`, w.ID, w.Name, w.ExpectedHashrate, w.Location)
+	return err
+}
+
+func (s DBStore) RemoveWorker(id uint) error {
+	_, err := s.db.ExecContext(s.ctx, `DELETE FROM workers WHERE id = $1`, id)
+	return err
+}
+
+func (s DBStore) AddWorkerStats(stats []WorkerStat) error {
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, st := range stats {
+		_, err = tx.ExecContext(s.ctx, `INSERT INTO worker_stats
+			(project_id, user_id, worker, bucket_start, samples,
+			hashrate_sum, accepted, rejected)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (project_id, user_id, worker, bucket_start)
+			DO UPDATE SET
+				samples = worker_stats.samples + excluded.samples,
+				hashrate_sum = worker_stats.hashrate_sum +
+					excluded.hashrate_sum,
+				accepted = worker_stats.accepted + excluded.accepted,
+				rejected = worker_stats.rejected + excluded.rejected`,
+			st.ProjectID, st.UserID, st.Worker, st.BucketStart, st.Samples,
+			st.HashrateSum, st.Accepted, st.Rejected)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s DBStore) WorkerHashrates(at time.Time,
+	window time.Duration) ([]WorkerHashrate, error) {
+	buckets := float64(window / StatsBucket)
+	if buckets < 1 {
+		buckets = 1
+	}
+
+	rows, err := s.db.QueryContext(s.ctx, `SELECT project_id, user_id, worker,
+			COALESCE(avg(hashrate_sum / samples)
+				FILTER (WHERE bucket_start > $2), 0),
+			sum(hashrate_sum / samples) / $4
+		FROM worker_stats
+		WHERE bucket_start > $3 AND bucket_start <= $1 AND samples > 0
+		GROUP BY project_id, user_id, worker
+		ORDER BY project_id, user_id, worker`,
+		at, at.Add(-CurrentHashrateWindow), at.Add(-window), buckets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hs []WorkerHashrate
+
+	for rows.Next() {
+		var (
+			h                 WorkerHashrate
+			projectID, userID int64
+		)
+		err := rows.Scan(&projectID, &userID, &h.Worker, &h.Current,
+			&h.Average)
+		if err != nil {
+			return nil, err
+		}
+		h.ProjectID = uint(projectID)
+		h.UserID = uint(userID)
+		hs = append(hs, h)
+	}
+
+	return hs, rows.Err()
+}
+
+func (s DBStore) RemoveWorkerStats(before time.Time) error {
+	_, err := s.db.ExecContext(s.ctx, `DELETE FROM worker_stats
+		WHERE bucket_start < $1`, before)
+	return err
+}
+
+func (s DBStore) AddBalanceSnapshots(snaps []BalanceSnapshot) error {
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, sn := range snaps {
+		_, err = tx.ExecContext(s.ctx, `INSERT INTO balance_snapshots
+			(project_id, email, coin, amount, taken_at)
+			VALUES ($1, $2, $3, $4, $5)`,
+			sn.ProjectID, sn.Email, sn.Coin, sn.Amount, sn.TakenAt)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s DBStore) BalanceSnapshots(since time.Time) ([]BalanceSnapshot,
+	error) {
+	rows, err := s.db.QueryContext(s.ctx, `SELECT project_id, email, coin,
+		amount::text, taken_at FROM balance_snapshots WHERE taken_at > $1
+		ORDER BY taken_at`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snaps []BalanceSnapshot
+
+	for rows.Next() {
+		var (
+			sn        BalanceSnapshot
+			projectID int64
+		)
+		err := rows.Scan(&projectID, &sn.Email, &sn.Coin, &sn.Amount,
+			&sn.TakenAt)
+		if err != nil {
+			return nil, err
+		}
+		sn.ProjectID = uint(projectID)
+		snaps = append(snaps, sn)
+	}
+
+	return snaps, rows.Err()
+}
+
+func (s DBStore) RemoveBalanceSnapshots(before time.Time) error {
+	_, err := s.db.ExecContext(s.ctx, `DELETE FROM balance_snapshots
+		WHERE taken_at < $1`, before)
+	return err
+}
+
+func (s DBStore) AddAlert(a Alert) (uint, error) {
+	var id int64
+	err := s.db.QueryRowContext(s.ctx, `INSERT INTO alerts
+		(kind, project_id, project_name, email, coin, details)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		a.Kind, a.ProjectID, a.ProjectName, a.Email, a.Coin,
+		a.Details).Scan(&id)
+	return uint(id), err
+}
+
+const alertColumns = `id, kind, project_id, project_name, email, coin,
+	details, opened_at, acknowledged_by, acknowledged_at, resolved_at`
+
+func scanAlert(row interface {
+	Scan(dest ...interface{}) error
+}) (Alert, error) {
+	var (
+		a                          Alert
+		id, projectID              int64
+		acknowledgedAt, resolvedAt pq.NullTime
+	)
+	err := row.Scan(&id, &a.Kind, &projectID, &a.ProjectName, &a.Email,
+		&a.Coin, &a.Details, &a.OpenedAt, &a.AcknowledgedBy, &acknowledgedAt,
+		&resolvedAt)
+	a.ID = uint(id)
+	a.ProjectID = uint(projectID)
+	a.AcknowledgedAt = acknowledgedAt.Time
+	a.ResolvedAt = resolvedAt.Time
+	return a, err
+}
+
+func (s DBStore) GetAlert(id uint) (Alert, error) {
+	a, err := scanAlert(s.db.QueryRowContext(s.ctx, `SELECT `+alertColumns+`
+		FROM alerts WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return Alert{}, ErrNotFound
+	}
+	return a, err
+}
+
+func (s DBStore) queryAlerts(query string,
+	args ...interface{}) ([]Alert, error) {
+	rows, err := s.db.QueryContext(s.ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var as []Alert
+
+	for rows.Next() {
+		a, err := scanAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		as = append(as, a)
+	}
+
+	return as, rows.Err()
+}
+
+func (s DBStore) OpenAlerts() ([]Alert, error) {
+	return s.queryAlerts(`SELECT ` + alertColumns + ` FROM alerts
+		WHERE resolved_at IS NULL ORDER BY opened_at, id`)
+}
+
+func (s DBStore) ResolvedAlerts(limit int) ([]Alert, error) {
+	return s.queryAlerts(`SELECT `+alertColumns+` FROM alerts
+		WHERE resolved_at IS NOT NULL
+		ORDER BY resolved_at DESC, id DESC LIMIT $1`, limit)
+}
+
+func (s DBStore) AcknowledgeAlert(id uint, by string) error {
+	res, err := s.db.ExecContext(s.ctx, `UPDATE alerts
+		SET acknowledged_by = $2, acknowledged_at = now()
+		WHERE id = $1 AND resolved_at IS NULL`, id, by)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s DBStore) ResolveAlert(id uint, at time.Time) error {
+	_, err := s.db.ExecContext(s.ctx, `UPDATE alerts SET resolved_at = $2
+		WHERE id = $1 AND resolved_at IS NULL`, id, at)
+	return err
+}
+
+func (s DBStore) AddReport(r Report) (uint, error) {
+	var id int64
+	err := s.db.QueryRowContext(s.ctx, `INSERT INTO reports
+		(name, format, content) VALUES ($1, $2, $3) RETURNING id`,
+		r.Name, r.Format, r.Content).Scan(&id)
+	return uint(id), err
+}
+
+func (s DBStore) GetReport(id uint) (Report, error) {
+	r := Report{ID: id}
+	err := s.db.QueryRowContext(s.ctx, `SELECT name, format, content,
+		created_at FROM reports WHERE id = $1`, id).Scan(&r.Name, &r.Format,
+		&r.Content, &r.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Report{}, ErrNotFound
+	}
+	if err != nil {
+		return Report{}, err
+	}
+	r.Size = len(r.Content)
+	return r, nil
+}
+
+func (s DBStore) Reports(limit int) ([]Report, error) {
+	rows, err := s.db.QueryContext(s.ctx, `SELECT id, name, format,
+		octet_length(content), created_at FROM reports
+		ORDER BY created_at DESC, id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rs []Report
+
+	for rows.Next() {
+		var (
+			r  Report
+			id int64
+		)
+		err := rows.Scan(&id, &r.Name, &r.Format, &r.Size, &r.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		r.ID = uint(id)
+		rs = append(rs, r)
+	}
+
+	return rs, rows.Err()
+}
+
+func (s DBStore) RemoveReports(before time.Time) error {
+	_, err := s.db.ExecContext(s.ctx, `DELETE FROM reports
+		WHERE created_at < $1`, before)
+	return err
+}
@@ -0,0 +1,351 @@
+package mastore
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockStore is Store mock for tests.
+type MockStore struct {
+	mock.Mock
+}
+
+func NewMockStore() *MockStore {
+	return &MockStore{}
+}
+
+// WithContext returns the same mock, so expectations are shared by all
+// requests.
+func (s *MockStore) WithContext(ctx context.Context) Store {
+	return s
+}
+
+func (s *MockStore) GetAdminLocale(login string) (string, error) {
+	args := s.Called(login)
+	return args.String(0), args.Error(1)
+}
+
+func (s *MockStore) SetAdminLocale(login string, locale string) error {
+	args := s.Called(login, locale)
+	return args.Error(0)
+}
+
+func (s *MockStore) TrackProjects(projectIDs []uint) error {
+	args := s.Called(projectIDs)
+	return args.Error(0)
+}
+
+func (s *MockStore) GetProjectMeta(projectID uint) (ProjectMeta, error) {
+	args := s.Called(projectID)
+	return args.Get(0).(ProjectMeta), args.Error(1)
+}
+
+func (s *MockStore) ProjectsMeta() (map[uint]ProjectMeta, error) {
+	args := s.Called()
+	return args.Get(0).(map[uint]ProjectMeta), args.Error(1)
+}
+
+func (s *MockStore) SetProjectMeta(meta ProjectMeta) error {
+	args := s.Called(meta)
+	return args.Error(0)
+}
+
+func (s *MockStore) SetProjectStatus(projectID uint,
+	status ProjectStatus) error {
+	args := s.Called(projectID, status)
+	return args.Error(0)
+}
+
+func (s *MockStore) AddTrashItem(item TrashItem) (uint, error) {
+	args := s.Called(item)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (s *MockStore) GetTrashItem(id uint) (TrashItem, error) {
+	args := s.Called(id)
+	return args.Get(0).(TrashItem), args.Error(1)
+}
+
+func (s *MockStore) TrashItems(kind string) ([]TrashItem, error) {
+	args := s.Called(kind)
+	return args.Get(0).([]TrashItem), args.Error(1)
+}
+
+func (s *MockStore) ExpiredTrashItems(before time.Time) ([]TrashItem,
+	error) {
+	args := s.Called(before)
+	return args.Get(0).([]TrashItem), args.Error(1)
+}
+
+func (s *MockStore) RemoveTrashItem(id uint) error {
+	args := s.Called(id)
+	return args.Error(0)
+}
+
+func (s *MockStore) AddAddressChange(ch AddressChange) (uint, error) {
+	args := s.Called(ch)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (s *MockStore) GetAddressChange(id uint) (AddressChange, error) {
+	args := s.Called(id)
+	return args.Get(0).(AddressChange), args.Error(1)
+}
+
+func (s *MockStore) PendingAddressChanges() ([]AddressChange, error) {
+	args := s.Called()
+	return args.Get(0).([]AddressChange), args.Error(1)
+}
+
+func (s *MockStore) CountPendingAddressChanges() (int, error) {
+	args := s.Called()
+	return args.Int(0), args.Error(1)
+}
+
+func (s *MockStore) UserAddressChanges(userID uint) ([]AddressChange,
+	error) {
+	args := s.Called(userID)
+	return args.Get(0).([]AddressChange), args.Error(1)
+}
+
+func (s *MockStore) RecentAddressChanges(limit int) ([]AddressChange,
+	error) {
+	args := s.Called(limit)
+	return args.Get(0).([]AddressChange), args.Error(1)
+}
+
+func (s *MockStore) DecideAddressChange(id uint, status string, by string,
+	eligibleAt time.Time) error {
+	args := s.Called(id, status, by, eligibleAt)
+	return args.Error(0)
+}
+
+func (s *MockStore) ReopenAddressChange(id uint) error {
+	args := s.Called(id)
+	return args.Error(0)
+}
+
+func (s *MockStore) CoolingAddressChanges(at time.Time) ([]AddressChange,
+	error) {
+	args := s.Called(at)
+	return args.Get(0).([]AddressChange), args.Error(1)
+}
+
+func (s *MockStore) PrimaryAddresses(userID uint) (map[string]string,
+	error) {
+	args := s.Called(userID)
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+func (s *MockStore) SetPrimaryAddress(userID uint, coin string,
+	address string, by string) error {
+	args := s.Called(userID, coin, address, by)
+	return args.Error(0)
+}
+
+func (s *MockStore) RemovePrimaryAddress(userID uint, coin string) error {
+	args := s.Called(userID, coin)
+	return args.Error(0)
+}
+
+func (s *MockStore) TrackAddress(userID uint, coin string, address string,
+	by string) error {
+	args := s.Called(userID, coin, address, by)
+	return args.Error(0)
+}
+
+func (s *MockStore) UserAddressesMeta(userID uint) ([]AddressMeta, error) {
+	args := s.Called(userID)
+	return args.Get(0).([]AddressMeta), args.Error(1)
+}
+
+func (s *MockStore) SetAddressNote(userID uint, coin string, address string,
+	label string, note string) error {
+	args := s.Called(userID, coin, address, label, note)
+	return args.Error(0)
+}
+
+func (s *MockStore) SearchAddresses(query string) ([]AddressMeta, error) {
+	args := s.Called(query)
+	return args.Get(0).([]AddressMeta), args.Error(1)
+}
+
+func (s *MockStore) AddWebhook(w Webhook) (uint, error) {
+	args := s.Called(w)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (s *MockStore) Webhooks() ([]Webhook, error) {
+	args := s.Called()
+	return args.Get(0).([]Webhook), args.Error(1)
+}
+
+func (s *MockStore) RemoveWebhook(id uint) error {
+	args := s.Called(id)
+	return args.Error(0)
+}
+
+func (s *MockStore) AddWebhookDeliveries(event string,
+	payload string) (int, error) {
+	args := s.Called(event, payload)
+	return args.Int(0), args.Error(1)
+}
+
+func (s *MockStore) GetWebhookDelivery(id uint) (WebhookDelivery, error) {
+	args := s.Called(id)
+	return args.Get(0).(WebhookDelivery), args.Error(1)
+}
+
+func (s *MockStore) DueWebhookDeliveries(before time.Time,
+	limit int) ([]WebhookDelivery, error) {
+	args := s.Called(before, limit)
+	return args.Get(0).([]WebhookDelivery), args.Error(1)
+}
+
+func (s *MockStore) WebhookDeliveries(limit int) ([]WebhookDelivery,
+	error) {
+	args := s.Called(limit)
+	return args.Get(0).([]WebhookDelivery), args.Error(1)
+}
+
+func (s *MockStore) UpdateWebhookDelivery(d WebhookDelivery) error {
+	args := s.Called(d)
+	return args.Error(0)
+}
+
+func (s *MockStore) AddLoginToken(tokenHash string, userID uint,
+	expiresAt time.Time) error {
+	args := s.Called(tokenHash, userID, expiresAt)
+	return args.Error(0)
+}
+
+func (s *MockStore) UseLoginToken(tokenHash string, at time.Time) (uint,
+	error) {
+	args := s.Called(tokenHash, at)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (s *MockStore) RemoveExpiredLoginTokens(before time.Time) error {
+	args := s.Called(before)
+	return args.Error(0)
+}
+
+func (s *MockStore) RequestLoginLink(email string, at time.Time,
+	since time.Time) (bool, error) {
+	args := s.Called(email, at, since)
+	return args.Bool(0), args.Error(1)
+}
+
+func (s *MockStore) AddWorker(w Worker) (uint, error) {
+	args := s.Called(w)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (s *MockStore) GetWorker(id uint) (Worker, error) {
+	args := s.Called(id)
+	return args.Get(0).(Worker), args.Error(1)
+}
+
+func (s *MockStore) UserWorkers(userID uint) ([]Worker, error) {
+	args := s.Called(userID)
+	return args.Get(0).([]Worker), args.Error(1)
+}
+
+func (s *MockStore) Workers() ([]Worker, error) {
+	args := s.Called()
+	return args.Get(0).([]Worker), args.Error(1)
+}
+
+func (s *MockStore) UpdateWorker(w Worker) error {
+	args := s.Called(w)
+	return args.Error(0)
+}
+
+func (s *MockStore) RemoveWorker(id uint) error {
+	args := s.Called(id)
+	return args.Error(0)
+}
+
+func (s *MockStore) AddWorkerStats(stats []WorkerStat) error {
+	args := s.Called(stats)
+	return args.Error(0)
+}
+
+func (s *MockStore) WorkerHashrates(at time.Time,
+	window time.Duration) ([]WorkerHashrate, error) {
+	args := s.Called(at, window)
+	return args.Get(0).([]WorkerHashrate), args.Error(1)
+}
+
+func (s *MockStore) RemoveWorkerStats(before time.Time) error {
+	args := s.Called(before)
+	return args.Error(0)
+}
+
+func (s *MockStore) AddBalanceSnapshots(snaps []BalanceSnapshot) error {
+	args := s.Called(snaps)
+	return args.Error(0)
+}
+
+func (s *MockStore) BalanceSnapshots(since time.Time) ([]BalanceSnapshot,
+	error) {
+	args := s.Called(since)
+	return args.Get(0).([]BalanceSnapshot), args.Error(1)
+}
+
+func (s *MockStore) RemoveBalanceSnapshots(before time.Time) error {
+	args := s.Called(before)
+	return args.Error(0)
+}
+
+func (s *MockStore) AddAlert(a Alert) (uint, error) {
+	args := s.Called(a)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (s *MockStore) GetAlert(id uint) (Alert, error) {
+	args := s.Called(id)
+	return args.Get(0).(Alert), args.Error(1)
+}
+
+func (s *MockStore) OpenAlerts() ([]Alert, error) {
+	args := s.Called()
+	return args.Get(0).([]Alert), args.Error(1)
+}
+
+func (s *MockStore) ResolvedAlerts(limit int) ([]Alert, error) {
+	args := s.Called(limit)
+	return args.Get(0).([]Alert), args.Error(1)
+}
+
+func (s *MockStore) AcknowledgeAlert(id uint, by string) error {
+	args := s.Called(id, by)
+	return args.Error(0)
+}
+
+func (s *MockStore) ResolveAlert(id uint, at time.Time) error {
+	args := s.Called(id, at)
+	return args.Error(0)
+}
+
+func (s *MockStore) AddReport(r Report) (uint, error) {
+	args := s.Called(r)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (s *MockStore) GetReport(id uint) (Report, error) {
+	args := s.Called(id)
+	return args.Get(0).(Report), args.Error(1)
+}
+
+func (s *MockStore) Reports(limit int) ([]Report, error) {
+	args := s.Called(limit)
+	return args.Get(0).([]Report), args.Error(1)
+}
+
+func (s *MockStore) RemoveReports(before time.Time) error {
+	args := s.Called(before)
+	return args.Error(0)
+}
@@ -0,0 +1,428 @@
+// Package mastore keeps mineradmin own data in tables beside bestore ones.
+// Tables are created by migrations from migration package.
+package mastore
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Store is mineradmin own data storage.
+type Store interface {
+	// WithContext returns store which queries are cancelled when ctx is
+	// done.
+	WithContext(ctx context.Context) Store
+
+	// GetAdminLocale returns admin preferred locale or empty string if admin
+	// has no preference.
+	GetAdminLocale(login string) (string, error)
+	SetAdminLocale(login string, locale string) error
+
+	// TrackProjects creates metadata with default values and current
+	// creation date for projects which have none, it is called when
+	// projects are added.
+	TrackProjects(projectIDs []uint) error
+	// GetProjectMeta returns project metadata, metadata with default values
+	// is returned for untracked project.
+	GetProjectMeta(projectID uint) (ProjectMeta, error)
+	// ProjectsMeta returns metadata of all tracked projects by project ID.
+	ProjectsMeta() (map[uint]ProjectMeta, error)
+	// SetProjectMeta saves project metadata, creation date is kept.
+	SetProjectMeta(meta ProjectMeta) error
+	SetProjectStatus(projectID uint, status ProjectStatus) error
+
+	// AddTrashItem puts item to trash and returns its ID, item DeletedAt is
+	// set to current time.
+	AddTrashItem(item TrashItem) (uint, error)
+	// GetTrashItem returns trash item, ErrNotFound is returned if there is
+	// no such item.
+	GetTrashItem(id uint) (TrashItem, error)
+	// TrashItems returns items of kind, most recently deleted first.
+	TrashItems(kind string) ([]TrashItem, error)
+	// ExpiredTrashItems returns items deleted before time.
+	ExpiredTrashItems(before time.Time) ([]TrashItem, error)
+	RemoveTrashItem(id uint) error
+
+	// AddAddressChange adds pending address change request and returns its
+	// ID.
+	AddAddressChange(ch AddressChange) (uint, error)
+	// GetAddressChange returns address change, ErrNotFound is returned if
+	// there is no such change.
+	GetAddressChange(id uint) (AddressChange, error)
+	// PendingAddressChanges returns pending changes, oldest first.
+	PendingAddressChanges() ([]AddressChange, error)
+	// CountPendingAddressChanges returns number of pending changes.
+	CountPendingAddressChanges() (int, error)
+	// UserAddressChanges returns all user address changes, most recent
+	// first.
+	UserAddressChanges(userID uint) ([]AddressChange, error)
+	// RecentAddressChanges returns at most limit address changes of all
+	// users, most recently requested or decided first.
+	RecentAddressChanges(limit int) ([]AddressChange, error)
+	// DecideAddressChange sets status of pending change, ErrNotFound is
+	// returned if change is not pending anymore. eligibleAt can be zero.
+	DecideAddressChange(id uint, status string, by string,
+		eligibleAt time.Time) error
+	// ReopenAddressChange makes decided change pending again.
+	ReopenAddressChange(id uint) error
+	// CoolingAddressChanges returns approved address additions which are
+	// not eligible for payouts at time yet.
+	CoolingAddressChanges(at time.Time) ([]AddressChange, error)
+
+	// PrimaryAddresses returns user primary payout addresses by coin.
+	PrimaryAddresses(userID uint) (map[string]string, error)
+	SetPrimaryAddress(userID uint, coin string, address string,
+		by string) error
+	RemovePrimaryAddress(userID uint, coin string) error
+
+	// TrackAddress records who added address and sets its creation date to
+	// current time, label and note are kept.
+	TrackAddress(userID uint, coin string, address string, by string) error
+	// UserAddressesMeta returns metadata of user addresses which have it.
+	UserAddressesMeta(userID uint) ([]AddressMeta, error)
+	// SetAddressNote saves address label and note.
+	SetAddressNote(userID uint, coin string, address string, label string,
+		note string) error
+	// SearchAddresses returns metadata of addresses which address, label or
+	// note contains query, case is ignored.
+	SearchAddresses(query string) ([]AddressMeta, error)
+
+	// AddWebhook registers webhook and returns its ID.
+	AddWebhook(w Webhook) (uint, error)
+	// Webhooks returns all webhooks, oldest first.
+	Webhooks() ([]Webhook, error)
+	// RemoveWebhook removes webhook with its deliveries.
+	RemoveWebhook(id uint) error
+	// AddWebhookDeliveries queues event payload delivery to every webhook
+	// subscribed to event and returns number of queued deliveries.
+	AddWebhookDeliveries(event string, payload string) (int, error)
+	// GetWebhookDelivery returns delivery, ErrNotFound is returned if there
+	// is no such delivery.
+	GetWebhookDelivery(id uint) (WebhookDelivery, error)
+	// DueWebhookDeliveries returns at most limit pending deliveries which
+	// next attempt is before time, oldest first.
+	DueWebhookDeliveries(before time.Time, limit int) ([]WebhookDelivery,
+		error)
+	// WebhookDeliveries returns at most limit deliveries, most recent first.
+	WebhookDeliveries(limit int) ([]WebhookDelivery, error)
+	// UpdateWebhookDelivery saves delivery status, attempts and result.
+	UpdateWebhookDelivery(d WebhookDelivery) error
+
+	// AddLoginToken saves hash of portal login token of user.
+	AddLoginToken(tokenHash string, userID uint, expiresAt time.Time) error
+	// UseLoginToken marks login token used and returns its user ID,
+	// ErrNotFound is returned if token is unknown, used or expired at time.
+	UseLoginToken(tokenHash string, at time.Time) (uint, error)
+	// RemoveExpiredLoginTokens removes tokens expired before time.
+	RemoveExpiredLoginTokens(before time.Time) error
+	// RequestLoginLink records that login link is requested for email at
+	// time. False is returned and nothing is recorded if link was requested
+	// for email after since.
+	RequestLoginLink(email string, at time.Time, since time.Time) (bool,
+		error)
+
+	// AddWorker adds user worker and returns its ID.
+	AddWorker(w Worker) (uint, error)
+	// GetWorker returns worker, ErrNotFound is returned if there is no such
+	// worker.
+	GetWorker(id uint) (Worker, error)
+	// UserWorkers returns user workers ordered by name.
+	UserWorkers(userID uint) ([]Worker, error)
+	// Workers returns workers of all users ordered by user ID and name.
+	Workers() ([]Worker, error)
+	// UpdateWorker saves worker name, expected hashrate and location.
+	UpdateWorker(w Worker) error
+	RemoveWorker(id uint) error
+
+	// AddWorkerStats adds stats to buckets with the same key, so reports
+	// of one bucket can be ingested in several batches.
+	AddWorkerStats(stats []WorkerStat) error
+	// WorkerHashrates returns hashrates of workers which reported within
+	// window before time.
+	WorkerHashrates(at time.Time, window time.Duration) ([]WorkerHashrate,
+		error)
+	// RemoveWorkerStats removes buckets started before time.
+	RemoveWorkerStats(before time.Time) error
+
+	AddBalanceSnapshots(snaps []BalanceSnapshot) error
+	// BalanceSnapshots returns snapshots taken after time, oldest first.
+	BalanceSnapshots(since time.Time) ([]BalanceSnapshot, error)
+	// RemoveBalanceSnapshots removes snapshots taken before time.
+	RemoveBalanceSnapshots(before time.Time) error
+
+	// AddAlert opens alert and returns its ID.
+	AddAlert(a Alert) (uint, error)
+	// GetAlert returns alert, ErrNotFound is returned if there is no such
+	// alert.
+	GetAlert(id uint) (Alert, error)
+	// OpenAlerts returns alerts which are not resolved, oldest first.
+	OpenAlerts() ([]Alert, error)
+	// ResolvedAlerts returns at most limit resolved alerts, most recently
+	// resolved first.
+	ResolvedAlerts(limit int) ([]Alert, error)
+	// AcknowledgeAlert marks open alert seen by admin, ErrNotFound is
+	// returned if alert is not open.
+	AcknowledgeAlert(id uint, by string) error
+	ResolveAlert(id uint, at time.Time) error
+
+	// AddReport saves generated report and returns its ID, report CreatedAt
+	// is set to current time.
+	AddReport(r Report) (uint, error)
+	// GetReport returns report with content, ErrNotFound is returned if
+	// there is no such report.
+	GetReport(id uint) (Report, error)
+	// Reports returns at most limit reports without content, most recent
+	// first.
+	Reports(limit int) ([]Report, error)
+	// RemoveReports removes reports created before time.
+	RemoveReports(before time.Time) error
+}
+
+// Address change actions.
+const (
+	AddressAdd    = "add"
+	AddressRemove = "remove"
+
+	// AddressPrimary makes address primary.
+	AddressPrimary = "primary"
+)
+
+// Address change statuses.
+const (
+	ChangePending   = "pending"
+	ChangeApproved  = "approved"
+	ChangeRejected  = "rejected"
+	ChangeCancelled = "cancelled"
+)
+
+// AddressChange is user payout address change request which takes effect
+// after approval by another admin.
+type AddressChange struct {
+	ID          uint
+	UserID      uint
+	Action      string
+	Coin        string
+	Address     string
+	Status      string
+	RequestedBy string
+	RequestedAt time.Time
+	// DecidedBy and DecidedAt are empty for pending change.
+	DecidedBy string
+	DecidedAt time.Time
+	// EligibleAt is when added address can be used for payouts, it is zero
+	// if there is no cooldown.
+	EligibleAt time.Time
+	// Replacement is address which becomes primary when primary address
+	// is removed, it is empty otherwise.
+	Replacement string
+}
+
+// AddressMeta is user address data which bestore does not have.
+type AddressMeta struct {
+	UserID  uint
+	Coin    string
+	Address string
+	// Label is short name which tells user addresses apart.
+	Label string
+	Note  string
+	// CreatedBy and CreatedAt are empty for addresses added before metadata
+	// was introduced.
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// Webhook is endpoint events are delivered to.
+type Webhook struct {
+	ID  uint
+	URL string
+	// Events are types of events webhook is subscribed to.
+	Events []string
+	// Secret is key deliveries are signed with.
+	Secret    string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is event delivery to webhook.
+type WebhookDelivery struct {
+	ID        uint
+	WebhookID uint
+	// URL and Secret are of webhook.
+	URL     string
+	Secret  string
+	Event   string
+	Payload string
+	Status  string
+	// Attempts is number of failed attempts.
+	Attempts int
+	// ResponseCode and LastError are of the last attempt.
+	ResponseCode  int
+	LastError     string
+	CreatedAt     time.Time
+	NextAttemptAt time.Time
+	// DeliveredAt is zero unless delivery is delivered.
+	DeliveredAt time.Time
+}
+
+// Worker is user mining rig.
+type Worker struct {
+	ID     uint
+	UserID uint
+	// Name is rig name pool reports shares of, unique per user ignoring
+	// case.
+	Name string
+	// ExpectedHashrate is hashes per second rig should produce, zero if
+	// unknown.
+	ExpectedHashrate float64
+	// Location is where rig is, e.g. facility and rack.
+	Location  string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// StatsBucket is length of worker stats buckets, bucket starts are
+// truncated to it.
+const StatsBucket = 10 * time.Minute
+
+// CurrentHashrateWindow is how far back reports count for current
+// hashrate.
+const CurrentHashrateWindow = 2 * StatsBucket
+
+// WorkerStat is worker share reports rolled up to bucket.
+type WorkerStat struct {
+	ProjectID   uint
+	UserID      uint
+	Worker      string
+	BucketStart time.Time
+	// Samples is number of hashrate reports and HashrateSum is sum of
+	// their hashrates in hashes per second.
+	Samples     int
+	HashrateSum float64
+	// Accepted and Rejected are numbers of shares.
+	Accepted int64
+	Rejected int64
+}
+
+// WorkerHashrate is worker hashrate in hashes per second.
+type WorkerHashrate struct {
+	ProjectID uint
+	UserID    uint
+	Worker    string
+	// Current is mean hashrate of buckets within CurrentHashrateWindow,
+	// zero if worker did not report then.
+	Current float64
+	// Average is mean hashrate over window, buckets without reports count
+	// as zero.
+	Average float64
+}
+
+// BalanceSnapshot is user balance in project at time, alerts are raised
+// by balance changes between snapshots.
+type BalanceSnapshot struct {
+	ProjectID uint
+	Email     string
+	Coin      string
+	// Amount is decimal string.
+	Amount  string
+	TakenAt time.Time
+}
+
+// Alert kinds.
+const (
+	// AlertStale is raised when user balance does not grow.
+	AlertStale = "stale"
+	// AlertAnomaly is raised when user balance grows much faster or slower
+	// than usual.
+	AlertAnomaly = "anomaly"
+)
+
+// Alert is raised about user balance in project coin. There is at most one
+// open alert of kind per user balance.
+type Alert struct {
+	ID          uint
+	Kind        string
+	ProjectID   uint
+	ProjectName string
+	Email       string
+	Coin        string
+	// Details tells why alert is raised.
+	Details  string
+	OpenedAt time.Time
+	// AcknowledgedBy and AcknowledgedAt are empty unless admin saw alert.
+	AcknowledgedBy string
+	AcknowledgedAt time.Time
+	// ResolvedAt is zero for open alert.
+	ResolvedAt time.Time
+}
+
+// Report is generated report, Content is in Format, e.g. "csv".
+type Report struct {
+	ID        uint
+	Name      string
+	Format    string
+	Content   []byte
+	Size      int
+	CreatedAt time.Time
+}
+
+// ErrNotFound is returned when requested entity does not exist.
+var ErrNotFound = errors.New("not found")
+
+// TrashItem is soft deleted entity.
+type TrashItem struct {
+	ID   uint
+	Kind string
+	// EntityID is ID of deleted entity or of its owner if entity has no ID.
+	EntityID uint
+	// Name is entity name shown to admins.
+	Name string
+	// Payload is data needed to restore entity.
+	Payload   string
+	DeletedBy string
+	DeletedAt time.Time
+}
+
+// ProjectStatus is project lifecycle status.
+type ProjectStatus string
+
+const (
+	ProjectActive   ProjectStatus = "active"
+	ProjectPaused   ProjectStatus = "paused"
+	ProjectArchived ProjectStatus = "archived"
+)
+
+// ProjectStatuses lists all project statuses.
+var ProjectStatuses = []ProjectStatus{ProjectActive, ProjectPaused,
+	ProjectArchived}
+
+// Valid checks that status is one of ProjectStatuses.
+func (s ProjectStatus) Valid() bool {
+	for _, ps := range ProjectStatuses {
+		if s == ps {
+			return true
+		}
+	}
+	return false
+}
+
+// ProjectMeta is project data which bestore does not have.
+type ProjectMeta struct {
+	ProjectID   uint
+	Description string
+	// Owner is project owner contact, e.g. email or phone.
+	Owner  string
+	Status ProjectStatus
+	// Coins are symbols of coins project targets.
+	Coins []string
+	// CreatedAt is zero for untracked project, projects created before
+	// metadata was introduced have date they were tracked first.
+	CreatedAt time.Time
+}
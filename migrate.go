@@ -0,0 +1,108 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/boomstarternetwork/mineradmin/migration"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var migrateCommand = cli.Command{
+	Name:  "migrate",
+	Usage: "manage mineradmin database schema",
+	Subcommands: []cli.Command{
+		{
+			Name:   "up",
+			Usage:  "apply all pending migrations",
+			Action: migrateUp,
+			Flags:  []cli.Flag{postgresFlag},
+		},
+		{
+			Name:   "down",
+			Usage:  "revert last applied migrations",
+			Action: migrateDown,
+			Flags: []cli.Flag{
+				postgresFlag,
+				cli.IntFlag{
+					Name:  "steps, n",
+					Usage: "number of migrations to revert",
+					Value: 1,
+				},
+			},
+		},
+		{
+			Name:   "status",
+			Usage:  "show migrations status",
+			Action: migrateStatus,
+			Flags:  []cli.Flag{postgresFlag},
+		},
+	},
+}
+
+func openDB(c *cli.Context) (*sql.DB, error) {
+	db, err := sql.Open("postgres", c.String("postgres-cs"))
+	if err != nil {
+		return nil, cli.NewExitError("failed to open DB: "+err.Error(), 5)
+	}
+	return db, nil
+}
+
+func migrateUp(c *cli.Context) error {
+	db, err := openDB(c)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ms, err := migration.Up(db)
+	for _, m := range ms {
+		fmt.Printf("Applied: %d_%s\n", m.Version, m.Name)
+	}
+	return err
+}
+
+func migrateDown(c *cli.Context) error {
+	steps := c.Int("steps")
+	if steps < 1 {
+		return errors.New("invalid steps number")
+	}
+
+	db, err := openDB(c)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ms, err := migration.Down(db, steps)
+	for _, m := range ms {
+		fmt.Printf("Reverted: %d_%s\n", m.Version, m.Name)
+	}
+	return err
+}
+
+func migrateStatus(c *cli.Context) error {
+	db, err := openDB(c)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ss, err := migration.GetStatus(db)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, s := range ss {
+		applied := "pending"
+		if s.Applied {
+			applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,204 @@
+package migration
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Migration is versioned change of mineradmin own database tables. Tables
+// managed by bestore are not touched by migrations.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is migration with its applied state.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+const versionsTable = "mineradmin_schema_migrations"
+
+// ErrOutdated is returned by Check when database has pending migrations.
+var ErrOutdated = errors.New("database schema is out of date, " +
+	"run migrate up command")
+
+// List returns all known migrations sorted by version.
+func List() []Migration {
+	ms := make([]Migration, len(migrations))
+	copy(ms, migrations)
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].Version < ms[j].Version
+	})
+	return ms
+}
+
+func ensureVersionsTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + versionsTable + ` (
+		version integer PRIMARY KEY,
+		applied_at timestamp with time zone NOT NULL DEFAULT now()
+	)`)
+	return err
+}
+
+func appliedVersions(db *sql.DB) (map[uint]time.Time, error) {
+	var exists bool
+	err := db.QueryRow(`SELECT to_regclass($1) IS NOT NULL`,
+		versionsTable).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[uint]time.Time{}
+
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := db.Query(`SELECT version, applied_at FROM ` + versionsTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			version   uint
+			appliedAt time.Time
+		)
+		err = rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// GetStatus returns all migrations with their applied state.
+func GetStatus(db *sql.DB) ([]Status, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, errors.New("failed to get applied migrations: " +
+			err.Error())
+	}
+
+	var ss []Status
+
+	for _, m := range List() {
+		appliedAt, ok := applied[m.Version]
+		ss = append(ss, Status{
+			Migration: m,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return ss, nil
+}
+
+// Check returns ErrOutdated if any migration is not applied.
+func Check(db *sql.DB) error {
+	ss, err := GetStatus(db)
+	if err != nil {
+		return err
+	}
+	for _, s := range ss {
+		if !s.Applied {
+			return ErrOutdated
+		}
+	}
+	return nil
+}
+
+func apply(db *sql.DB, query string, record string, version uint) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(record, version)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Up applies all pending migrations in version order and returns applied
+// ones.
+func Up(db *sql.DB) ([]Migration, error) {
+	err := ensureVersionsTable(db)
+	if err != nil {
+		return nil, errors.New("failed to create migrations table: " +
+			err.Error())
+	}
+
+	ss, err := GetStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+
+	for _, s := range ss {
+		if s.Applied {
+			continue
+		}
+
+		err = apply(db, s.Up, `INSERT INTO `+versionsTable+
+			` (version) VALUES ($1)`, s.Version)
+		if err != nil {
+			return done, fmt.Errorf("failed to apply migration %d_%s: %v",
+				s.Version, s.Name, err)
+		}
+
+		done = append(done, s.Migration)
+	}
+
+	return done, nil
+}
+
+// Down reverts up to steps last applied migrations and returns reverted
+// ones.
+func Down(db *sql.DB, steps int) ([]Migration, error) {
+	ss, err := GetStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+
+	for i := len(ss) - 1; i >= 0 && len(done) < steps; i-- {
+		s := ss[i]
+		if !s.Applied {
+			continue
+		}
+
+		err = apply(db, s.Down, `DELETE FROM `+versionsTable+
+			` WHERE version = $1`, s.Version)
+		if err != nil {
+			return done, fmt.Errorf("failed to revert migration %d_%s: %v",
+				s.Version, s.Name, err)
+		}
+
+		done = append(done, s.Migration)
+	}
+
+	return done, nil
+}
@@ -0,0 +1,39 @@
+package migration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_migrations(t *testing.T) {
+	versions := map[uint]bool{}
+
+	for _, m := range migrations {
+		assert.NotZero(t, m.Version)
+		assert.False(t, versions[m.Version], "duplicate version %d",
+			m.Version)
+		assert.NotEmpty(t, strings.TrimSpace(m.Name))
+		assert.NotEmpty(t, strings.TrimSpace(m.Up))
+		assert.NotEmpty(t, strings.TrimSpace(m.Down))
+
+		versions[m.Version] = true
+	}
+}
+
+func Test_List(t *testing.T) {
+	saved := migrations
+	defer func() { migrations = saved }()
+
+	migrations = []Migration{{Version: 2}, {Version: 1}, {Version: 3}}
+
+	ms := List()
+
+	if assert.Len(t, ms, 3) {
+		assert.Equal(t, uint(1), ms[0].Version)
+		assert.Equal(t, uint(2), ms[1].Version)
+		assert.Equal(t, uint(3), ms[2].Version)
+	}
+	assert.Equal(t, uint(2), migrations[0].Version)
+}
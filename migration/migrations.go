@@ -0,0 +1,259 @@
+package migration
+
+// migrations are mineradmin database schema changes. Released migrations
+// must never be edited, add new one with next version instead.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "admin_settings",
+		Up: `CREATE TABLE admin_settings (
+			login text PRIMARY KEY,
+			locale text NOT NULL DEFAULT ''
+		)`,
+		Down: `DROP TABLE admin_settings`,
+	},
+	{
+		Version: 2,
+		Name:    "project_meta",
+		Up: `CREATE TABLE project_meta (
+			project_id bigint PRIMARY KEY,
+			description text NOT NULL DEFAULT '',
+			owner text NOT NULL DEFAULT '',
+			status text NOT NULL DEFAULT 'active'
+				CHECK (status IN ('active', 'paused', 'archived')),
+			coins text[] NOT NULL DEFAULT '{}',
+			created_at timestamptz NOT NULL DEFAULT now()
+		)`,
+		Down: `DROP TABLE project_meta`,
+	},
+	{
+		Version: 3,
+		Name:    "trash",
+		Up: `CREATE TABLE trash (
+			id bigserial PRIMARY KEY,
+			kind text NOT NULL,
+			entity_id bigint NOT NULL,
+			name text NOT NULL,
+			payload text NOT NULL DEFAULT '',
+			deleted_by text NOT NULL,
+			deleted_at timestamptz NOT NULL DEFAULT now()
+		);
+		CREATE INDEX trash_kind_idx ON trash (kind, deleted_at)`,
+		Down: `DROP TABLE trash`,
+	},
+	{
+		Version: 4,
+		Name:    "address_changes",
+		Up: `CREATE TABLE address_changes (
+			id bigserial PRIMARY KEY,
+			user_id bigint NOT NULL,
+			action text NOT NULL CHECK (action IN ('add', 'remove')),
+			coin text NOT NULL,
+			address text NOT NULL,
+			status text NOT NULL DEFAULT 'pending'
+				CHECK (status IN ('pending', 'approved', 'rejected',
+					'cancelled')),
+			requested_by text NOT NULL,
+			requested_at timestamptz NOT NULL DEFAULT now(),
+			decided_by text NOT NULL DEFAULT '',
+			decided_at timestamptz,
+			eligible_at timestamptz
+		);
+		CREATE INDEX address_changes_user_idx
+			ON address_changes (user_id, requested_at);
+		CREATE INDEX address_changes_pending_idx
+			ON address_changes (requested_at) WHERE status = 'pending'`,
+		Down: `DROP TABLE address_changes`,
+	},
+	{
+		Version: 5,
+		Name:    "primary_addresses",
+		Up: `CREATE TABLE primary_addresses (
+			user_id bigint NOT NULL,
+			coin text NOT NULL,
+			address text NOT NULL,
+			set_by text NOT NULL,
+			set_at timestamptz NOT NULL DEFAULT now(),
+			PRIMARY KEY (user_id, coin)
+		)`,
+		Down: `DROP TABLE primary_addresses`,
+	},
+	{
+		Version: 6,
+		Name:    "address_meta",
+		Up: `CREATE TABLE address_meta (
+			user_id bigint NOT NULL,
+			coin text NOT NULL,
+			address text NOT NULL,
+			label text NOT NULL DEFAULT '',
+			note text NOT NULL DEFAULT '',
+			created_by text NOT NULL DEFAULT '',
+			created_at timestamptz,
+			PRIMARY KEY (user_id, coin, address)
+		)`,
+		Down: `DROP TABLE address_meta`,
+	},
+	{
+		Version: 7,
+		Name:    "webhooks",
+		Up: `CREATE TABLE webhooks (
+			id bigserial PRIMARY KEY,
+			url text NOT NULL,
+			events text[] NOT NULL,
+			secret text NOT NULL,
+			created_by text NOT NULL,
+			created_at timestamptz NOT NULL DEFAULT now()
+		);
+		CREATE TABLE webhook_deliveries (
+			id bigserial PRIMARY KEY,
+			webhook_id bigint NOT NULL
+				REFERENCES webhooks (id) ON DELETE CASCADE,
+			event text NOT NULL,
+			payload text NOT NULL,
+			status text NOT NULL DEFAULT 'pending'
+				CHECK (status IN ('pending', 'delivered', 'failed')),
+			attempts integer NOT NULL DEFAULT 0,
+			response_code integer NOT NULL DEFAULT 0,
+			last_error text NOT NULL DEFAULT '',
+			created_at timestamptz NOT NULL DEFAULT now(),
+			next_attempt_at timestamptz NOT NULL DEFAULT now(),
+			delivered_at timestamptz
+		);
+		CREATE INDEX webhook_deliveries_due
+			ON webhook_deliveries (next_attempt_at)
+			WHERE status = 'pending'`,
+		Down: `DROP TABLE webhook_deliveries;
+		DROP TABLE webhooks`,
+	},
+	{
+		Version: 8,
+		Name:    "portal_login_tokens",
+		Up: `CREATE TABLE portal_login_tokens (
+			token_hash text PRIMARY KEY,
+			user_id bigint NOT NULL,
+			created_at timestamptz NOT NULL DEFAULT now(),
+			expires_at timestamptz NOT NULL,
+			used_at timestamptz
+		)`,
+		Down: `DROP TABLE portal_login_tokens`,
+	},
+	{
+		Version: 9,
+		Name:    "workers",
+		Up: `CREATE TABLE workers (
+			id bigserial PRIMARY KEY,
+			user_id bigint NOT NULL,
+			name text NOT NULL,
+			expected_hashrate double precision NOT NULL DEFAULT 0,
+			location text NOT NULL DEFAULT '',
+			created_by text NOT NULL DEFAULT '',
+			created_at timestamptz NOT NULL DEFAULT now()
+		);
+		CREATE UNIQUE INDEX workers_user_id_name_idx
+			ON workers (user_id, lower(name))`,
+		Down: `DROP TABLE workers`,
+	},
+	{
+		Version: 10,
+		Name:    "worker_stats",
+		Up: `CREATE TABLE worker_stats (
+			project_id bigint NOT NULL,
+			user_id bigint NOT NULL,
+			worker text NOT NULL,
+			bucket_start timestamptz NOT NULL,
+			samples integer NOT NULL,
+			hashrate_sum double precision NOT NULL,
+			accepted bigint NOT NULL DEFAULT 0,
+			rejected bigint NOT NULL DEFAULT 0,
+			PRIMARY KEY (project_id, user_id, worker, bucket_start)
+		);
+		CREATE INDEX worker_stats_bucket_start_idx
+			ON worker_stats (bucket_start)`,
+		Down: `DROP TABLE worker_stats`,
+	},
+	{
+		Version: 11,
+		Name:    "alerts",
+		Up: `CREATE TABLE balance_snapshots (
+			project_id bigint NOT NULL,
+			email text NOT NULL,
+			coin text NOT NULL,
+			amount numeric NOT NULL,
+			taken_at timestamptz NOT NULL
+		);
+		CREATE INDEX balance_snapshots_taken_at_idx
+			ON balance_snapshots (taken_at);
+		CREATE TABLE alerts (
+			id bigserial PRIMARY KEY,
+			kind text NOT NULL,
+			project_id bigint NOT NULL,
+			project_name text NOT NULL DEFAULT '',
+			email text NOT NULL,
+			coin text NOT NULL,
+			details text NOT NULL DEFAULT '',
+			opened_at timestamptz NOT NULL DEFAULT now(),
+			acknowledged_by text NOT NULL DEFAULT '',
+			acknowledged_at timestamptz,
+			resolved_at timestamptz
+		);
+		CREATE UNIQUE INDEX alerts_open_idx
+			ON alerts (kind, project_id, email, coin)
+			WHERE resolved_at IS NULL`,
+		Down: `DROP TABLE alerts;
+		DROP TABLE balance_snapshots`,
+	},
+	{
+		Version: 12,
+		Name:    "reports",
+		Up: `CREATE TABLE reports (
+			id bigserial PRIMARY KEY,
+			name text NOT NULL,
+			format text NOT NULL,
+			content bytea NOT NULL,
+			created_at timestamptz NOT NULL DEFAULT now()
+		);
+		CREATE INDEX reports_created_at_idx ON reports (created_at)`,
+		Down: `DROP TABLE reports`,
+	},
+	{
+		Version: 13,
+		Name:    "address_change_primary",
+		Up: `ALTER TABLE address_changes
+			DROP CONSTRAINT address_changes_action_check,
+			ADD CONSTRAINT address_changes_action_check
+				CHECK (action IN ('add', 'remove', 'primary')),
+			ADD COLUMN replacement text NOT NULL DEFAULT ''`,
+		Down: `DELETE FROM address_changes WHERE action = 'primary';
+		ALTER TABLE address_changes
+			DROP COLUMN replacement,
+			DROP CONSTRAINT address_changes_action_check,
+			ADD CONSTRAINT address_changes_action_check
+				CHECK (action IN ('add', 'remove'))`,
+	},
+	{
+		// Projects are tracked when added, projects added before get
+		// migration time as creation date.
+		Version: 14,
+		Name:    "project_meta_backfill",
+		Up: `DO $$
+		BEGIN
+			IF to_regclass('projects') IS NOT NULL THEN
+				INSERT INTO project_meta (project_id)
+					SELECT id FROM projects
+					ON CONFLICT (project_id) DO NOTHING;
+			END IF;
+		END
+		$$`,
+		// Backfilled metadata can not be told from recorded one.
+		Down: `SELECT 1`,
+	},
+	{
+		Version: 15,
+		Name:    "portal_login_requests",
+		Up: `CREATE TABLE portal_login_requests (
+			email text PRIMARY KEY,
+			requested_at timestamptz NOT NULL
+		)`,
+		Down: `DROP TABLE portal_login_requests`,
+	},
+}
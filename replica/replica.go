@@ -0,0 +1,158 @@
+// Package replica sends heavy bestore reads to read replica of pool
+// database, so balances aggregation does not compete with pool writes on
+// primary. Only balances are read from replica: admins read users and
+// addresses right after changing them and must not see them stale.
+// Balances list projects, so they are read from primary for max lag after
+// projects are changed. Reads fall back to primary while replica lags or
+// fails.
+package replica
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+)
+
+// LagFunc returns how far replica is behind primary.
+type LagFunc func() (time.Duration, error)
+
+// DBLag returns LagFunc of Postgres streaming replica. Replica which
+// replayed everything it received is not lagging, however long ago primary
+// was written.
+func DBLag(db *sql.DB) LagFunc {
+	return func() (time.Duration, error) {
+		var seconds float64
+		err := db.QueryRow(`
+			SELECT CASE
+				WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn()
+				THEN 0
+				ELSE COALESCE(EXTRACT(EPOCH FROM
+					now() - pg_last_xact_replay_timestamp()), 0)
+			END`).Scan(&seconds)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+}
+
+// Store is bestore.Store which reads ProjectsBalances and
+// ProjectUsersBalances from replica while it is healthy. Other methods are
+// passed to primary store.
+type Store struct {
+	bestore.Store
+	replica bestore.Store
+	lag     LagFunc
+	maxLag  time.Duration
+
+	mutex   sync.Mutex
+	healthy bool
+	// changedAt is when projects were changed last time.
+	changedAt time.Time
+}
+
+// NewStore returns store which reads from primary until Check finds replica
+// healthy.
+func NewStore(primary bestore.Store, replica bestore.Store, lag LagFunc,
+	maxLag time.Duration) *Store {
+	return &Store{
+		Store:   primary,
+		replica: replica,
+		lag:     lag,
+		maxLag:  maxLag,
+	}
+}
+
+// Check measures replica lag, replica is healthy if it is reachable and
+// lags at most max lag.
+func (s *Store) Check() (time.Duration, error) {
+	lag, err := s.lag()
+	s.setHealthy(err == nil && lag <= s.maxLag)
+	return lag, err
+}
+
+// Healthy reports whether reads are sent to replica.
+func (s *Store) Healthy() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.healthy
+}
+
+func (s *Store) setHealthy(healthy bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.healthy = healthy
+}
+
+// fresh reports whether replica may miss projects changes, healthy replica
+// replays them in max lag.
+func (s *Store) fresh() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return time.Since(s.changedAt) < s.maxLag
+}
+
+// changed remembers that projects were changed.
+func (s *Store) changed() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.changedAt = time.Now()
+}
+
+// read calls f with replica if it is healthy and projects were not changed
+// recently. f is called again with primary if replica fails, replica is not
+// read then until next check.
+func (s *Store) read(f func(bs bestore.Store) error) error {
+	if s.Healthy() && !s.fresh() {
+		err := f(s.replica)
+		if err == nil {
+			return nil
+		}
+		s.setHealthy(false)
+	}
+	return f(s.Store)
+}
+
+// AddProject sends balances reads to primary for a while, new project is
+// listed in them.
+func (s *Store) AddProject(name string) error {
+	defer s.changed()
+	return s.Store.AddProject(name)
+}
+
+func (s *Store) SetProjectName(id uint, name string) error {
+	defer s.changed()
+	return s.Store.SetProjectName(id, name)
+}
+
+func (s *Store) RemoveProject(id uint) error {
+	defer s.changed()
+	return s.Store.RemoveProject(id)
+}
+
+func (s *Store) ProjectsBalances() ([]bestore.ProjectBalance, error) {
+	var pbs []bestore.ProjectBalance
+	err := s.read(func(bs bestore.Store) (err error) {
+		pbs, err = bs.ProjectsBalances()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pbs, nil
+}
+
+func (s *Store) ProjectUsersBalances(projectID uint) ([]bestore.UserBalance,
+	error) {
+	var ubs []bestore.UserBalance
+	err := s.read(func(bs bestore.Store) (err error) {
+		ubs, err = bs.ProjectUsersBalances(projectID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ubs, nil
+}
@@ -0,0 +1,128 @@
+package replica
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestStore returns routing store which replica lag is set by returned
+// func.
+func newTestStore() (*bestore.MockStore, *bestore.MockStore, *Store,
+	func(lag time.Duration, err error)) {
+	primary := bestore.NewMockStore()
+	replica := bestore.NewMockStore()
+
+	var (
+		lag    time.Duration
+		lagErr error
+	)
+
+	s := NewStore(primary, replica, func() (time.Duration, error) {
+		return lag, lagErr
+	}, 10*time.Second)
+
+	return primary, replica, s, func(l time.Duration, err error) {
+		lag, lagErr = l, err
+	}
+}
+
+func Test_Store_ProjectsBalances(t *testing.T) {
+	primary, replica, s, setLag := newTestStore()
+
+	primary.On("ProjectsBalances").Return([]bestore.ProjectBalance{
+		{ProjectID: 1, ProjectName: "primary"}}, nil)
+	replica.On("ProjectsBalances").Return([]bestore.ProjectBalance{
+		{ProjectID: 1, ProjectName: "replica"}}, nil)
+
+	// Replica is not read before it is checked.
+	pbs, err := s.ProjectsBalances()
+	assert.NoError(t, err)
+	assert.Equal(t, "primary", pbs[0].ProjectName)
+
+	setLag(time.Second, nil)
+	s.Check()
+
+	pbs, err = s.ProjectsBalances()
+	assert.NoError(t, err)
+	assert.Equal(t, "replica", pbs[0].ProjectName)
+
+	setLag(time.Minute, nil)
+	s.Check()
+
+	pbs, err = s.ProjectsBalances()
+	assert.NoError(t, err)
+	assert.Equal(t, "primary", pbs[0].ProjectName)
+
+	setLag(0, errors.New("connection refused"))
+	_, err = s.Check()
+	assert.Error(t, err)
+	assert.False(t, s.Healthy())
+}
+
+func Test_Store_ProjectUsersBalances_fallback(t *testing.T) {
+	primary, replica, s, setLag := newTestStore()
+
+	setLag(0, nil)
+	s.Check()
+
+	replica.On("ProjectUsersBalances", uint(1)).Return(
+		[]bestore.UserBalance(nil), errors.New("connection reset")).Once()
+	primary.On("ProjectUsersBalances", uint(1)).Return(
+		[]bestore.UserBalance{{Email: "a@example.com"}}, nil)
+
+	ubs, err := s.ProjectUsersBalances(1)
+	assert.NoError(t, err)
+	assert.Len(t, ubs, 1)
+
+	// Failed replica is not read until next check.
+	assert.False(t, s.Healthy())
+
+	_, err = s.ProjectUsersBalances(1)
+	assert.NoError(t, err)
+
+	replica.AssertNumberOfCalls(t, "ProjectUsersBalances", 1)
+	primary.AssertNumberOfCalls(t, "ProjectUsersBalances", 2)
+}
+
+func Test_Store_writes(t *testing.T) {
+	primary, _, s, setLag := newTestStore()
+
+	setLag(0, nil)
+	s.Check()
+
+	primary.On("AddProject", "Alpha").Return(nil)
+
+	assert.NoError(t, s.AddProject("Alpha"))
+
+	primary.AssertExpectations(t)
+}
+
+func Test_Store_ProjectsBalances_afterProjectChange(t *testing.T) {
+	primary, replica, s, setLag := newTestStore()
+
+	primary.On("AddProject", "new").Return(nil)
+	primary.On("ProjectsBalances").Return([]bestore.ProjectBalance{
+		{ProjectID: 1, ProjectName: "primary"}}, nil)
+	replica.On("ProjectsBalances").Return([]bestore.ProjectBalance{
+		{ProjectID: 1, ProjectName: "replica"}}, nil)
+
+	setLag(time.Second, nil)
+	s.Check()
+
+	assert.NoError(t, s.AddProject("new"))
+
+	// Replica may not have replayed new project yet.
+	pbs, err := s.ProjectsBalances()
+	assert.NoError(t, err)
+	assert.Equal(t, "primary", pbs[0].ProjectName)
+
+	s.changedAt = time.Now().Add(-s.maxLag)
+
+	pbs, err = s.ProjectsBalances()
+	assert.NoError(t, err)
+	assert.Equal(t, "replica", pbs[0].ProjectName)
+}
@@ -0,0 +1,162 @@
+package report
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is cron expression: minute, hour, day of month, month and day of
+// week fields, e.g. "0 6 * * 1" is every Monday at 06:00. Fields are
+// numbers, ranges, lists and steps, e.g. "*/15", "1-5" or "0,30". Sunday is
+// 0 or 7. Day matches if either day field matches, unless one of them is
+// "*". @hourly, @daily, @weekly and @monthly are shortcuts.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are true if day fields are "*".
+	domAny, dowAny bool
+}
+
+var shortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// ParseSchedule parses cron expression.
+func ParseSchedule(expr string) (Schedule, error) {
+	if full, ok := shortcuts[expr]; ok {
+		expr = full
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Schedule{}, errors.New("invalid schedule " + expr +
+			": expected 5 fields")
+	}
+
+	var (
+		s   Schedule
+		err error
+	)
+
+	bounds := []struct {
+		set      *uint64
+		min, max uint
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dom, 1, 31},
+		{&s.month, 1, 12},
+		{&s.dow, 0, 7},
+	}
+
+	for i, b := range bounds {
+		*b.set, err = parseField(fields[i], b.min, b.max)
+		if err != nil {
+			return Schedule{}, errors.New("invalid schedule " + expr + ": " +
+				err.Error())
+		}
+	}
+
+	// Sunday is both 0 and 7.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	s.domAny = fields[2] == "*"
+	s.dowAny = fields[4] == "*"
+
+	return s, nil
+}
+
+// parseField parses field into set of values bits.
+func parseField(field string, min uint, max uint) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, uint(1)
+
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.ParseUint(part[i+1:], 10, 8)
+			if err != nil || n == 0 {
+				return 0, errors.New("invalid step in " + part)
+			}
+			rng, step = part[:i], uint(n)
+		}
+
+		from, to := min, max
+
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+
+			n, err := strconv.ParseUint(bounds[0], 10, 8)
+			if err != nil {
+				return 0, errors.New("invalid value in " + part)
+			}
+			from, to = uint(n), uint(n)
+
+			if len(bounds) == 2 {
+				n, err = strconv.ParseUint(bounds[1], 10, 8)
+				if err != nil {
+					return 0, errors.New("invalid value in " + part)
+				}
+				to = uint(n)
+			} else if step > 1 {
+				to = max
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return 0, errors.New(part + " is out of range")
+		}
+
+		for v := from; v <= to; v += step {
+			set |= 1 << v
+		}
+	}
+
+	return set, nil
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<uint(v)) != 0
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns first time after t which matches schedule, zero time is
+// returned if there is none in five years, e.g. for February 30.
+func (s Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case !has(s.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0,
+				t.Location())
+		case !has(s.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0,
+				t.Location())
+		case !has(s.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
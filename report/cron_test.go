@@ -0,0 +1,38 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Schedule_Next(t *testing.T) {
+	// Monday.
+	at := time.Date(2018, 10, 8, 12, 30, 0, 0, time.UTC)
+
+	for expr, next := range map[string]time.Time{
+		"0 6 * * 1":    time.Date(2018, 10, 15, 6, 0, 0, 0, time.UTC),
+		"*/15 * * * *": time.Date(2018, 10, 8, 12, 45, 0, 0, time.UTC),
+		"30 12 * * *":  time.Date(2018, 10, 9, 12, 30, 0, 0, time.UTC),
+		"0 0 1 * *":    time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC),
+		"0 9 1-5 * 7":  time.Date(2018, 10, 14, 9, 0, 0, 0, time.UTC),
+		"0 9,18 * 1 *": time.Date(2019, 1, 1, 9, 0, 0, 0, time.UTC),
+		"@weekly":      time.Date(2018, 10, 14, 0, 0, 0, 0, time.UTC),
+		"0 0 30 2 *":   {},
+	} {
+		s, err := ParseSchedule(expr)
+		if assert.NoError(t, err, expr) {
+			assert.Equal(t, next, s.Next(at), expr)
+		}
+	}
+}
+
+func Test_ParseSchedule_invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *",
+		"* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *",
+		"x * * * *", "* * * * 8"} {
+		_, err := ParseSchedule(expr)
+		assert.Error(t, err, expr)
+	}
+}
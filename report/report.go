@@ -0,0 +1,307 @@
+// Package report generates balances reports on schedule: balances of
+// projects and of their users as CSV or HTML. Reports are stored in mastore
+// for download and optionally emailed.
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mail"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/trash"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Report formats.
+const (
+	CSV  = "csv"
+	HTML = "html"
+)
+
+// ContentType returns content type of report format.
+func ContentType(format string) string {
+	if format == HTML {
+		return "text/html; charset=utf-8"
+	}
+	return "text/csv"
+}
+
+// FileName returns name report is downloaded and attached with.
+func FileName(r mastore.Report) string {
+	return r.Name + "-" + r.CreatedAt.Format("2006-01-02") + "." + r.Format
+}
+
+// Job is scheduled report.
+type Job struct {
+	// Name is a part of report file names.
+	Name     string `yaml:"name"`
+	Schedule string `yaml:"schedule"`
+	Format   string `yaml:"format"`
+	// Pool is name of pool report is generated of, primary pool is used if
+	// it is empty.
+	Pool string `yaml:"pool"`
+	// Emails receive report, report is only stored if there are none.
+	Emails []string `yaml:"emails"`
+
+	schedule Schedule
+}
+
+// Next returns first time after t job is run at.
+func (j Job) Next(t time.Time) time.Time {
+	return j.schedule.Next(t)
+}
+
+var jobNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// jobsDoc is reports file, e.g.:
+//
+//	reports:
+//	  - name: weekly-balances
+//	    schedule: "0 6 * * 1"
+//	    format: csv
+//	    emails:
+//	      - finance@example.com
+type jobsDoc struct {
+	Reports []Job `yaml:"reports"`
+}
+
+// LoadJobs loads jobs from YAML file.
+func LoadJobs(path string) ([]Job, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc jobsDoc
+
+	err = yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+
+	for i, j := range doc.Reports {
+		if !jobNameRe.MatchString(j.Name) {
+			return nil, fmt.Errorf("invalid report name %q", j.Name)
+		}
+		if names[j.Name] {
+			return nil, errors.New("duplicate report " + j.Name)
+		}
+		names[j.Name] = true
+
+		if j.Format != CSV && j.Format != HTML {
+			return nil, fmt.Errorf("invalid report %s format %q", j.Name,
+				j.Format)
+		}
+
+		doc.Reports[i].schedule, err = ParseSchedule(j.Schedule)
+		if err != nil {
+			return nil, errors.New("invalid report " + j.Name + ": " +
+				err.Error())
+		}
+	}
+
+	return doc.Reports, nil
+}
+
+// Row is user balance of coin in project, Email is empty in rows of
+// project balance.
+type Row struct {
+	ProjectID   uint
+	ProjectName string
+	Email       string
+	Coin        string
+	Amount      string
+}
+
+// Rows returns balances of projects which are not in trash, each project
+// balance is followed by balances of its users.
+func Rows(s bestore.Store, ms mastore.Store) ([]Row, error) {
+	hidden, err := trash.NewBin(s, ms).Hidden(trash.Projects)
+	if err != nil {
+		return nil, errors.New("failed to get trashed projects from DB: " +
+			err.Error())
+	}
+
+	pbs, err := s.ProjectsBalances()
+	if err != nil {
+		return nil, errors.New("failed to get projects balances from DB: " +
+			err.Error())
+	}
+
+	var rows []Row
+
+	for _, pb := range pbs {
+		if hidden[pb.ProjectID] {
+			continue
+		}
+
+		for _, ca := range pb.Coins {
+			rows = append(rows, Row{
+				ProjectID:   pb.ProjectID,
+				ProjectName: pb.ProjectName,
+				Coin:        fmt.Sprintf("%s", ca.Coin),
+				Amount:      ca.Amount,
+			})
+		}
+
+		ubs, err := s.ProjectUsersBalances(pb.ProjectID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get project %d users "+
+				"balances from DB: %v", pb.ProjectID, err)
+		}
+
+		for _, ub := range ubs {
+			for _, ca := range ub.Coins {
+				rows = append(rows, Row{
+					ProjectID:   pb.ProjectID,
+					ProjectName: pb.ProjectName,
+					Email:       ub.Email,
+					Coin:        fmt.Sprintf("%s", ca.Coin),
+					Amount:      ca.Amount,
+				})
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+func writeCSV(rows []Row) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+
+	err := w.Write([]string{"project_id", "project", "email", "coin",
+		"amount"})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range rows {
+		err := w.Write([]string{strconv.FormatUint(uint64(r.ProjectID), 10),
+			r.ProjectName, r.Email, r.Coin, r.Amount})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>{{.Name}} {{.At.Format "2006-01-02 15:04 MST"}}</title>
+    <style>
+        td, th { padding: 0.2em 0.5em; text-align: left; }
+        tr.project { font-weight: bold; }
+    </style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p>Generated at {{.At.Format "2006-01-02 15:04 MST"}}</p>
+<table>
+    <tr><th>Project</th><th>User</th><th>Coin</th><th>Amount</th></tr>
+    {{range .Rows}}
+    <tr{{if not .Email}} class="project"{{end}}>
+        <td>{{or .ProjectName .ProjectID}}</td>
+        <td>{{.Email}}</td>
+        <td>{{.Coin}}</td>
+        <td>{{.Amount}}</td>
+    </tr>
+    {{end}}
+</table>
+</body>
+</html>
+`))
+
+func writeHTML(name string, at time.Time, rows []Row) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := htmlTemplate.Execute(buf, struct {
+		Name string
+		At   time.Time
+		Rows []Row
+	}{name, at, rows})
+	return buf.Bytes(), err
+}
+
+// Generate returns job report of balances at time.
+func Generate(j Job, s bestore.Store, ms mastore.Store,
+	at time.Time) (mastore.Report, error) {
+	rows, err := Rows(s, ms)
+	if err != nil {
+		return mastore.Report{}, err
+	}
+
+	r := mastore.Report{Name: j.Name, Format: j.Format, CreatedAt: at}
+
+	switch j.Format {
+	case CSV:
+		r.Content, err = writeCSV(rows)
+	case HTML:
+		r.Content, err = writeHTML(j.Name, at, rows)
+	default:
+		err = errors.New("unknown report format " + j.Format)
+	}
+	if err != nil {
+		return mastore.Report{}, err
+	}
+
+	r.Size = len(r.Content)
+
+	return r, nil
+}
+
+// Runner generates reports of pool.
+type Runner struct {
+	store  bestore.Store
+	mstore mastore.Store
+	// notifier can be nil if jobs have no emails.
+	notifier *mail.Notifier
+}
+
+func NewRunner(s bestore.Store, ms mastore.Store, n *mail.Notifier) Runner {
+	return Runner{store: s, mstore: ms, notifier: n}
+}
+
+// Run generates job report at time, stores it and emails it to job emails.
+// Report ID is returned, report is stored even if it is not sent.
+func (r Runner) Run(j Job, at time.Time) (uint, error) {
+	rep, err := Generate(j, r.store, r.mstore, at)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := r.mstore.AddReport(rep)
+	if err != nil {
+		return 0, errors.New("failed to add report to DB: " + err.Error())
+	}
+
+	if r.notifier == nil || len(j.Emails) == 0 {
+		return id, nil
+	}
+
+	err = r.notifier.Report(j.Emails, j.Name, at, mail.Attachment{
+		Name:        FileName(rep),
+		ContentType: ContentType(rep.Format),
+		Data:        rep.Content,
+	})
+	if err != nil {
+		return id, errors.New("failed to send report: " + err.Error())
+	}
+
+	return id, nil
+}
@@ -0,0 +1,107 @@
+package report
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_LoadJobs(t *testing.T) {
+	f, err := ioutil.TempFile("", "reports")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.Remove(f.Name())
+
+	f.WriteString(`reports:
+  - name: weekly-balances
+    schedule: "0 6 * * 1"
+    format: csv
+    emails:
+      - finance@example.com
+  - name: daily
+    schedule: "@daily"
+    format: html
+    pool: eu
+`)
+	f.Close()
+
+	jobs, err := LoadJobs(f.Name())
+	if !assert.NoError(t, err) || !assert.Len(t, jobs, 2) {
+		return
+	}
+
+	assert.Equal(t, "weekly-balances", jobs[0].Name)
+	assert.Equal(t, []string{"finance@example.com"}, jobs[0].Emails)
+	assert.Equal(t, time.Date(2018, 10, 15, 6, 0, 0, 0, time.UTC),
+		jobs[0].Next(time.Date(2018, 10, 8, 12, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "eu", jobs[1].Pool)
+}
+
+func setupStores() (*bestore.MockStore, *mastore.MockStore) {
+	s := bestore.NewMockStore()
+	ms := mastore.NewMockStore()
+
+	ms.On("TrashItems", "projects").Return([]mastore.TrashItem{
+		{Kind: "projects", EntityID: 2},
+	}, nil)
+	s.On("ProjectsBalances").Return([]bestore.ProjectBalance{
+		{ProjectID: 1, ProjectName: "Alpha", Coins: []bestore.CoinAmount{
+			{Coin: bestore.ETH, Amount: "3"}}},
+		{ProjectID: 2, ProjectName: "Trashed"},
+	}, nil)
+	s.On("ProjectUsersBalances", uint(1)).Return([]bestore.UserBalance{
+		{Email: "a@example.com", Coins: []bestore.CoinAmount{
+			{Coin: bestore.ETH, Amount: "1"}}},
+		{Email: "b@example.com", Coins: []bestore.CoinAmount{
+			{Coin: bestore.ETH, Amount: "2"}}},
+	}, nil)
+
+	return s, ms
+}
+
+func Test_Generate(t *testing.T) {
+	s, ms := setupStores()
+	at := time.Date(2018, 10, 15, 6, 0, 0, 0, time.UTC)
+
+	r, err := Generate(Job{Name: "weekly", Format: CSV}, s, ms, at)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "project_id,project,email,coin,amount\n"+
+		"1,Alpha,,ETH,3\n"+
+		"1,Alpha,a@example.com,ETH,1\n"+
+		"1,Alpha,b@example.com,ETH,2\n", string(r.Content))
+	assert.Equal(t, "weekly-2018-10-15.csv", FileName(r))
+	s.AssertNotCalled(t, "ProjectUsersBalances", uint(2))
+
+	r, err = Generate(Job{Name: "weekly", Format: HTML}, s, ms, at)
+	if assert.NoError(t, err) {
+		assert.True(t, strings.Contains(string(r.Content),
+			"<td>b@example.com</td>"))
+	}
+}
+
+func Test_Runner_Run(t *testing.T) {
+	s, ms := setupStores()
+	at := time.Date(2018, 10, 15, 6, 0, 0, 0, time.UTC)
+
+	ms.On("AddReport", mock.MatchedBy(func(r mastore.Report) bool {
+		return r.Name == "weekly" && r.Format == CSV && r.Size > 0
+	})).Return(uint(5), nil)
+
+	id, err := NewRunner(s, ms, nil).Run(Job{Name: "weekly", Format: CSV,
+		Emails: []string{"finance@example.com"}}, at)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(5), id)
+
+	ms.AssertExpectations(t)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/boomstarternetwork/mineradmin/migration"
+	"github.com/boomstarternetwork/mineradmin/stats"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var statsCommand = cli.Command{
+	Name:  "stats",
+	Usage: "manage worker stats",
+	Subcommands: []cli.Command{
+		{
+			Name: "import",
+			Usage: "import worker reports in JSON lines format, see " +
+				"stats package documentation",
+			Action: importStats,
+			Flags: []cli.Flag{
+				postgresFlag,
+				cli.StringFlag{
+					Name:  "file, f",
+					Usage: "reports file, - for stdin",
+					Value: "-",
+				},
+			},
+		},
+	},
+}
+
+func importStats(c *cli.Context) error {
+	var r io.Reader = os.Stdin
+
+	if file := c.String("file"); file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return errors.New("failed to open reports file: " + err.Error())
+		}
+		defer f.Close()
+		r = f
+	}
+
+	db, err := openDB(c)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	err = migration.Check(db)
+	if err != nil {
+		return cli.NewExitError("failed to check DB schema: "+
+			err.Error(), 4)
+	}
+
+	n, buckets, err := stats.Ingest(mastore.NewDBStore(db), r)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d reports to %d buckets\n", n, buckets)
+
+	return nil
+}
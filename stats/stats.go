@@ -0,0 +1,218 @@
+// Package stats ingests worker share and hashrate reports from the pool and
+// sums worker hashrates up per user and project.
+//
+// Reports are JSON lines, one report per line, e.g.
+//
+//	{"time":"2018-10-01T12:00:00Z","project_id":1,"user_id":3,"worker":"rig1","hashrate":120000000000000,"accepted":1200,"rejected":3}
+//
+// time is when report is made in RFC 3339 format, project_id and user_id
+// are IDs of project mined for and of miner, worker is rig name, hashrate
+// is in hashes per second, accepted and rejected are numbers of shares
+// since previous report. Blank lines are skipped. Reports are rolled up to
+// mastore.StatsBucket long buckets, only bucket sums are kept.
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+)
+
+// Report is worker report from the pool.
+type Report struct {
+	Time      time.Time `json:"time"`
+	ProjectID uint      `json:"project_id"`
+	UserID    uint      `json:"user_id"`
+	Worker    string    `json:"worker"`
+	Hashrate  float64   `json:"hashrate"`
+	Accepted  int64     `json:"accepted"`
+	Rejected  int64     `json:"rejected"`
+}
+
+// Validate checks that report has all fields and they are in range.
+func (r Report) Validate() error {
+	switch {
+	case r.Time.IsZero():
+		return errors.New("missing time")
+	case r.ProjectID == 0:
+		return errors.New("missing project_id")
+	case r.UserID == 0:
+		return errors.New("missing user_id")
+	case strings.TrimSpace(r.Worker) == "":
+		return errors.New("missing worker")
+	case r.Hashrate < 0 || math.IsInf(r.Hashrate, 0) || math.IsNaN(r.Hashrate):
+		return errors.New("invalid hashrate")
+	case r.Accepted < 0 || r.Rejected < 0:
+		return errors.New("negative shares")
+	}
+	return nil
+}
+
+// maxLineLen is the longest report line accepted.
+const maxLineLen = 64 * 1024
+
+// invalidError is error of reports which can not be read or are invalid.
+type invalidError struct {
+	error
+}
+
+// InvalidReports checks that error is returned because reports can not be
+// read or are invalid, not because store failed.
+func InvalidReports(err error) bool {
+	_, ok := err.(invalidError)
+	return ok
+}
+
+// Parse reads JSON lines reports and validates them. Error tells number of
+// the first invalid line.
+func Parse(r io.Reader) ([]Report, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 4096), maxLineLen)
+
+	var reports []Report
+
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+
+		var rep Report
+
+		err := json.Unmarshal([]byte(line), &rep)
+		if err == nil {
+			err = rep.Validate()
+		}
+		if err != nil {
+			return nil, invalidError{fmt.Errorf("line %d: %v", n, err)}
+		}
+
+		rep.Worker = strings.TrimSpace(rep.Worker)
+		reports = append(reports, rep)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, invalidError{err}
+	}
+
+	return reports, nil
+}
+
+// Rollup sums reports up to buckets, ordered by first report of bucket.
+func Rollup(reports []Report) []mastore.WorkerStat {
+	type key struct {
+		projectID, userID uint
+		worker            string
+		start             time.Time
+	}
+
+	var (
+		keys    []key
+		buckets = map[key]*mastore.WorkerStat{}
+	)
+
+	for _, r := range reports {
+		k := key{r.ProjectID, r.UserID, r.Worker,
+			r.Time.UTC().Truncate(mastore.StatsBucket)}
+
+		b, ok := buckets[k]
+		if !ok {
+			b = &mastore.WorkerStat{
+				ProjectID:   k.projectID,
+				UserID:      k.userID,
+				Worker:      k.worker,
+				BucketStart: k.start,
+			}
+			buckets[k] = b
+			keys = append(keys, k)
+		}
+
+		b.Samples++
+		b.HashrateSum += r.Hashrate
+		b.Accepted += r.Accepted
+		b.Rejected += r.Rejected
+	}
+
+	stats := make([]mastore.WorkerStat, len(keys))
+	for i, k := range keys {
+		stats[i] = *buckets[k]
+	}
+
+	return stats
+}
+
+// Ingest parses reports from r and adds them to store. Numbers of reports
+// and buckets they are rolled up to are returned. Nothing is added if any
+// report is invalid.
+func Ingest(ms mastore.Store, r io.Reader) (int, int, error) {
+	reports, err := Parse(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	stats := Rollup(reports)
+
+	err = ms.AddWorkerStats(stats)
+	if err != nil {
+		return 0, 0, errors.New("failed to add worker stats to DB: " +
+			err.Error())
+	}
+
+	return len(reports), len(stats), nil
+}
+
+// AverageWindow is window average hashrate is computed over.
+const AverageWindow = 24 * time.Hour
+
+// Hashrate is current and average hashrate in hashes per second.
+type Hashrate struct {
+	Current float64
+	Average float64
+}
+
+// Add returns sum of hashrates.
+func (h Hashrate) Add(o Hashrate) Hashrate {
+	return Hashrate{Current: h.Current + o.Current,
+		Average: h.Average + o.Average}
+}
+
+// ProjectUser is key of user hashrate in project.
+type ProjectUser struct {
+	ProjectID uint
+	UserID    uint
+}
+
+// Summary is worker hashrates summed up.
+type Summary struct {
+	Users        map[uint]Hashrate
+	Projects     map[uint]Hashrate
+	ProjectUsers map[ProjectUser]Hashrate
+}
+
+// Summarize sums worker hashrates up per user, project and user in
+// project.
+func Summarize(hs []mastore.WorkerHashrate) Summary {
+	s := Summary{
+		Users:        map[uint]Hashrate{},
+		Projects:     map[uint]Hashrate{},
+		ProjectUsers: map[ProjectUser]Hashrate{},
+	}
+
+	for _, wh := range hs {
+		h := Hashrate{Current: wh.Current, Average: wh.Average}
+		pu := ProjectUser{wh.ProjectID, wh.UserID}
+
+		s.Users[wh.UserID] = s.Users[wh.UserID].Add(h)
+		s.Projects[wh.ProjectID] = s.Projects[wh.ProjectID].Add(h)
+		s.ProjectUsers[pu] = s.ProjectUsers[pu].Add(h)
+	}
+
+	return s
+}
@@ -0,0 +1,96 @@
+package stats
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const reports = `{"time":"2018-10-01T12:01:00Z","project_id":1,"user_id":3,"worker":"rig1","hashrate":100,"accepted":10}
+
+{"time":"2018-10-01T12:09:59Z","project_id":1,"user_id":3,"worker":" rig1 ","hashrate":200,"accepted":20,"rejected":1}
+{"time":"2018-10-01T12:10:00Z","project_id":1,"user_id":3,"worker":"rig1","hashrate":300}
+{"time":"2018-10-01T14:05:00+02:00","project_id":1,"user_id":4,"worker":"rig1","hashrate":50}
+`
+
+func Test_Parse(t *testing.T) {
+	rs, err := Parse(strings.NewReader(reports))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, rs, 4)
+	assert.Equal(t, "rig1", rs[1].Worker)
+	assert.Equal(t, int64(1), rs[1].Rejected)
+}
+
+func Test_Parse_invalid(t *testing.T) {
+	_, err := Parse(strings.NewReader(reports +
+		`{"time":"2018-10-01T12:10:00Z","project_id":1,"worker":"rig1"}`))
+	assert.EqualError(t, err, "line 6: missing user_id")
+
+	_, err = Parse(strings.NewReader("{"))
+	assert.Error(t, err)
+
+	_, err = Parse(strings.NewReader(`{"time":"2018-10-01T12:10:00Z",` +
+		`"project_id":1,"user_id":3,"worker":"rig1","hashrate":-1}`))
+	assert.EqualError(t, err, "line 1: invalid hashrate")
+}
+
+func Test_Rollup(t *testing.T) {
+	rs, err := Parse(strings.NewReader(reports))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	start := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, []mastore.WorkerStat{
+		{ProjectID: 1, UserID: 3, Worker: "rig1", BucketStart: start,
+			Samples: 2, HashrateSum: 300, Accepted: 30, Rejected: 1},
+		{ProjectID: 1, UserID: 3, Worker: "rig1",
+			BucketStart: start.Add(10 * time.Minute), Samples: 1,
+			HashrateSum: 300},
+		{ProjectID: 1, UserID: 4, Worker: "rig1", BucketStart: start,
+			Samples: 1, HashrateSum: 50},
+	}, Rollup(rs))
+}
+
+func Test_Ingest(t *testing.T) {
+	ms := mastore.NewMockStore()
+	ms.On("AddWorkerStats", mock.Anything).Return(nil)
+
+	n, buckets, err := Ingest(ms, strings.NewReader(reports))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, 3, buckets)
+
+	ms = mastore.NewMockStore()
+	ms.On("AddWorkerStats", mock.Anything).Return(errors.New("oops"))
+
+	_, _, err = Ingest(ms, strings.NewReader(reports))
+	assert.EqualError(t, err, "failed to add worker stats to DB: oops")
+	assert.False(t, InvalidReports(err))
+
+	_, _, err = Ingest(ms, strings.NewReader(`{"worker": "rig1"}`))
+	assert.True(t, InvalidReports(err))
+}
+
+func Test_Summarize(t *testing.T) {
+	s := Summarize([]mastore.WorkerHashrate{
+		{ProjectID: 1, UserID: 3, Worker: "rig1", Current: 100, Average: 80},
+		{ProjectID: 1, UserID: 3, Worker: "rig2", Current: 50, Average: 40},
+		{ProjectID: 2, UserID: 3, Worker: "rig3", Current: 0, Average: 10},
+		{ProjectID: 1, UserID: 4, Worker: "rig1", Current: 10, Average: 10},
+	})
+
+	assert.Equal(t, Hashrate{Current: 150, Average: 130}, s.Users[3])
+	assert.Equal(t, Hashrate{Current: 160, Average: 130}, s.Projects[1])
+	assert.Equal(t, Hashrate{Current: 150, Average: 120},
+		s.ProjectUsers[ProjectUser{1, 3}])
+	assert.Equal(t, Hashrate{}, s.Users[5])
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+)
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// templatesFS returns templates from dir if it is set and templates
+// compiled into the binary otherwise.
+func templatesFS(dir string) (fs.FS, error) {
+	if dir != "" {
+		return os.DirFS(dir), nil
+	}
+	return fs.Sub(embeddedTemplates, "templates")
+}
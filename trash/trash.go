@@ -0,0 +1,206 @@
+// Package trash implements soft deletion of projects, admins and user
+// addresses. Trashed projects and admins stay in bestore until purged and
+// are hidden by mineradmin. User addresses are removed from bestore at once,
+// so miners are not paid to them.
+package trash
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+)
+
+// Trash item kinds.
+const (
+	Projects  = "projects"
+	Admins    = "admins"
+	Addresses = "addresses"
+)
+
+// Kinds lists all trash item kinds.
+var Kinds = []string{Projects, Admins, Addresses}
+
+// ValidKind checks that kind is one of Kinds.
+func ValidKind(kind string) bool {
+	for _, k := range Kinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+type addressPayload struct {
+	Coin    string `json:"coin"`
+	Address string `json:"address"`
+}
+
+// Bin moves entities to trash, restores and purges them.
+type Bin struct {
+	store  bestore.Store
+	mstore mastore.Store
+}
+
+func NewBin(s bestore.Store, ms mastore.Store) Bin {
+	return Bin{store: s, mstore: ms}
+}
+
+// RemoveProject puts project to trash and returns trash item ID.
+func (b Bin) RemoveProject(id uint, name string, by string) (uint, error) {
+	return b.mstore.AddTrashItem(mastore.TrashItem{
+		Kind:      Projects,
+		EntityID:  id,
+		Name:      name,
+		DeletedBy: by,
+	})
+}
+
+// RemoveAdmin puts admin to trash and returns trash item ID.
+func (b Bin) RemoveAdmin(id uint, login string, by string) (uint, error) {
+	return b.mstore.AddTrashItem(mastore.TrashItem{
+		Kind:      Admins,
+		EntityID:  id,
+		Name:      login,
+		DeletedBy: by,
+	})
+}
+
+// RemoveAddress removes user address from bestore keeping it in trash and
+// returns trash item ID.
+func (b Bin) RemoveAddress(userID uint, coin bestore.Coin, address string,
+	by string) (uint, error) {
+	payload, err := json.Marshal(addressPayload{
+		Coin:    fmt.Sprintf("%s", coin),
+		Address: address,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := b.mstore.AddTrashItem(mastore.TrashItem{
+		Kind:      Addresses,
+		EntityID:  userID,
+		Name:      fmt.Sprintf("%s %s", coin, address),
+		Payload:   string(payload),
+		DeletedBy: by,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = b.store.RemoveUserAddress(userID, coin, address)
+	if err != nil {
+		b.mstore.RemoveTrashItem(id)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Hidden returns IDs of trashed entities of kind.
+func (b Bin) Hidden(kind string) (map[uint]bool, error) {
+	items, err := b.mstore.TrashItems(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[uint]bool{}
+	for _, item := range items {
+		ids[item.EntityID] = true
+	}
+
+	return ids, nil
+}
+
+// AdminTrashed checks that admin with login is in trash.
+func (b Bin) AdminTrashed(login string) (bool, error) {
+	items, err := b.mstore.TrashItems(Admins)
+	if err != nil {
+		return false, err
+	}
+
+	for _, item := range items {
+		if item.Name == login {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Address returns user ID, coin and address of address item.
+func Address(item mastore.TrashItem) (userID uint, coin bestore.Coin,
+	address string, err error) {
+	var p addressPayload
+
+	err = json.Unmarshal([]byte(item.Payload), &p)
+	if err != nil {
+		err = errors.New("invalid address payload: " + err.Error())
+		return
+	}
+
+	coin, err = bestore.ParseCoin(p.Coin)
+	if err != nil {
+		err = errors.New("invalid address coin: " + err.Error())
+		return
+	}
+
+	return item.EntityID, coin, p.Address, nil
+}
+
+// Restore takes item out of trash.
+func (b Bin) Restore(item mastore.TrashItem) error {
+	if item.Kind == Addresses {
+		userID, coin, address, err := Address(item)
+		if err != nil {
+			return err
+		}
+
+		err = b.store.AddUserAddress(userID, coin, address)
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.mstore.RemoveTrashItem(item.ID)
+}
+
+// Purge deletes item permanently.
+func (b Bin) Purge(item mastore.TrashItem) error {
+	var err error
+
+	switch item.Kind {
+	case Projects:
+		err = b.store.RemoveProject(item.EntityID)
+	case Admins:
+		err = b.store.RemoveAdmin(item.EntityID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return b.mstore.RemoveTrashItem(item.ID)
+}
+
+// PurgeExpired purges items deleted more than retention ago and returns
+// number of purged items.
+func (b Bin) PurgeExpired(retention time.Duration) (int, error) {
+	items, err := b.mstore.ExpiredTrashItems(time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+
+	for i, item := range items {
+		err := b.Purge(item)
+		if err != nil {
+			return i, fmt.Errorf("failed to purge %s item %d: %v",
+				item.Kind, item.ID, err)
+		}
+	}
+
+	return len(items), nil
+}
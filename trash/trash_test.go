@@ -0,0 +1,73 @@
+package trash
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/bestore"
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_Bin_RemoveAddress(t *testing.T) {
+	s := bestore.NewMockStore()
+	ms := mastore.NewMockStore()
+
+	ms.On("AddTrashItem", mastore.TrashItem{
+		Kind:      Addresses,
+		EntityID:  3,
+		Name:      "BTC addr",
+		Payload:   `{"coin":"BTC","address":"addr"}`,
+		DeletedBy: "admin",
+	}).Return(uint(7), nil)
+	s.On("RemoveUserAddress", uint(3), bestore.BTC, "addr").Return(nil)
+
+	id, err := NewBin(s, ms).RemoveAddress(3, bestore.BTC, "addr", "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), id)
+
+	s.AssertExpectations(t)
+	ms.AssertExpectations(t)
+}
+
+func Test_Bin_RemoveAddress_storeError(t *testing.T) {
+	s := bestore.NewMockStore()
+	ms := mastore.NewMockStore()
+
+	ms.On("AddTrashItem", mock.Anything).Return(uint(7), nil)
+	ms.On("RemoveTrashItem", uint(7)).Return(nil)
+	s.On("RemoveUserAddress", uint(3), bestore.BTC, "addr").
+		Return(errors.New("error"))
+
+	_, err := NewBin(s, ms).RemoveAddress(3, bestore.BTC, "addr", "admin")
+	assert.Error(t, err)
+
+	ms.AssertExpectations(t)
+}
+
+func Test_Bin_PurgeExpired(t *testing.T) {
+	s := bestore.NewMockStore()
+	ms := mastore.NewMockStore()
+
+	ms.On("ExpiredTrashItems", mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) > 23*time.Hour
+	})).Return([]mastore.TrashItem{
+		{ID: 1, Kind: Projects, EntityID: 10},
+		{ID: 2, Kind: Admins, EntityID: 20},
+		{ID: 3, Kind: Addresses, EntityID: 30},
+	}, nil)
+	s.On("RemoveProject", uint(10)).Return(nil)
+	s.On("RemoveAdmin", uint(20)).Return(nil)
+	ms.On("RemoveTrashItem", uint(1)).Return(nil)
+	ms.On("RemoveTrashItem", uint(2)).Return(nil)
+	ms.On("RemoveTrashItem", uint(3)).Return(nil)
+
+	n, err := NewBin(s, ms).PurgeExpired(24 * time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	s.AssertExpectations(t)
+	ms.AssertExpectations(t)
+}
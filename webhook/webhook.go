@@ -0,0 +1,229 @@
+// Package webhook delivers domain events to endpoints registered by admins.
+// Events are queued in mastore and delivered as JSON signed with webhook
+// secret, failed deliveries are retried with growing delay.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+)
+
+// Event types.
+const (
+	ProjectCreated         = "project.created"
+	ProjectUpdated         = "project.updated"
+	UserCreated            = "user.created"
+	AddressesUpdated       = "user.addresses.updated"
+	AddressChangeRejected  = "user.address_change.rejected"
+	AddressChangeCancelled = "user.address_change.cancelled"
+	AdminUpdated           = "admin.updated"
+	AlertOpened            = "alert.opened"
+	AlertResolved          = "alert.resolved"
+)
+
+// Events lists all event types.
+var Events = []string{ProjectCreated, ProjectUpdated, UserCreated,
+	AddressesUpdated, AddressChangeRejected, AddressChangeCancelled,
+	AdminUpdated, AlertOpened, AlertResolved}
+
+// ValidEvent checks that event is one of Events.
+func ValidEvent(event string) bool {
+	for _, e := range Events {
+		if event == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery request headers.
+const (
+	EventHeader    = "X-Mineradmin-Event"
+	DeliveryHeader = "X-Mineradmin-Delivery"
+	// SignatureHeader is "sha256=" followed by hex encoded HMAC-SHA256 of
+	// request body keyed with webhook secret.
+	SignatureHeader = "X-Mineradmin-Signature"
+)
+
+// Payload is JSON delivered to webhooks.
+type Payload struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	// By is login of admin who caused event, it is empty for events
+	// raised by mineradmin itself.
+	By   string      `json:"by"`
+	Data interface{} `json:"data"`
+}
+
+// Emitter emits events to webhooks.
+type Emitter interface {
+	Emit(event string, by string, data interface{}) error
+}
+
+// Queue is Emitter which queues deliveries in mastore.
+type Queue struct {
+	mstore mastore.Store
+}
+
+func NewQueue(ms mastore.Store) Queue {
+	return Queue{mstore: ms}
+}
+
+func (q Queue) Emit(event string, by string, data interface{}) error {
+	payload, err := json.Marshal(Payload{
+		Event: event,
+		Time:  time.Now().UTC(),
+		By:    by,
+		Data:  data,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = q.mstore.AddWebhookDeliveries(event, string(payload))
+
+	return err
+}
+
+// Sign returns signature of body for SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret returns random webhook secret.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ValidateURL checks that webhook URL is absolute HTTP(S) URL.
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+		u.Host == "" {
+		return errors.New("invalid webhook URL")
+	}
+	return nil
+}
+
+// MaxAttempts is number of failed attempts after which delivery fails.
+const MaxAttempts = 10
+
+// Backoff returns delay before next attempt after attempts failed ones.
+func Backoff(attempts int) time.Duration {
+	const max = 6 * time.Hour
+	if attempts > 9 {
+		return max
+	}
+	d := time.Minute << uint(attempts-1)
+	if d > max {
+		return max
+	}
+	return d
+}
+
+// Deliverer delivers queued events.
+type Deliverer struct {
+	mstore mastore.Store
+	client *http.Client
+}
+
+func NewDeliverer(ms mastore.Store, client *http.Client) Deliverer {
+	return Deliverer{mstore: ms, client: client}
+}
+
+// post sends delivery and returns response status code.
+func (d Deliverer) post(dl mastore.WebhookDelivery) (int, error) {
+	body := []byte(dl.Payload)
+
+	req, err := http.NewRequest(http.MethodPost, dl.URL,
+		bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, dl.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(dl.ID), 10))
+	req.Header.Set(SignatureHeader, Sign(dl.Secret, body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// Body is drained so connection can be reused.
+	io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected response status %s",
+			res.Status)
+	}
+
+	return res.StatusCode, nil
+}
+
+// Deliver makes delivery attempt and saves its result.
+func (d Deliverer) Deliver(dl mastore.WebhookDelivery) error {
+	code, err := d.post(dl)
+
+	dl.ResponseCode = code
+
+	if err == nil {
+		dl.Status = mastore.DeliveryDelivered
+		dl.LastError = ""
+		dl.DeliveredAt = time.Now()
+	} else {
+		dl.Attempts++
+		dl.LastError = err.Error()
+		if dl.Attempts >= MaxAttempts {
+			dl.Status = mastore.DeliveryFailed
+		} else {
+			dl.NextAttemptAt = time.Now().Add(Backoff(dl.Attempts))
+		}
+	}
+
+	return d.mstore.UpdateWebhookDelivery(dl)
+}
+
+// deliverBatch is maximal number of deliveries made by DeliverDue.
+const deliverBatch = 100
+
+// DeliverDue attempts due deliveries and returns number of attempted ones.
+func (d Deliverer) DeliverDue() (int, error) {
+	dls, err := d.mstore.DueWebhookDeliveries(time.Now(), deliverBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, dl := range dls {
+		err := d.Deliver(dl)
+		if err != nil {
+			return i, fmt.Errorf("failed to save delivery %d: %v", dl.ID,
+				err)
+		}
+	}
+
+	return len(dls), nil
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/boomstarternetwork/mineradmin/mastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_Sign(t *testing.T) {
+	assert.Equal(t, "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a1494"+
+		"6175997479dbc2d1a3cd8", Sign("key", []byte("The quick brown fox "+
+		"jumps over the lazy dog")))
+}
+
+func Test_Backoff(t *testing.T) {
+	assert.Equal(t, time.Minute, Backoff(1))
+	assert.Equal(t, 4*time.Minute, Backoff(3))
+	assert.Equal(t, 256*time.Minute, Backoff(9))
+	assert.Equal(t, 6*time.Hour, Backoff(10))
+	assert.Equal(t, 6*time.Hour, Backoff(100))
+}
+
+func Test_ValidateURL(t *testing.T) {
+	assert.NoError(t, ValidateURL("https://example.com/hook"))
+	assert.Error(t, ValidateURL("ftp://example.com"))
+	assert.Error(t, ValidateURL("/hook"))
+}
+
+func Test_Queue_Emit(t *testing.T) {
+	ms := mastore.NewMockStore()
+
+	var payload string
+	ms.On("AddWebhookDeliveries", UserCreated, mock.Anything).
+		Run(func(args mock.Arguments) {
+			payload = args.String(1)
+		}).Return(1, nil)
+
+	err := NewQueue(ms).Emit(UserCreated, "admin",
+		map[string]interface{}{"id": 3})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var p struct {
+		Event string
+		By    string
+		Data  map[string]int
+	}
+	assert.NoError(t, json.Unmarshal([]byte(payload), &p))
+	assert.Equal(t, UserCreated, p.Event)
+	assert.Equal(t, "admin", p.By)
+	assert.Equal(t, map[string]int{"id": 3}, p.Data)
+}
+
+func Test_Deliverer_Deliver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			if r.Header.Get(SignatureHeader) != Sign("secret", body) ||
+				r.Header.Get(EventHeader) != UserCreated ||
+				r.Header.Get(DeliveryHeader) != "7" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	defer srv.Close()
+
+	ms := mastore.NewMockStore()
+	ms.On("UpdateWebhookDelivery", mock.Anything).Return(nil)
+
+	d := NewDeliverer(ms, srv.Client())
+
+	dl := mastore.WebhookDelivery{
+		ID:      7,
+		URL:     srv.URL,
+		Secret:  "secret",
+		Event:   UserCreated,
+		Payload: `{"event":"user.created"}`,
+		Status:  mastore.DeliveryPending,
+	}
+
+	assert.NoError(t, d.Deliver(dl))
+
+	saved := ms.Calls[0].Arguments.Get(0).(mastore.WebhookDelivery)
+	assert.Equal(t, mastore.DeliveryDelivered, saved.Status)
+	assert.Equal(t, http.StatusNoContent, saved.ResponseCode)
+	assert.False(t, saved.DeliveredAt.IsZero())
+
+	dl.Secret = "wrong"
+	dl.Attempts = MaxAttempts - 2
+
+	assert.NoError(t, d.Deliver(dl))
+
+	saved = ms.Calls[1].Arguments.Get(0).(mastore.WebhookDelivery)
+	assert.Equal(t, mastore.DeliveryPending, saved.Status)
+	assert.Equal(t, MaxAttempts-1, saved.Attempts)
+	assert.Equal(t, http.StatusForbidden, saved.ResponseCode)
+	assert.True(t, saved.NextAttemptAt.After(time.Now()))
+
+	dl.Attempts = MaxAttempts - 1
+
+	assert.NoError(t, d.Deliver(dl))
+
+	saved = ms.Calls[2].Arguments.Get(0).(mastore.WebhookDelivery)
+	assert.Equal(t, mastore.DeliveryFailed, saved.Status)
+}